@@ -3,7 +3,10 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"server/internal/config"
@@ -11,6 +14,8 @@ import (
 	"server/internal/handlers"
 	"server/internal/i18n"
 	"server/internal/icons"
+	"server/internal/notify"
+	"server/internal/providers"
 	"server/internal/services"
 	"server/internal/traefik"
 )
@@ -34,6 +39,52 @@ func noDirListingFileServer(dir string) http.Handler {
 	})
 }
 
+// reloadOnSIGHUP reloads the translation files whenever the process receives SIGHUP.
+func reloadOnSIGHUP() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		log.Println("Received SIGHUP, reloading translations")
+		if err := i18n.Reload(); err != nil {
+			log.Printf("WARNING: Could not reload translations, keeping the current ones: %v", err)
+		}
+	}
+}
+
+// rescanIconsOnSIGUSR1 rescans the user icon directory whenever the process receives SIGUSR1,
+// without reloading the configuration or translations.
+func rescanIconsOnSIGUSR1() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	for range signals {
+		log.Println("Received SIGUSR1, rescanning user icons")
+		if err := icons.ScanUserIcons(); err != nil {
+			log.Printf("WARNING: Could not rescan user icons, keeping the current ones: %v", err)
+		}
+	}
+}
+
+// warmCaches runs the cache warmers in the background, at most limit of them at a time.
+// A limit of zero runs all of them at once.
+func warmCaches(limit int, warmers ...func()) {
+	if limit <= 0 || limit >= len(warmers) {
+		for _, warm := range warmers {
+			go warm()
+		}
+		return
+	}
+	go func() {
+		slots := make(chan struct{}, limit)
+		for _, warm := range warmers {
+			slots <- struct{}{}
+			go func() {
+				defer func() { <-slots }()
+				warm()
+			}()
+		}
+	}()
+}
+
 func main() {
 	// Load configuration
 	conf := config.NewTralaConfiguration()
@@ -42,36 +93,63 @@ func main() {
 	debug.Init(conf)
 	traefik.Init(conf)
 	services.Init(conf)
+	providers.Init(conf)
+	services.SetInstanceFetcher(providers.FetchInstanceServices)
+	notify.Init(conf)
 	icons.Init(conf)
 
+	// Reuse icons discovered before the restart
+	if err := icons.LoadCache(); err != nil {
+		log.Printf("WARNING: Ignoring the icon cache: %v", err)
+	}
+
 	// Initialize HTTP clients
 	traefik.InitializeHTTPClient()
 
 	// Create external HTTP client for icon discovery (always has SSL verification enabled)
-	externalHTTPClient := &http.Client{Timeout: 5 * time.Second}
-	icons.InitHTTPClient(externalHTTPClient)
+	icons.InitHTTPClient(icons.NewExternalHTTPClient(conf.GetExternalClient()))
 
 	// Initialize i18n
 	i18n.Init(conf)
+	go reloadOnSIGHUP()
 
 	// Set version info in handlers
 	handlers.SetVersionInfo(version, commit, buildTime)
 
 	// Load HTML template
-	handlers.LoadHTMLTemplate("/app/template")
+	handlers.LoadHTMLTemplate(conf.GetTemplateDir())
 
-	// Pre-warm caches
-	go icons.GetSelfHstIconNames()
-	go icons.GetSelfHstAppTags()
-	go icons.ScanUserIcons()
+	// Pre-warm caches, the local user icons first when they do not all run at once
+	warmers := []func(){func() { icons.ScanUserIcons() }}
+	// Without the selfh.st icon source no references are resolved, so neither index is needed
+	if conf.GetUseSelfhstIcons() {
+		warmers = append(warmers, func() { icons.GetSelfHstIconNames() })
+	}
+	// The apps index only provides tags, which are only used for grouping
+	if conf.GetUseSelfhstIcons() && conf.GetGroupingEnabled() {
+		warmers = append(warmers, func() { icons.GetSelfHstAppTags() })
+	}
+	warmCaches(conf.GetWarmupConcurrency(), warmers...)
+	go rescanIconsOnSIGUSR1()
+	go traefik.DetectAPIVersions()
+	// Build the service list in the background, so requests are served from the cache
+	go services.RefreshServicesPeriodically(conf)
 
 	// Setup routes
 	mux := http.NewServeMux()
-	mux.HandleFunc("/api/services", handlers.ServicesHandler(conf))
-	mux.HandleFunc("/api/status", handlers.StatusHandler(conf))
-	mux.HandleFunc("/api/health", handlers.HealthHandler(conf))
+	// API routes are bounded by the request timeout; static files and icons are not.
+	mux.Handle("/api/services", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.ServicesHandler(conf))))
+	mux.Handle("/api/services/{id}", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.ServiceHandler(conf))))
+	mux.Handle("/api/services/refresh", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.RefreshServicesHandler(conf))))
+	mux.Handle("/api/services.csv", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.ServicesCSVHandler(conf))))
+	mux.Handle("/api/links", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.LinksHandler(conf))))
+	mux.HandleFunc("/api/openapi.json", handlers.OpenAPIHandler)
+	mux.Handle("/api/status", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.StatusHandler(conf))))
+	mux.Handle("/api/health", handlers.RequestTimeout(conf, http.HandlerFunc(handlers.HealthHandler(conf))))
+	mux.Handle(icons.IconProxyPath, handlers.RequestTimeout(conf, http.HandlerFunc(handlers.IconProxyHandler(conf))))
+	mux.Handle(icons.NormalizedIconPath, http.StripPrefix(icons.NormalizedIconPath, handlers.NormalizedIconHandler(conf)))
 	mux.Handle("/static/", http.StripPrefix("/static/", noDirListingFileServer("/app/static")))
-	mux.Handle("/icons/", http.StripPrefix("/icons/", noDirListingFileServer("/icons")))
+	mux.Handle(icons.UserIconPath, http.StripPrefix(icons.UserIconPath, handlers.IconFileServer(conf, "/icons")))
 	mux.HandleFunc("/", handlers.ServeHTMLTemplate(conf))
 
 	// Start server
@@ -79,7 +157,7 @@ func main() {
 	log.Println("Starting server on :8080...")
 	server := &http.Server{
 		Addr:              ":8080",
-		Handler:           handlers.SecurityHeaders(mux),
+		Handler:           handlers.SecurityHeaders(handlers.Recover(mux)),
 		ReadTimeout:       15 * time.Second,
 		WriteTimeout:      30 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
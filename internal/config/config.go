@@ -7,6 +7,8 @@ import (
 	"log"
 	"net/url"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -39,6 +41,7 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 		Version: "0.0", // Default to 0.0 to trigger warning if version is not set in config file
 		Environment: EnvironmentConfiguration{
 			SelfhstIconURL:         "https://cdn.jsdelivr.net/gh/selfhst/icons/",
+			UseSelfhstIcons:        true,
 			SearchEngineURL:        "https://www.google.com/search?q=",
 			RefreshIntervalSeconds: 30,
 			LogLevel:               "info",
@@ -60,14 +63,47 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 				TagFrequencyThreshold: 0.9,
 				MinServicesPerGroup:   2,
 			},
+			IconCacheMaxAgeSeconds:   86400,
+			RequestTimeoutSeconds:    20,
+			ServicesTimeoutSeconds:   15,
+			StatusCacheSeconds:       300,
+			HealthCacheSeconds:       2,
+			RateLimitRetries:         2,
+			IconDiscoveryConcurrency: 10,
+			IconCacheTTLSeconds:      604800,
+			RateLimitWaitSeconds:     5,
+			StaleMaxAgeSeconds:       300,
+			NotifyDebounceSeconds:    60,
+			StripEntrypointPrefix:    true,
+			DefaultEntryPoint: DefaultEntryPointConfig{
+				Scheme: "https",
+			},
+			ExternalClient: ExternalClientConfig{
+				MaxIdleConns:        100,
+				MaxIdleConnsPerHost: 10,
+				MaxConnsPerHost:     20,
+			},
+			TemplateDir:        "/app/template",
+			TranslationsDir:    "/app/translations",
+			UserIconExtensions: []string{".png", ".jpg", ".jpeg", ".svg", ".webp", ".gif"},
+			IconLinkRels:       []string{"apple-touch-icon", "apple-touch-icon-precomposed", "icon", "fluid-icon", "mask-icon"},
+			IconProxy: IconProxyConfig{
+				Enabled:      false,
+				AllowedHosts: []string{"cdn.jsdelivr.net"},
+			},
+			// Cloud metadata endpoints (AWS, GCP, Azure, Alibaba Cloud and the AWS IPv6 address)
+			IconBlockedNetworks: []string{"link-local", "100.100.100.200/32", "fd00:ec2::254/128"},
 		},
 		Services: ServiceConfiguration{
 			Exclude: ExcludeConfig{
 				Routers:     []string{},
 				Entrypoints: []string{},
+				Middlewares: []string{},
 			},
-			Overrides: make([]ServiceOverride, 0),
-			Manual:    make([]ManualService, 0),
+			Overrides:      make([]ServiceOverride, 0),
+			Manual:         make([]ManualService, 0),
+			QuickLinks:     make([]QuickLink, 0),
+			ManualPriority: 50,
 		},
 	}
 
@@ -98,6 +134,7 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 		debugLog("  - Version: %s", config.Version)
 		debugLog("  - Exclude routers: %v", config.Services.Exclude.Routers)
 		debugLog("  - Exclude entrypoints: %v", config.Services.Exclude.Entrypoints)
+		debugLog("  - Exclude middlewares: %v", config.Services.Exclude.Middlewares)
 		debugLog("  - Service overrides: %d items", len(config.Services.Overrides))
 	}
 
@@ -114,13 +151,23 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	}
 
 	// Step 5: environment overrides
-	if v := os.Getenv("SELFHST_ICON_URL"); v != "" {
+	if v := getenv("SELFHST_ICON_URL"); v != "" {
 		config.Environment.SelfhstIconURL = v
 	}
-	if v := os.Getenv("SEARCH_ENGINE_URL"); v != "" {
+	if v := getenv("USE_SELFHST_ICONS"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.UseSelfhstIcons = enabled
+		} else {
+			log.Printf("Warning: Invalid USE_SELFHST_ICONS '%s', using %t", v, config.Environment.UseSelfhstIcons)
+		}
+	}
+	if v := getenv("SEARCH_ENGINE_URL"); v != "" {
 		config.Environment.SearchEngineURL = v
 	}
-	if v := os.Getenv("REFRESH_INTERVAL_SECONDS"); v != "" {
+	if v := getenv("SEARCH_ENGINE_ICON"); v != "" {
+		config.Environment.SearchEngineIcon = v
+	}
+	if v := getenv("REFRESH_INTERVAL_SECONDS"); v != "" {
 		if num, err := strconv.Atoi(v); err == nil && num > 0 {
 			config.Environment.RefreshIntervalSeconds = num
 		} else {
@@ -134,25 +181,28 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 			config.Environment.Traefik.Instances = []TraefikInstanceConfig{{}}
 		}
 		inst := &config.Environment.Traefik.Instances[0]
-		if v := os.Getenv("TRAEFIK_API_HOST"); v != "" {
+		if v := getenv("TRAEFIK_API_HOST"); v != "" {
 			inst.APIHost = v
 		}
-		if v := os.Getenv("TRAEFIK_BASIC_AUTH_USERNAME"); v != "" {
+		if v := getenv("TRAEFIK_BASIC_AUTH_USERNAME"); v != "" {
 			inst.BasicAuth.Username = v
 		}
-		if v := os.Getenv("TRAEFIK_BASIC_AUTH_PASSWORD"); v != "" {
+		if v := getenv("TRAEFIK_BASIC_AUTH_PASSWORD"); v != "" {
 			inst.BasicAuth.Password = v
 		}
-		if v := os.Getenv("TRAEFIK_BASIC_AUTH_PASSWORD_FILE"); v != "" {
+		if v := getenv("TRAEFIK_BASIC_AUTH_PASSWORD_FILE"); v != "" {
 			inst.BasicAuth.PasswordFile = v
 		}
-		if v := os.Getenv("TRAEFIK_INSECURE_SKIP_VERIFY"); v != "" {
+		if v := getenv("TRAEFIK_INSECURE_SKIP_VERIFY"); v != "" {
 			if skipVerify, err := strconv.ParseBool(v); err == nil {
 				inst.InsecureSkipVerify = skipVerify
 			} else {
 				log.Printf("Warning: Invalid TRAEFIK_INSECURE_SKIP_VERIFY '%s', using %t", v, inst.InsecureSkipVerify)
 			}
 		}
+		if v := getenv("TRAEFIK_API_VERSION"); v != "" {
+			inst.APIVersion = v
+		}
 	} else {
 		// In multi-instance mode, the legacy single-instance env vars do not apply.
 		traefikEnvKeys := []string{
@@ -161,43 +211,47 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 			"TRAEFIK_BASIC_AUTH_PASSWORD",
 			"TRAEFIK_BASIC_AUTH_PASSWORD_FILE",
 			"TRAEFIK_INSECURE_SKIP_VERIFY",
+			"TRAEFIK_API_VERSION",
 		}
 		for _, key := range traefikEnvKeys {
-			if os.Getenv(key) != "" {
+			if getenv(key) != "" {
 				log.Printf("WARNING: Multi-instance mode detected: %s and related env vars are ignored. Use configuration file instead.", key)
 				break
 			}
 		}
 	}
 
-	if v := os.Getenv("LOG_LEVEL"); v != "" {
+	if v := getenv("LOG_LEVEL"); v != "" {
 		config.Environment.LogLevel = v
 	}
-	if v := os.Getenv("LANGUAGE"); v != "" {
+	if v := getenv("LANGUAGE"); v != "" {
 		config.Environment.Language = v
 	}
-	if v := os.Getenv("GROUPING_ENABLED"); v != "" {
+	if v := getenv("GROUPING_ENABLED"); v != "" {
 		if enabled, err := strconv.ParseBool(v); err == nil {
 			config.Environment.Grouping.Enabled = enabled
 		} else {
 			log.Printf("Warning: Invalid GROUPING_ENABLED '%s', using %t", v, config.Environment.Grouping.Enabled)
 		}
 	}
-	if v := os.Getenv("GROUPING_TAG_FREQUENCY_THRESHOLD"); v != "" {
+	if v := getenv("GROUPING_TAG_FREQUENCY_THRESHOLD"); v != "" {
 		if num, err := strconv.ParseFloat(v, 64); err == nil && num > 0 && num <= 1 {
 			config.Environment.Grouping.TagFrequencyThreshold = num
 		} else {
 			log.Printf("Warning: Invalid GROUPING_TAG_FREQUENCY_THRESHOLD '%s', using %f", v, config.Environment.Grouping.TagFrequencyThreshold)
 		}
 	}
-	if v := os.Getenv("GROUPING_MIN_SERVICES_PER_GROUP"); v != "" {
+	if v := getenv("GROUPING_MIN_SERVICES_PER_GROUP"); v != "" {
 		if num, err := strconv.Atoi(v); err == nil && num >= 1 {
 			config.Environment.Grouping.MinServicesPerGroup = num
 		} else {
 			log.Printf("Warning: Invalid GROUPING_MIN_SERVICES_PER_GROUP '%s', must be >= 1, using %d", v, config.Environment.Grouping.MinServicesPerGroup)
 		}
 	}
-	if v := os.Getenv("GROUPED_COLUMNS"); v != "" {
+	if v := getenv("GROUPING_MANUAL_GROUP"); v != "" {
+		config.Environment.Grouping.ManualGroup = v
+	}
+	if v := getenv("GROUPED_COLUMNS"); v != "" {
 		if num, err := strconv.Atoi(v); err == nil && num >= 1 && num <= 6 {
 			config.Environment.Grouping.Columns = num
 		} else {
@@ -205,6 +259,333 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 		}
 	}
 
+	if v := getenv("ICON_CACHE_MAX_AGE_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.IconCacheMaxAgeSeconds = num
+		} else {
+			log.Printf("Warning: Invalid ICON_CACHE_MAX_AGE_SECONDS '%s', must be >= 0, using %d", v, config.Environment.IconCacheMaxAgeSeconds)
+		}
+	}
+
+	if v := getenv("ICON_PLACEHOLDER"); v != "" {
+		config.Environment.IconPlaceholder = v
+	}
+
+	if v := getenv("ICON_PROXY_ENABLED"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.IconProxy.Enabled = enabled
+		} else {
+			log.Printf("Warning: Invalid ICON_PROXY_ENABLED '%s', using %t", v, config.Environment.IconProxy.Enabled)
+		}
+	}
+
+	if v := getenv("NORMALIZE_FAVICONS"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.NormalizeFavicons = enabled
+		} else {
+			log.Printf("Warning: Invalid NORMALIZE_FAVICONS '%s', using %t", v, config.Environment.NormalizeFavicons)
+		}
+	}
+
+	if v := getenv("CONVERT_ICO_FAVICONS"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.ConvertICOFavicons = enabled
+		} else {
+			log.Printf("Warning: Invalid CONVERT_ICO_FAVICONS '%s', using %t", v, config.Environment.ConvertICOFavicons)
+		}
+	}
+
+	if v := getenv("ICON_MIN_FUZZY_LENGTH"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.IconMinFuzzyLength = num
+		} else {
+			log.Printf("Warning: Invalid ICON_MIN_FUZZY_LENGTH '%s', must be >= 0, using %d", v, config.Environment.IconMinFuzzyLength)
+		}
+	}
+
+	if v := getenv("ICON_CATEGORY_MATCHING"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.IconCategoryMatching = enabled
+		} else {
+			log.Printf("Warning: Invalid ICON_CATEGORY_MATCHING '%s', using %t", v, config.Environment.IconCategoryMatching)
+		}
+	}
+
+	if v := getenv("ICON_MATCH_PATH"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.IconMatchPath = enabled
+		} else {
+			log.Printf("Warning: Invalid ICON_MATCH_PATH '%s', using %t", v, config.Environment.IconMatchPath)
+		}
+	}
+
+	if v := getenv("ICON_CACHE_DIR"); v != "" {
+		config.Environment.IconCacheDir = v
+	}
+
+	if v := getenv("ICON_CACHE_TTL_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.IconCacheTTLSeconds = num
+		} else {
+			log.Printf("Warning: Invalid ICON_CACHE_TTL_SECONDS '%s', must be >= 0, using %d", v, config.Environment.IconCacheTTLSeconds)
+		}
+	}
+
+	if v := getenv("SERVER_SIDE_RENDER"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.ServerSideRender = enabled
+		} else {
+			log.Printf("Warning: Invalid SERVER_SIDE_RENDER '%s', using %t", v, config.Environment.ServerSideRender)
+		}
+	}
+
+	if v := getenv("REQUEST_TIMEOUT_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.RequestTimeoutSeconds = num
+		} else {
+			log.Printf("Warning: Invalid REQUEST_TIMEOUT_SECONDS '%s', must be >= 0, using %d", v, config.Environment.RequestTimeoutSeconds)
+		}
+	}
+
+	if v := getenv("SERVICES_TIMEOUT_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.ServicesTimeoutSeconds = num
+		} else {
+			log.Printf("Warning: Invalid SERVICES_TIMEOUT_SECONDS '%s', must be >= 0, using %d", v, config.Environment.ServicesTimeoutSeconds)
+		}
+	}
+
+	if v := getenv("STATUS_CACHE_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.StatusCacheSeconds = num
+		} else {
+			log.Printf("Warning: Invalid STATUS_CACHE_SECONDS '%s', must be >= 0, using %d", v, config.Environment.StatusCacheSeconds)
+		}
+	}
+
+	if v := getenv("HEALTH_CACHE_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.HealthCacheSeconds = num
+		} else {
+			log.Printf("Warning: Invalid HEALTH_CACHE_SECONDS '%s', must be >= 0, using %d", v, config.Environment.HealthCacheSeconds)
+		}
+	}
+
+	if v := getenv("HEALTH_CHECK_CONFIG"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.HealthCheckConfig = enabled
+		} else {
+			log.Printf("Warning: Invalid HEALTH_CHECK_CONFIG '%s', using %t", v, config.Environment.HealthCheckConfig)
+		}
+	}
+
+	if v := getenv("DEBUG_ENDPOINTS"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.DebugEndpoints = enabled
+		} else {
+			log.Printf("Warning: Invalid DEBUG_ENDPOINTS '%s', using %t", v, config.Environment.DebugEndpoints)
+		}
+	}
+
+	if v := getenv("RATE_LIMIT_RETRIES"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.RateLimitRetries = num
+		} else {
+			log.Printf("Warning: Invalid RATE_LIMIT_RETRIES '%s', must be >= 0, using %d", v, config.Environment.RateLimitRetries)
+		}
+	}
+
+	if v := getenv("RATE_LIMIT_MAX_WAIT_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.RateLimitWaitSeconds = num
+		} else {
+			log.Printf("Warning: Invalid RATE_LIMIT_MAX_WAIT_SECONDS '%s', must be >= 0, using %d", v, config.Environment.RateLimitWaitSeconds)
+		}
+	}
+
+	if v := getenv("WARMUP_CONCURRENCY"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.WarmupConcurrency = num
+		} else {
+			log.Printf("Warning: Invalid WARMUP_CONCURRENCY '%s', must be >= 0, using %d", v, config.Environment.WarmupConcurrency)
+		}
+	}
+
+	if v := getenv("ICON_DISCOVERY_CONCURRENCY"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 1 {
+			config.Environment.IconDiscoveryConcurrency = num
+		} else {
+			log.Printf("Warning: Invalid ICON_DISCOVERY_CONCURRENCY '%s', must be >= 1, using %d", v, config.Environment.IconDiscoveryConcurrency)
+		}
+	}
+
+	if v := getenv("MAX_SERVICES"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.MaxServices = num
+		} else {
+			log.Printf("Warning: Invalid MAX_SERVICES '%s', must be >= 0, using %d", v, config.Environment.MaxServices)
+		}
+	}
+
+	if v := getenv("HIDE_UNHEALTHY"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.HideUnhealthy = enabled
+		} else {
+			log.Printf("Warning: Invalid HIDE_UNHEALTHY '%s', using %t", v, config.Environment.HideUnhealthy)
+		}
+	}
+
+	if v := getenv("STALE_MAX_AGE_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.StaleMaxAgeSeconds = num
+		} else {
+			log.Printf("Warning: Invalid STALE_MAX_AGE_SECONDS '%s', must be >= 0, using %d", v, config.Environment.StaleMaxAgeSeconds)
+		}
+	}
+
+	if v := getenv("NOTIFY_WEBHOOK_URL"); v != "" {
+		config.Environment.NotifyWebhookURL = v
+	}
+
+	if v := getenv("NOTIFY_DEBOUNCE_SECONDS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.NotifyDebounceSeconds = num
+		} else {
+			log.Printf("Warning: Invalid NOTIFY_DEBOUNCE_SECONDS '%s', must be >= 0, using %d", v, config.Environment.NotifyDebounceSeconds)
+		}
+	}
+
+	if v := getenv("CASE_INSENSITIVE_NAMES"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.CaseInsensitiveNames = enabled
+		} else {
+			log.Printf("Warning: Invalid CASE_INSENSITIVE_NAMES '%s', using %t", v, config.Environment.CaseInsensitiveNames)
+		}
+	}
+
+	if v := getenv("STRIP_ENTRYPOINT_PREFIX"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.StripEntrypointPrefix = enabled
+		} else {
+			log.Printf("Warning: Invalid STRIP_ENTRYPOINT_PREFIX '%s', using %t", v, config.Environment.StripEntrypointPrefix)
+		}
+	}
+
+	if v := getenv("DEFAULT_DOMAIN"); v != "" {
+		config.Environment.DefaultDomain = v
+	}
+
+	if v := getenv("DEFAULT_ENTRYPOINT_NAME"); v != "" {
+		config.Environment.DefaultEntryPoint.Name = v
+	}
+
+	if v := getenv("DEFAULT_ENTRYPOINT_PORT"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 && num <= 65535 {
+			config.Environment.DefaultEntryPoint.Port = num
+		} else {
+			log.Printf("Warning: Invalid DEFAULT_ENTRYPOINT_PORT '%s', must be between 0 and 65535, using %d", v, config.Environment.DefaultEntryPoint.Port)
+		}
+	}
+
+	if v := getenv("DEFAULT_ENTRYPOINT_SCHEME"); v != "" {
+		scheme := strings.ToLower(v)
+		if scheme == "http" || scheme == "https" {
+			config.Environment.DefaultEntryPoint.Scheme = scheme
+		} else {
+			log.Printf("Warning: Invalid DEFAULT_ENTRYPOINT_SCHEME '%s', must be http or https, using %s", v, config.Environment.DefaultEntryPoint.Scheme)
+		}
+	}
+
+	if v := getenv("MIDDLEWARE_TAGS"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Environment.MiddlewareTags = enabled
+		} else {
+			log.Printf("Warning: Invalid MIDDLEWARE_TAGS '%s', using %t", v, config.Environment.MiddlewareTags)
+		}
+	}
+
+	if v := getenv("EXTERNAL_CLIENT_MAX_IDLE_CONNS"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.ExternalClient.MaxIdleConns = num
+		} else {
+			log.Printf("Warning: Invalid EXTERNAL_CLIENT_MAX_IDLE_CONNS '%s', must be >= 0, using %d", v, config.Environment.ExternalClient.MaxIdleConns)
+		}
+	}
+
+	if v := getenv("EXTERNAL_CLIENT_MAX_IDLE_CONNS_PER_HOST"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.ExternalClient.MaxIdleConnsPerHost = num
+		} else {
+			log.Printf("Warning: Invalid EXTERNAL_CLIENT_MAX_IDLE_CONNS_PER_HOST '%s', must be >= 0, using %d", v, config.Environment.ExternalClient.MaxIdleConnsPerHost)
+		}
+	}
+
+	if v := getenv("EXTERNAL_CLIENT_MAX_CONNS_PER_HOST"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Environment.ExternalClient.MaxConnsPerHost = num
+		} else {
+			log.Printf("Warning: Invalid EXTERNAL_CLIENT_MAX_CONNS_PER_HOST '%s', must be >= 0, using %d", v, config.Environment.ExternalClient.MaxConnsPerHost)
+		}
+	}
+
+	if v := getenv("TEMPLATE_DIR"); v != "" {
+		config.Environment.TemplateDir = v
+	}
+
+	if v := getenv("TRANSLATIONS_DIR"); v != "" {
+		config.Environment.TranslationsDir = v
+	}
+
+	if v := getenv("SERVICES_INCLUDE_DISABLED"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Services.IncludeDisabled = enabled
+		} else {
+			log.Printf("Warning: Invalid SERVICES_INCLUDE_DISABLED '%s', using %t", v, config.Services.IncludeDisabled)
+		}
+	}
+
+	if v := getenv("SERVICES_INCLUDE_TCP"); v != "" {
+		if enabled, err := strconv.ParseBool(v); err == nil {
+			config.Services.IncludeTCP = enabled
+		} else {
+			log.Printf("Warning: Invalid SERVICES_INCLUDE_TCP '%s', using %t", v, config.Services.IncludeTCP)
+		}
+	}
+
+	if v := getenv("MANUAL_SERVICE_DEFAULT_PRIORITY"); v != "" {
+		if num, err := strconv.Atoi(v); err == nil && num >= 0 {
+			config.Services.ManualPriority = num
+		} else {
+			log.Printf("Warning: Invalid MANUAL_SERVICE_DEFAULT_PRIORITY '%s', must be >= 0, using %d", v, config.Services.ManualPriority)
+		}
+	}
+
+	if v := getenv("MANUAL_SERVICES_FILES"); v != "" {
+		config.Services.ManualServicesFiles = v
+	}
+	if pattern := strings.TrimSpace(config.Services.ManualServicesFiles); pattern != "" {
+		config.Services.Manual = loadManualServiceFiles(pattern, filepath.Dir(path), config.Services.Manual)
+	}
+
+	if v := getenv("MAINTENANCE_MESSAGE"); v != "" {
+		config.Environment.MaintenanceMessage = v
+	}
+	config.Environment.MaintenanceMessage = strings.TrimSpace(config.Environment.MaintenanceMessage)
+
+	if v := getenv("SITE_TITLE"); v != "" {
+		config.Environment.SiteTitle = v
+	}
+	config.Environment.SiteTitle = strings.TrimSpace(config.Environment.SiteTitle)
+
+	if v := getenv("LOGO_URL"); v != "" {
+		config.Environment.LogoURL = v
+	}
+	config.Environment.LogoURL = strings.TrimSpace(config.Environment.LogoURL)
+
+	if v := getenv("BASE_PATH"); v != "" {
+		config.Environment.BasePath = v
+	}
+
 	// After environment overrides, log effective configuration
 	debugLogEffectiveConfig := func(format string, v ...interface{}) {
 		if config.Environment.LogLevel == "debug" {
@@ -213,6 +594,7 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	}
 
 	debugLogEffectiveConfig("=== Effective Configuration ===")
+	debugLogEffectiveConfig("Environment Variable Prefix: %s", os.Getenv(EnvPrefixVar))
 	var apiHost string
 	if !config.Environment.Traefik.IsMulti && len(config.Environment.Traefik.Instances) > 0 {
 		apiHost = config.Environment.Traefik.Instances[0].APIHost
@@ -220,11 +602,65 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	debugLogEffectiveConfig("Traefik API: %s", apiHost)
 	debugLogEffectiveConfig("Log Level: %s", config.Environment.LogLevel)
 	debugLogEffectiveConfig("Language: %s", config.Environment.Language)
+	debugLogEffectiveConfig("Search Engine Icon: %s", config.Environment.SearchEngineIcon)
 	debugLogEffectiveConfig("Refresh Interval: %d seconds", config.Environment.RefreshIntervalSeconds)
+	debugLogEffectiveConfig("Server Side Render: %t", config.Environment.ServerSideRender)
+	debugLogEffectiveConfig("Request Timeout: %d seconds", config.Environment.RequestTimeoutSeconds)
+	debugLogEffectiveConfig("Services Timeout: %d seconds", config.Environment.ServicesTimeoutSeconds)
+	debugLogEffectiveConfig("Base Path: %s", config.Environment.BasePath)
+	debugLogEffectiveConfig("Status Cache: %d seconds", config.Environment.StatusCacheSeconds)
+	debugLogEffectiveConfig("Health Cache: %d seconds", config.Environment.HealthCacheSeconds)
+	debugLogEffectiveConfig("Health Check Config: %t", config.Environment.HealthCheckConfig)
+	debugLogEffectiveConfig("Debug Endpoints: %t", config.Environment.DebugEndpoints)
+	debugLogEffectiveConfig("Rate Limit Retries: %d (max wait %d seconds)", config.Environment.RateLimitRetries, config.Environment.RateLimitWaitSeconds)
+	debugLogEffectiveConfig("Warmup Concurrency: %d", config.Environment.WarmupConcurrency)
+	debugLogEffectiveConfig("Icon Discovery Concurrency: %d", config.Environment.IconDiscoveryConcurrency)
+	debugLogEffectiveConfig("Max Services: %d", config.Environment.MaxServices)
+	debugLogEffectiveConfig("Hide Unhealthy: %t", config.Environment.HideUnhealthy)
+	debugLogEffectiveConfig("Stale Max-Age: %d seconds", config.Environment.StaleMaxAgeSeconds)
+	debugLogEffectiveConfig("Notify Webhook URL: %s", config.Environment.NotifyWebhookURL)
+	debugLogEffectiveConfig("Notify Debounce: %d seconds", config.Environment.NotifyDebounceSeconds)
+	debugLogEffectiveConfig("Case Insensitive Names: %t", config.Environment.CaseInsensitiveNames)
+	debugLogEffectiveConfig("Strip Entrypoint Prefix: %t", config.Environment.StripEntrypointPrefix)
+	debugLogEffectiveConfig("Traefik Providers: %v", config.Environment.Traefik.Providers)
+	debugLogEffectiveConfig("Traefik Preferred Host Suffix: %s", config.Environment.Traefik.PreferredHostSuffix)
+	debugLogEffectiveConfig("Host Rewrites: %v", config.Environment.HostRewrites)
+	debugLogEffectiveConfig("Default Domain: %s", config.Environment.DefaultDomain)
+	debugLogEffectiveConfig("Internal Domains: %v", config.Environment.InternalDomains)
+	debugLogEffectiveConfig("Middleware Tags: %t", config.Environment.MiddlewareTags)
+	debugLogEffectiveConfig("Template Dir: %s", config.Environment.TemplateDir)
+	debugLogEffectiveConfig("Translations Dir: %s", config.Environment.TranslationsDir)
+	debugLogEffectiveConfig("Maintenance Message: %s", config.Environment.MaintenanceMessage)
+	debugLogEffectiveConfig("Site Title: %s", config.Environment.SiteTitle)
+	debugLogEffectiveConfig("Logo URL: %s", config.Environment.LogoURL)
+	debugLogEffectiveConfig("External Client: max idle conns %d, max idle conns per host %d, max conns per host %d", config.Environment.ExternalClient.MaxIdleConns, config.Environment.ExternalClient.MaxIdleConnsPerHost, config.Environment.ExternalClient.MaxConnsPerHost)
+	debugLogEffectiveConfig("Default Entrypoint: name %s, port %d, scheme %s", config.Environment.DefaultEntryPoint.Name, config.Environment.DefaultEntryPoint.Port, config.Environment.DefaultEntryPoint.Scheme)
 	debugLogEffectiveConfig("Grouping Enabled: %t", config.Environment.Grouping.Enabled)
 	debugLogEffectiveConfig("Grouping Columns: %d", config.Environment.Grouping.Columns)
+	debugLogEffectiveConfig("Grouping Manual Group: %s", config.Environment.Grouping.ManualGroup)
+	debugLogEffectiveConfig("Include Disabled Routers: %t", config.Services.IncludeDisabled)
+	debugLogEffectiveConfig("Include TCP Routers: %t", config.Services.IncludeTCP)
+	debugLogEffectiveConfig("Manual Service Default Priority: %d", config.Services.ManualPriority)
+	debugLogEffectiveConfig("Icon Cache Max-Age: %d seconds", config.Environment.IconCacheMaxAgeSeconds)
+	debugLogEffectiveConfig("Icon Placeholder: %s", config.Environment.IconPlaceholder)
+	debugLogEffectiveConfig("User Icon Extensions: %v", config.Environment.UserIconExtensions)
+	debugLogEffectiveConfig("User Icon Strip Prefixes: %v", config.Environment.UserIconStripPrefixes)
+	debugLogEffectiveConfig("User Icon Strip Suffixes: %v", config.Environment.UserIconStripSuffixes)
+	debugLogEffectiveConfig("Normalize Favicons: %t", config.Environment.NormalizeFavicons)
+	debugLogEffectiveConfig("Convert ICO Favicons: %t", config.Environment.ConvertICOFavicons)
+	debugLogEffectiveConfig("Use selfh.st Icons: %t", config.Environment.UseSelfhstIcons)
+	debugLogEffectiveConfig("Icon Scrape Allowed Hosts: %v", config.Environment.IconScrapeAllowedHosts)
+	debugLogEffectiveConfig("Icon Blocked Networks: %v (allowed: %v)", config.Environment.IconBlockedNetworks, config.Environment.IconAllowedNetworks)
+	debugLogEffectiveConfig("Icon Min Fuzzy Length: %d", config.Environment.IconMinFuzzyLength)
+	debugLogEffectiveConfig("Icon Category Matching: %t", config.Environment.IconCategoryMatching)
+	debugLogEffectiveConfig("Icon Match Path: %t", config.Environment.IconMatchPath)
+	debugLogEffectiveConfig("Icon Cache Dir: %s (TTL %d seconds)", config.Environment.IconCacheDir, config.Environment.IconCacheTTLSeconds)
+	debugLogEffectiveConfig("Icon Content Types: %v", config.Environment.IconContentTypes)
+	debugLogEffectiveConfig("Icon Link Rels: %v", config.Environment.IconLinkRels)
+	debugLogEffectiveConfig("Icon Proxy Enabled: %t (allowed hosts: %v)", config.Environment.IconProxy.Enabled, config.Environment.IconProxy.AllowedHosts)
 	debugLogEffectiveConfig("Excluded routers: %v", config.Services.Exclude.Routers)
 	debugLogEffectiveConfig("Excluded entrypoints: %v", config.Services.Exclude.Entrypoints)
+	debugLogEffectiveConfig("Excluded middlewares: %v", config.Services.Exclude.Middlewares)
 	debugLogEffectiveConfig("Service overrides: %d", len(config.Services.Overrides))
 
 	// Log each service override individually
@@ -240,6 +676,12 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 			m.Name, m.Name, m.URL, m.Icon, m.Group)
 	}
 
+	// Log quick links
+	debugLogEffectiveConfig("Quick links: %d", len(config.Services.QuickLinks))
+	for _, l := range config.Services.QuickLinks {
+		debugLogEffectiveConfig("Quick link: %s -> url=%s, icon=%s", l.Name, l.URL, l.Icon)
+	}
+
 	// Step 6: post-processing / validation
 
 	// Sanitize LogLevel: if invalid, fallback to info so Validate() passes
@@ -259,13 +701,29 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	if singleInst != nil && singleInst.APIHost != "" && !strings.HasPrefix(singleInst.APIHost, "http://") && !strings.HasPrefix(singleInst.APIHost, "https://") {
 		singleInst.APIHost = "http://" + singleInst.APIHost
 	}
+	for i := range config.Environment.Traefik.Instances {
+		config.Environment.Traefik.Instances[i].APIVersion = strings.ToLower(strings.TrimSpace(config.Environment.Traefik.Instances[i].APIVersion))
+	}
 	for i := range config.Environment.Traefik.Instances {
 		if config.Environment.Traefik.Instances[i].APIHost != "" && !strings.HasPrefix(config.Environment.Traefik.Instances[i].APIHost, "http://") && !strings.HasPrefix(config.Environment.Traefik.Instances[i].APIHost, "https://") {
 			config.Environment.Traefik.Instances[i].APIHost = "http://" + config.Environment.Traefik.Instances[i].APIHost
 		}
 	}
-	if !strings.HasSuffix(config.Environment.SelfhstIconURL, "/") {
-		config.Environment.SelfhstIconURL += "/"
+	config.Environment.SelfhstIconURL = normalizeSelfhstIconURL(config.Environment.SelfhstIconURL)
+	config.Environment.UserIconExtensions = normalizeExtensions(config.Environment.UserIconExtensions)
+	config.Environment.IconLinkRels = normalizeLinkRels(config.Environment.IconLinkRels)
+	config.Services.Manual = sanitizeManualServices(config.Services.Manual)
+	config.Services.QuickLinks = sanitizeQuickLinks(config.Services.QuickLinks)
+	config.Environment.HostRewrites = normalizeHostRewrites(config.Environment.HostRewrites)
+	config.Environment.Traefik.Providers = normalizeProviders(config.Environment.Traefik.Providers)
+	config.Environment.Traefik.PreferredHostSuffix = strings.ToLower(strings.Trim(strings.TrimSpace(config.Environment.Traefik.PreferredHostSuffix), "."))
+	config.Environment.InternalDomains = normalizeDomains(config.Environment.InternalDomains)
+	config.Environment.BasePath = normalizeBasePath(config.Environment.BasePath)
+	config.Environment.DefaultDomain = strings.ToLower(strings.Trim(strings.TrimSpace(config.Environment.DefaultDomain), "."))
+	if config.Environment.IconPlaceholder != "" {
+		if _, err := os.Stat(config.Environment.IconPlaceholder); err != nil {
+			log.Printf("Warning: Icon placeholder %s is not readable, missing icons will return 404: %v", config.Environment.IconPlaceholder, err)
+		}
 	}
 
 	// Single-instance: read basic auth password file at config load time (existing behavior)
@@ -283,7 +741,7 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 		passwordFilePath = singleInst.BasicAuth.PasswordFile
 	}
 	if singleInst != nil && singleInst.EnableBasicAuth && passwordFilePath != "" {
-		data, err := os.ReadFile(passwordFilePath)
+		password, err := ReadPasswordFile(passwordFilePath)
 		if err != nil {
 			if os.IsNotExist(err) {
 				if config.Environment.LogLevel == "debug" {
@@ -296,11 +754,12 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 			}
 			return nil, fmt.Errorf("could not read password file for basic auth")
 		}
-		singleInst.BasicAuth.Password = strings.TrimSpace(string(data))
+		singleInst.BasicAuth.Password = password
 	}
 
-	// Validate struct-level rules after all overrides are applied.
-	if err := Validate(&config); err != nil {
+	// Validate struct-level and semantic rules after all overrides are applied. All problems
+	// are logged together; only fatal ones stop the configuration from loading.
+	if err := reportValidationIssues(ValidateConfiguration(&config)); err != nil {
 		return nil, err
 	}
 
@@ -315,6 +774,7 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 
 	log.Printf("Loaded %d router excludes from %s", len(config.Services.Exclude.Routers), path)
 	log.Printf("Loaded %d entrypoint excludes from %s", len(config.Services.Exclude.Entrypoints), path)
+	log.Printf("Loaded %d middleware excludes from %s", len(config.Services.Exclude.Middlewares), path)
 	log.Printf("Loaded %d service overrides from %s", len(config.Services.Overrides), path)
 	log.Printf("Loaded %d hosts from %s", len(config.Environment.Traefik.Instances), path)
 
@@ -329,11 +789,13 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	defer config.mu.Unlock()
 
 	config.compatStatus = status
+	config.revision = config.computeRevision()
 
 	// Build map that maps a router name to a ServiceOverride for fast lookups (inside lock)
-	config.overrideMap = make(map[string]ServiceOverride, len(config.Services.Overrides))
-	for _, o := range config.Services.Overrides {
-		config.overrideMap[o.Service] = o
+	var duplicates []string
+	config.overrideMap, duplicates = buildOverrideMap(config.Services.Overrides, config.Environment.CaseInsensitiveNames)
+	if len(duplicates) > 0 {
+		log.Printf("Warning: Duplicate service overrides for %s, only the last definition of each is used", strings.Join(duplicates, ", "))
 	}
 
 	if config.Environment.LogLevel == "debug" {
@@ -355,6 +817,180 @@ func LoadConfiguration(path string) (*TralaConfiguration, error) {
 	return &config, nil
 }
 
+// ReadPasswordFile returns the contents of a basic auth password file. Surrounding whitespace,
+// including the trailing newline most editors and `echo` add, is not part of the password.
+func ReadPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// normalizeProviders lower-cases Traefik provider names, removes a leading "@" and drops empty entries.
+func normalizeProviders(providers []string) []string {
+	var result []string
+	for _, provider := range providers {
+		provider = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(provider), "@"))
+		if provider != "" {
+			result = append(result, provider)
+		}
+	}
+	return result
+}
+
+// normalizeHostRewrites lower-cases host rewrite suffixes and removes surrounding dots and
+// whitespace, so "internal", ".internal" and "Internal." are equivalent. Empty entries are dropped.
+func normalizeHostRewrites(rewrites map[string]string) map[string]string {
+	result := make(map[string]string, len(rewrites))
+	for from, to := range rewrites {
+		from = strings.ToLower(strings.Trim(strings.TrimSpace(from), "."))
+		to = strings.ToLower(strings.Trim(strings.TrimSpace(to), "."))
+		if from == "" || to == "" {
+			log.Printf("Warning: Ignoring host rewrite '%s' -> '%s': suffixes must not be empty", from, to)
+			continue
+		}
+		result[from] = to
+	}
+	return result
+}
+
+// normalizeSelfhstIconURL trims whitespace, adds https:// to a URL without a scheme that starts
+// with a host name (cdn.jsdelivr.net/gh/selfhst/icons) and makes the URL end in exactly one
+// slash, as icon paths are appended to it. A value that is still not a valid URL is logged and
+// returned unchanged, so validation reports it.
+func normalizeSelfhstIconURL(raw string) string {
+	u := strings.TrimSpace(raw)
+	if u == "" {
+		return raw
+	}
+	if !strings.Contains(u, "://") {
+		if host, _, _ := strings.Cut(u, "/"); strings.Contains(host, ".") {
+			log.Printf("Warning: selfhst_icon_url '%s' has no scheme, using https://%s", u, u)
+			u = "https://" + u
+		}
+	}
+	if !IsValidUrl(u) {
+		log.Printf("Warning: selfhst_icon_url '%s' is not a valid URL, selfh.st icons cannot be loaded", raw)
+		return raw
+	}
+	return strings.TrimRight(u, "/") + "/"
+}
+
+// normalizeBasePath returns the base path with a leading and without a trailing slash, so
+// "trala", "/trala/" and "/trala" are equivalent. The root path becomes "".
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// normalizeDomains lower-cases domain suffixes and removes surrounding dots and whitespace, so
+// "lan", ".lan" and "LAN." are equivalent. Empty entries are dropped.
+func normalizeDomains(domains []string) []string {
+	var result []string
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+		if domain != "" {
+			result = append(result, domain)
+		}
+	}
+	return result
+}
+
+// loadManualServiceFiles appends the manual services from the files matching pattern to manual.
+// Each file holds a YAML list of manual services in the services.manual format. A relative
+// pattern is resolved against baseDir, the directory of the configuration file. Files that
+// cannot be read or parsed are skipped with a warning. Every entry records the file it came
+// from, so ValidateSemantics can name it when the entry's name is used more than once.
+func loadManualServiceFiles(pattern, baseDir string, manual []ManualService) []ManualService {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(baseDir, pattern)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		log.Printf("Warning: Invalid manual_services_files pattern '%s': %v", pattern, err)
+		return manual
+	}
+	if len(files) == 0 {
+		log.Printf("Warning: No manual service files match '%s'", pattern)
+		return manual
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Warning: Could not read manual service file %s: %v", file, err)
+			continue
+		}
+		var entries []ManualService
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			log.Printf("Warning: Could not parse manual service file %s: %v", file, err)
+			continue
+		}
+		for i := range entries {
+			entries[i].source = fmt.Sprintf("%s[%d]", file, i)
+		}
+		manual = append(manual, entries...)
+		log.Printf("Loaded %d manual services from %s", len(entries), file)
+	}
+	return manual
+}
+
+// sanitizeManualServices trims whitespace from manual service names and URLs and drops
+// entries without a name, which would otherwise render as blank tiles.
+func sanitizeManualServices(manual []ManualService) []ManualService {
+	result := make([]ManualService, 0, len(manual))
+	for i, m := range manual {
+		m.Name = strings.TrimSpace(m.Name)
+		m.URL = strings.TrimSpace(m.URL)
+		if m.Name == "" {
+			log.Printf("Warning: Skipping manual service #%d (url '%s'): name is empty", i+1, m.URL)
+			continue
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// sanitizeQuickLinks trims whitespace from quick link names and URLs and drops entries
+// without a name.
+func sanitizeQuickLinks(links []QuickLink) []QuickLink {
+	result := make([]QuickLink, 0, len(links))
+	for i, l := range links {
+		l.Name = strings.TrimSpace(l.Name)
+		l.URL = strings.TrimSpace(l.URL)
+		if l.Name == "" {
+			log.Printf("Warning: Skipping quick link #%d (url '%s'): name is empty", i+1, l.URL)
+			continue
+		}
+		result = append(result, l)
+	}
+	return result
+}
+
+// buildOverrideMap maps each override's service name to the override. When the same
+// service is listed more than once the last definition wins; the duplicated names are
+// returned in order of first duplication so they can be reported. With caseInsensitive
+// the keys are lower-cased, so names differing only in case are duplicates.
+func buildOverrideMap(overrides []ServiceOverride, caseInsensitive bool) (map[string]ServiceOverride, []string) {
+	overrideMap := make(map[string]ServiceOverride, len(overrides))
+	var duplicates []string
+	for _, o := range overrides {
+		key := o.Service
+		if caseInsensitive {
+			key = strings.ToLower(key)
+		}
+		if _, exists := overrideMap[key]; exists && !slices.Contains(duplicates, o.Service) {
+			duplicates = append(duplicates, o.Service)
+		}
+		overrideMap[key] = o
+	}
+	return overrideMap, duplicates
+}
+
 // normalizeTraefikConfig detects the config format (single vs multi instance) and normalizes.
 // Supports both legacy single-instance format (fields on TraefikConfig) and new multi-instance format.
 func normalizeTraefikConfig(config *TralaConfiguration) error {
@@ -372,12 +1008,14 @@ func normalizeTraefikConfig(config *TralaConfiguration) error {
 			traefik.Instances[0].EnableBasicAuth = traefik.EnableBasicAuth
 			traefik.Instances[0].BasicAuth = traefik.BasicAuth
 			traefik.Instances[0].InsecureSkipVerify = traefik.InsecureSkipVerify
+			traefik.Instances[0].APIVersion = traefik.APIVersion
 		}
 		// Clear legacy single-instance fields to avoid confusion
 		traefik.APIHost = ""
 		traefik.EnableBasicAuth = false
 		traefik.BasicAuth = TraefikBasicAuth{}
 		traefik.InsecureSkipVerify = false
+		traefik.APIVersion = ""
 		return nil
 	}
 
@@ -390,12 +1028,14 @@ func normalizeTraefikConfig(config *TralaConfiguration) error {
 			EnableBasicAuth:    traefik.EnableBasicAuth,
 			BasicAuth:          traefik.BasicAuth,
 			InsecureSkipVerify: traefik.InsecureSkipVerify,
+			APIVersion:         traefik.APIVersion,
 		}}
 		// Clear legacy fields
 		traefik.APIHost = ""
 		traefik.EnableBasicAuth = false
 		traefik.BasicAuth = TraefikBasicAuth{}
 		traefik.InsecureSkipVerify = false
+		traefik.APIVersion = ""
 		return nil
 	}
 
@@ -404,6 +1044,35 @@ func normalizeTraefikConfig(config *TralaConfiguration) error {
 	return nil
 }
 
+// normalizeExtensions lowercases file extensions, adds a missing leading dot and drops empty entries.
+func normalizeExtensions(extensions []string) []string {
+	result := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" || ext == "." {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		result = append(result, ext)
+	}
+	return result
+}
+
+// normalizeLinkRels lower-cases rel values and collapses their whitespace, so "Shortcut  Icon"
+// becomes "shortcut icon". Empty values are dropped.
+func normalizeLinkRels(rels []string) []string {
+	result := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		rel = strings.Join(strings.Fields(strings.ToLower(rel)), " ")
+		if rel != "" {
+			result = append(result, rel)
+		}
+	}
+	return result
+}
+
 // postProcessTraefikConfig derives instance names from api_host URLs and handles duplicates.
 func postProcessTraefikConfig(config *TralaConfiguration) error {
 	instances := config.Environment.Traefik.Instances
@@ -474,6 +1143,25 @@ func ValidateConfigVersion(configVersion string, basicAuthWarning string) Config
 	return status
 }
 
+// EnvPrefixVar names the environment variable that holds an optional prefix for all other
+// environment variables, e.g. "TRALA" to read TRALA_TRAEFIK_API_HOST.
+const EnvPrefixVar = "TRALA_ENV_PREFIX"
+
+// getenv returns the value of the environment variable name. When TRALA_ENV_PREFIX is set, the
+// prefixed variable is preferred and the unprefixed one is used only if the prefixed one is
+// unset or empty. A missing "_" between prefix and name is added.
+func getenv(name string) string {
+	if prefix := os.Getenv(EnvPrefixVar); prefix != "" {
+		if !strings.HasSuffix(prefix, "_") {
+			prefix += "_"
+		}
+		if v := os.Getenv(prefix + name); v != "" {
+			return v
+		}
+	}
+	return os.Getenv(name)
+}
+
 // ValidateBasicAuthPassword checks if the basic auth password is configured using only one method.
 // Returns a warning message if multiple password sources are configured.
 func ValidateBasicAuthPassword(config TraefikConfig) string {
@@ -494,12 +1182,12 @@ func ValidateBasicAuthPassword(config TraefikConfig) string {
 			}
 
 			// Check environment variable password
-			if os.Getenv("TRAEFIK_BASIC_AUTH_PASSWORD") != "" {
+			if getenv("TRAEFIK_BASIC_AUTH_PASSWORD") != "" {
 				passwordSources++
 			}
 
 			// Check environment variable password file
-			if os.Getenv("TRAEFIK_BASIC_AUTH_PASSWORD_FILE") != "" {
+			if getenv("TRAEFIK_BASIC_AUTH_PASSWORD_FILE") != "" {
 				passwordSources++
 			}
 
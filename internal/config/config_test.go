@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 
@@ -12,6 +16,15 @@ import (
 
 // --- Test helpers ---
 
+// captureLog redirects the standard logger into a buffer for the duration of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
 // writeConfigFile stages a YAML config in a temp dir and returns its path.
 func writeConfigFile(t *testing.T, content string) string {
 	t.Helper()
@@ -34,19 +47,70 @@ func clearConfigEnv(t *testing.T) {
 	t.Helper()
 	vars := []string{
 		"SELFHST_ICON_URL",
+		"USE_SELFHST_ICONS",
 		"SEARCH_ENGINE_URL",
+		"SEARCH_ENGINE_ICON",
 		"REFRESH_INTERVAL_SECONDS",
 		"TRAEFIK_API_HOST",
 		"TRAEFIK_BASIC_AUTH_USERNAME",
 		"TRAEFIK_BASIC_AUTH_PASSWORD",
 		"TRAEFIK_BASIC_AUTH_PASSWORD_FILE",
 		"TRAEFIK_INSECURE_SKIP_VERIFY",
+		"TRAEFIK_API_VERSION",
 		"LOG_LEVEL",
 		"LANGUAGE",
 		"GROUPING_ENABLED",
 		"GROUPING_TAG_FREQUENCY_THRESHOLD",
 		"GROUPING_MIN_SERVICES_PER_GROUP",
+		"GROUPING_MANUAL_GROUP",
 		"GROUPED_COLUMNS",
+		"ICON_CACHE_MAX_AGE_SECONDS",
+		"ICON_PLACEHOLDER",
+		"ICON_PROXY_ENABLED",
+		"NORMALIZE_FAVICONS",
+		"CONVERT_ICO_FAVICONS",
+		"ICON_MIN_FUZZY_LENGTH",
+		"ICON_CATEGORY_MATCHING",
+		"ICON_MATCH_PATH",
+		"ICON_CACHE_DIR",
+		"ICON_CACHE_TTL_SECONDS",
+		"SERVER_SIDE_RENDER",
+		"REQUEST_TIMEOUT_SECONDS",
+		"SERVICES_TIMEOUT_SECONDS",
+		"STATUS_CACHE_SECONDS",
+		"HEALTH_CACHE_SECONDS",
+		"HEALTH_CHECK_CONFIG",
+		"DEBUG_ENDPOINTS",
+		"RATE_LIMIT_RETRIES",
+		"RATE_LIMIT_MAX_WAIT_SECONDS",
+		"WARMUP_CONCURRENCY",
+		"ICON_DISCOVERY_CONCURRENCY",
+		"MAX_SERVICES",
+		"HIDE_UNHEALTHY",
+		"STALE_MAX_AGE_SECONDS",
+		"NOTIFY_WEBHOOK_URL",
+		"NOTIFY_DEBOUNCE_SECONDS",
+		"CASE_INSENSITIVE_NAMES",
+		"STRIP_ENTRYPOINT_PREFIX",
+		"DEFAULT_DOMAIN",
+		"DEFAULT_ENTRYPOINT_NAME",
+		"DEFAULT_ENTRYPOINT_PORT",
+		"DEFAULT_ENTRYPOINT_SCHEME",
+		"MIDDLEWARE_TAGS",
+		"EXTERNAL_CLIENT_MAX_IDLE_CONNS",
+		"EXTERNAL_CLIENT_MAX_IDLE_CONNS_PER_HOST",
+		"EXTERNAL_CLIENT_MAX_CONNS_PER_HOST",
+		"TEMPLATE_DIR",
+		"TRANSLATIONS_DIR",
+		"MAINTENANCE_MESSAGE",
+		"MANUAL_SERVICES_FILES",
+		"SERVICES_INCLUDE_DISABLED",
+		"SERVICES_INCLUDE_TCP",
+		"MANUAL_SERVICE_DEFAULT_PRIORITY",
+		"SITE_TITLE",
+		"LOGO_URL",
+		"BASE_PATH",
+		EnvPrefixVar,
 	}
 	for _, v := range vars {
 		t.Setenv(v, "")
@@ -65,11 +129,12 @@ func newPopulatedConfig() *TralaConfiguration {
 	c := &TralaConfiguration{
 		Version: "3.1",
 		Environment: EnvironmentConfiguration{
-			SelfhstIconURL:         "https://icons.example/",
-			SearchEngineURL:        "https://search.example/?q=",
-			RefreshIntervalSeconds: 42,
-			LogLevel:               "debug",
-			Language:               "nl",
+			SelfhstIconURL:           "https://icons.example/",
+			SearchEngineURL:          "https://search.example/?q=",
+			RefreshIntervalSeconds:   42,
+			LogLevel:                 "debug",
+			Language:                 "nl",
+			IconDiscoveryConcurrency: 10,
 			Traefik: TraefikConfig{
 				Instances: []TraefikInstanceConfig{
 					{
@@ -525,11 +590,61 @@ func TestLoadConfiguration_DefaultsWhenFileMissing(t *testing.T) {
 	assert.Equal(t, 30, conf.GetRefreshIntervalSeconds())
 	assert.Equal(t, "info", conf.GetLogLevel())
 	assert.Equal(t, "https://cdn.jsdelivr.net/gh/selfhst/icons/", conf.GetSelfhstIconURL())
+	assert.True(t, conf.GetUseSelfhstIcons())
 	assert.Equal(t, "https://www.google.com/search?q=", conf.GetSearchEngineURL())
+	assert.Empty(t, conf.GetSearchEngineIcon())
 	assert.True(t, conf.GetGroupingEnabled())
 	assert.Equal(t, 3, conf.GetGroupingColumns())
 	assert.InDelta(t, 0.9, conf.GetTagFrequencyThreshold(), 1e-9)
 	assert.Equal(t, 2, conf.GetMinServicesPerGroup())
+	assert.Empty(t, conf.GetManualGroup())
+	assert.False(t, conf.GetIncludeDisabled())
+	assert.False(t, conf.GetIncludeTCP())
+	assert.Equal(t, 50, conf.GetManualServiceDefaultPriority())
+	assert.Equal(t, 86400, conf.GetIconCacheMaxAgeSeconds())
+	assert.Equal(t, []string{".png", ".jpg", ".jpeg", ".svg", ".webp", ".gif"}, conf.GetUserIconExtensions())
+	assert.False(t, conf.GetIconProxyEnabled())
+	assert.False(t, conf.GetNormalizeFavicons())
+	assert.False(t, conf.GetConvertICOFavicons())
+	assert.Equal(t, []string{"apple-touch-icon", "apple-touch-icon-precomposed", "icon", "fluid-icon", "mask-icon"}, conf.GetIconLinkRels())
+	assert.Equal(t, 0, conf.GetIconMinFuzzyLength())
+	assert.False(t, conf.GetIconCategoryMatching())
+	assert.False(t, conf.GetIconMatchPath())
+	assert.Empty(t, conf.GetIconCacheDir())
+	assert.Equal(t, 604800, conf.GetIconCacheTTLSeconds())
+	assert.False(t, conf.GetServerSideRender())
+	assert.Equal(t, 20, conf.GetRequestTimeoutSeconds())
+	assert.Equal(t, 15, conf.GetServicesTimeoutSeconds())
+	assert.Equal(t, 300, conf.GetStatusCacheSeconds())
+	assert.Equal(t, 2, conf.GetHealthCacheSeconds())
+	assert.False(t, conf.GetHealthCheckConfig())
+	assert.False(t, conf.GetDebugEndpoints())
+	assert.Equal(t, 2, conf.GetRateLimitRetries())
+	assert.Equal(t, 5, conf.GetRateLimitWaitSeconds())
+	assert.Equal(t, 0, conf.GetWarmupConcurrency())
+	assert.Equal(t, 10, conf.GetIconDiscoveryConcurrency())
+	assert.Equal(t, 0, conf.GetMaxServices())
+	assert.False(t, conf.GetHideUnhealthy())
+	assert.Equal(t, 300, conf.GetStaleMaxAgeSeconds())
+	assert.Empty(t, conf.GetNotifyWebhookURL())
+	assert.Equal(t, 60, conf.GetNotifyDebounceSeconds())
+	assert.False(t, conf.GetCaseInsensitiveNames())
+	assert.True(t, conf.GetStripEntrypointPrefix())
+	assert.Empty(t, conf.GetDefaultDomain())
+	assert.Empty(t, conf.GetTraefikProviders())
+	assert.False(t, conf.GetMiddlewareTags())
+	assert.Equal(t, "/app/template", conf.GetTemplateDir())
+	assert.Equal(t, "/app/translations", conf.GetTranslationsDir())
+	assert.Empty(t, conf.GetMaintenanceMessage())
+	assert.Empty(t, conf.GetSiteTitle())
+	assert.Empty(t, conf.GetLogoURL())
+	assert.Empty(t, conf.GetBasePath())
+	assert.Equal(t, ExternalClientConfig{MaxIdleConns: 100, MaxIdleConnsPerHost: 10, MaxConnsPerHost: 20}, conf.GetExternalClient())
+	assert.Empty(t, conf.GetExcludeMiddlewares())
+	assert.Equal(t, DefaultEntryPointConfig{Scheme: "https"}, conf.GetDefaultEntryPoint())
+	assert.Equal(t, []string{"cdn.jsdelivr.net"}, conf.GetIconProxyAllowedHosts())
+	assert.Equal(t, []string{"link-local", "100.100.100.200/32", "fd00:ec2::254/128"}, conf.GetIconBlockedNetworks())
+	assert.Empty(t, conf.GetIconAllowedNetworks())
 	assert.Equal(t, "http://traefik.local", conf.GetTraefikInstances()[0].APIHost,
 		"bare host should be prefixed with http://")
 	assert.False(t, conf.GetTraefikInstances()[0].EnableBasicAuth)
@@ -552,6 +667,7 @@ environment:
   traefik:
     api_host: "https://traefik.example"
     insecure_skip_verify: true
+    api_version: V2
   grouping:
     enabled: false
     columns: 5
@@ -593,6 +709,7 @@ services:
 	assert.Equal(t, "fr", conf.GetLanguage())
 	assert.Equal(t, "https://traefik.example", conf.GetTraefikInstances()[0].APIHost)
 	assert.True(t, conf.GetTraefikInstances()[0].InsecureSkipVerify)
+	assert.Equal(t, "v2", conf.GetTraefikInstances()[0].APIVersion, "api version is normalized to lower case")
 	assert.False(t, conf.GetGroupingEnabled())
 	assert.Equal(t, 5, conf.GetGroupingColumns())
 	assert.InDelta(t, 0.5, conf.GetTagFrequencyThreshold(), 1e-9)
@@ -658,36 +775,156 @@ environment:
 	path := writeConfigFile(t, baseYAML)
 
 	t.Setenv("SELFHST_ICON_URL", "https://env-icons.example/")
+	t.Setenv("USE_SELFHST_ICONS", "false")
 	t.Setenv("SEARCH_ENGINE_URL", "https://env-search.example/?q=")
+	t.Setenv("SEARCH_ENGINE_ICON", "searxng.svg")
 	t.Setenv("REFRESH_INTERVAL_SECONDS", "77")
 	t.Setenv("TRAEFIK_API_HOST", "https://env-traefik.example")
 	t.Setenv("TRAEFIK_BASIC_AUTH_USERNAME", "bob")
 	t.Setenv("TRAEFIK_BASIC_AUTH_PASSWORD", "envpass")
 	t.Setenv("TRAEFIK_INSECURE_SKIP_VERIFY", "true")
+	t.Setenv("TRAEFIK_API_VERSION", "v3")
 	t.Setenv("LOG_LEVEL", "debug")
 	t.Setenv("LANGUAGE", "de")
 	t.Setenv("GROUPING_ENABLED", "false")
 	t.Setenv("GROUPING_TAG_FREQUENCY_THRESHOLD", "0.25")
 	t.Setenv("GROUPING_MIN_SERVICES_PER_GROUP", "5")
+	t.Setenv("GROUPING_MANUAL_GROUP", "Bookmarks")
+	t.Setenv("SERVICES_INCLUDE_DISABLED", "true")
+	t.Setenv("SERVICES_INCLUDE_TCP", "true")
+	t.Setenv("MANUAL_SERVICE_DEFAULT_PRIORITY", "5")
 	t.Setenv("GROUPED_COLUMNS", "6")
+	t.Setenv("ICON_CACHE_MAX_AGE_SECONDS", "600")
+	t.Setenv("ICON_PLACEHOLDER", "/config/missing.png")
+	t.Setenv("ICON_PROXY_ENABLED", "true")
+	t.Setenv("NORMALIZE_FAVICONS", "true")
+	t.Setenv("CONVERT_ICO_FAVICONS", "true")
+	t.Setenv("ICON_MIN_FUZZY_LENGTH", "4")
+	t.Setenv("ICON_CATEGORY_MATCHING", "true")
+	t.Setenv("ICON_MATCH_PATH", "true")
+	t.Setenv("ICON_CACHE_DIR", "/data/icons")
+	t.Setenv("ICON_CACHE_TTL_SECONDS", "3600")
+	t.Setenv("SERVER_SIDE_RENDER", "true")
+	t.Setenv("REQUEST_TIMEOUT_SECONDS", "5")
+	t.Setenv("SERVICES_TIMEOUT_SECONDS", "3")
+	t.Setenv("STATUS_CACHE_SECONDS", "0")
+	t.Setenv("HEALTH_CACHE_SECONDS", "5")
+	t.Setenv("HEALTH_CHECK_CONFIG", "true")
+	t.Setenv("DEBUG_ENDPOINTS", "true")
+	t.Setenv("RATE_LIMIT_RETRIES", "0")
+	t.Setenv("RATE_LIMIT_MAX_WAIT_SECONDS", "30")
+	t.Setenv("WARMUP_CONCURRENCY", "1")
+	t.Setenv("ICON_DISCOVERY_CONCURRENCY", "3")
+	t.Setenv("MAX_SERVICES", "100")
+	t.Setenv("HIDE_UNHEALTHY", "true")
+	t.Setenv("STALE_MAX_AGE_SECONDS", "0")
+	t.Setenv("NOTIFY_WEBHOOK_URL", "https://hooks.example/trala")
+	t.Setenv("NOTIFY_DEBOUNCE_SECONDS", "5")
+	t.Setenv("CASE_INSENSITIVE_NAMES", "true")
+	t.Setenv("STRIP_ENTRYPOINT_PREFIX", "false")
+	t.Setenv("DEFAULT_DOMAIN", ".Example.com")
+	t.Setenv("DEFAULT_ENTRYPOINT_NAME", "websecure")
+	t.Setenv("MIDDLEWARE_TAGS", "true")
+	t.Setenv("TEMPLATE_DIR", "./web/html")
+	t.Setenv("TRANSLATIONS_DIR", "./translations")
+	t.Setenv("MAINTENANCE_MESSAGE", "  Maintenance tonight 22:00-23:00 ")
+	t.Setenv("SITE_TITLE", "Home Lab")
+	t.Setenv("LOGO_URL", "/icons/logo.svg")
+	t.Setenv("BASE_PATH", "trala/")
+	t.Setenv("EXTERNAL_CLIENT_MAX_IDLE_CONNS", "50")
+	t.Setenv("EXTERNAL_CLIENT_MAX_IDLE_CONNS_PER_HOST", "5")
+	t.Setenv("EXTERNAL_CLIENT_MAX_CONNS_PER_HOST", "0")
+	t.Setenv("DEFAULT_ENTRYPOINT_PORT", "8443")
+	t.Setenv("DEFAULT_ENTRYPOINT_SCHEME", "HTTP")
 
 	conf, err := LoadConfiguration(path)
 	require.NoError(t, err)
 	require.NotNil(t, conf)
 
 	assert.Equal(t, "https://env-icons.example/", conf.GetSelfhstIconURL())
+	assert.False(t, conf.GetUseSelfhstIcons())
 	assert.Equal(t, "https://env-search.example/?q=", conf.GetSearchEngineURL())
+	assert.Equal(t, "searxng.svg", conf.GetSearchEngineIcon())
 	assert.Equal(t, 77, conf.GetRefreshIntervalSeconds())
 	assert.Equal(t, "https://env-traefik.example", conf.GetTraefikInstances()[0].APIHost)
 	assert.Equal(t, "bob", conf.GetTraefikInstances()[0].BasicAuth.Username)
 	assert.Equal(t, "envpass", conf.GetTraefikInstances()[0].BasicAuth.Password)
 	assert.True(t, conf.GetTraefikInstances()[0].InsecureSkipVerify)
+	assert.Equal(t, "v3", conf.GetTraefikInstances()[0].APIVersion)
 	assert.Equal(t, "debug", conf.GetLogLevel())
 	assert.Equal(t, "de", conf.GetLanguage())
 	assert.False(t, conf.GetGroupingEnabled())
 	assert.InDelta(t, 0.25, conf.GetTagFrequencyThreshold(), 1e-9)
 	assert.Equal(t, 5, conf.GetMinServicesPerGroup())
+	assert.Equal(t, "Bookmarks", conf.GetManualGroup())
+	assert.True(t, conf.GetIncludeDisabled())
+	assert.True(t, conf.GetIncludeTCP())
+	assert.Equal(t, 5, conf.GetManualServiceDefaultPriority())
 	assert.Equal(t, 6, conf.GetGroupingColumns())
+	assert.Equal(t, 600, conf.GetIconCacheMaxAgeSeconds())
+	assert.Equal(t, "/config/missing.png", conf.GetIconPlaceholder())
+	assert.True(t, conf.GetIconProxyEnabled())
+	assert.True(t, conf.GetNormalizeFavicons())
+	assert.True(t, conf.GetConvertICOFavicons())
+	assert.Equal(t, 4, conf.GetIconMinFuzzyLength())
+	assert.True(t, conf.GetIconCategoryMatching())
+	assert.True(t, conf.GetIconMatchPath())
+	assert.Equal(t, "/data/icons", conf.GetIconCacheDir())
+	assert.Equal(t, 3600, conf.GetIconCacheTTLSeconds())
+	assert.True(t, conf.GetServerSideRender())
+	assert.Equal(t, 5, conf.GetRequestTimeoutSeconds())
+	assert.Equal(t, 3, conf.GetServicesTimeoutSeconds())
+	assert.Equal(t, 0, conf.GetStatusCacheSeconds())
+	assert.Equal(t, 5, conf.GetHealthCacheSeconds())
+	assert.True(t, conf.GetHealthCheckConfig())
+	assert.True(t, conf.GetDebugEndpoints())
+	assert.Equal(t, 0, conf.GetRateLimitRetries())
+	assert.Equal(t, 30, conf.GetRateLimitWaitSeconds())
+	assert.Equal(t, 1, conf.GetWarmupConcurrency())
+	assert.Equal(t, 3, conf.GetIconDiscoveryConcurrency())
+	assert.Equal(t, 100, conf.GetMaxServices())
+	assert.True(t, conf.GetHideUnhealthy())
+	assert.Equal(t, 0, conf.GetStaleMaxAgeSeconds())
+	assert.Equal(t, "https://hooks.example/trala", conf.GetNotifyWebhookURL())
+	assert.Equal(t, 5, conf.GetNotifyDebounceSeconds())
+	assert.True(t, conf.GetCaseInsensitiveNames())
+	assert.False(t, conf.GetStripEntrypointPrefix())
+	assert.Equal(t, "example.com", conf.GetDefaultDomain())
+	assert.True(t, conf.GetMiddlewareTags())
+	assert.Equal(t, "./web/html", conf.GetTemplateDir())
+	assert.Equal(t, "./translations", conf.GetTranslationsDir())
+	assert.Equal(t, "Maintenance tonight 22:00-23:00", conf.GetMaintenanceMessage())
+	assert.Equal(t, "Home Lab", conf.GetSiteTitle())
+	assert.Equal(t, "/icons/logo.svg", conf.GetLogoURL())
+	assert.Equal(t, "/trala", conf.GetBasePath())
+	assert.Equal(t, ExternalClientConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 5, MaxConnsPerHost: 0}, conf.GetExternalClient())
+	assert.Equal(t, DefaultEntryPointConfig{Name: "websecure", Port: 8443, Scheme: "http"}, conf.GetDefaultEntryPoint())
+}
+
+func TestLoadConfiguration_EnvPrefix(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv(EnvPrefixVar, "TRALA")
+	t.Setenv("TRALA_TRAEFIK_API_HOST", "http://prefixed.local")
+	t.Setenv("TRAEFIK_API_HOST", "http://unprefixed.local")
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("TRALA_LANGUAGE", "")
+	t.Setenv("LANGUAGE", "nl")
+
+	conf, err := LoadConfiguration(nonExistentPath(t))
+	require.NoError(t, err)
+	assert.Equal(t, "http://prefixed.local", conf.GetTraefikInstances()[0].APIHost, "the prefixed variable wins")
+	assert.Equal(t, "debug", conf.GetLogLevel(), "unprefixed variables are used as a fallback")
+	assert.Equal(t, "nl", conf.GetLanguage(), "an empty prefixed variable falls back to the unprefixed one")
+}
+
+func TestGetenv_PrefixWithoutPrefixVar(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("TRALA_LOG_LEVEL", "debug")
+	t.Setenv("LOG_LEVEL", "info")
+	assert.Equal(t, "info", getenv("LOG_LEVEL"), "prefixed variables are ignored unless TRALA_ENV_PREFIX is set")
+
+	t.Setenv(EnvPrefixVar, "TRALA_")
+	assert.Equal(t, "debug", getenv("LOG_LEVEL"))
 }
 
 func TestLoadConfiguration_EnvInvalidValuesKeepDefaults(t *testing.T) {
@@ -696,9 +933,16 @@ func TestLoadConfiguration_EnvInvalidValuesKeepDefaults(t *testing.T) {
 	t.Setenv("REFRESH_INTERVAL_SECONDS", "not-a-number")
 	t.Setenv("TRAEFIK_INSECURE_SKIP_VERIFY", "maybe")
 	t.Setenv("GROUPING_ENABLED", "nope")
-	t.Setenv("GROUPING_TAG_FREQUENCY_THRESHOLD", "2.0") // >1 is invalid
-	t.Setenv("GROUPING_MIN_SERVICES_PER_GROUP", "0")    // <1 is invalid
-	t.Setenv("GROUPED_COLUMNS", "99")                   // >6 is invalid
+	t.Setenv("GROUPING_TAG_FREQUENCY_THRESHOLD", "2.0")  // >1 is invalid
+	t.Setenv("GROUPING_MIN_SERVICES_PER_GROUP", "0")     // <1 is invalid
+	t.Setenv("GROUPED_COLUMNS", "99")                    // >6 is invalid
+	t.Setenv("ICON_CACHE_MAX_AGE_SECONDS", "-1")         // <0 is invalid
+	t.Setenv("REQUEST_TIMEOUT_SECONDS", "-5")            // <0 is invalid
+	t.Setenv("STALE_MAX_AGE_SECONDS", "soon")            // not a number
+	t.Setenv("DEFAULT_ENTRYPOINT_PORT", "70000")         // >65535 is invalid
+	t.Setenv("DEFAULT_ENTRYPOINT_SCHEME", "ftp")         // not http or https
+	t.Setenv("EXTERNAL_CLIENT_MAX_CONNS_PER_HOST", "-1") // <0 is invalid
+	t.Setenv("ICON_DISCOVERY_CONCURRENCY", "0")          // <1 is invalid
 
 	conf, err := LoadConfiguration(nonExistentPath(t))
 	require.NoError(t, err)
@@ -711,6 +955,12 @@ func TestLoadConfiguration_EnvInvalidValuesKeepDefaults(t *testing.T) {
 	assert.InDelta(t, 0.9, conf.GetTagFrequencyThreshold(), 1e-9)
 	assert.Equal(t, 2, conf.GetMinServicesPerGroup())
 	assert.Equal(t, 3, conf.GetGroupingColumns())
+	assert.Equal(t, 86400, conf.GetIconCacheMaxAgeSeconds())
+	assert.Equal(t, 20, conf.GetRequestTimeoutSeconds())
+	assert.Equal(t, 300, conf.GetStaleMaxAgeSeconds())
+	assert.Equal(t, DefaultEntryPointConfig{Scheme: "https"}, conf.GetDefaultEntryPoint())
+	assert.Equal(t, 20, conf.GetExternalClient().MaxConnsPerHost)
+	assert.Equal(t, 10, conf.GetIconDiscoveryConcurrency())
 }
 
 func TestLoadConfiguration_InvalidLogLevelFallsBackToInfo(t *testing.T) {
@@ -803,6 +1053,89 @@ environment:
 	assert.Equal(t, "alice", conf.GetTraefikInstances()[0].BasicAuth.Username)
 }
 
+func TestReadPasswordFile(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{"no newline", "s3cret"},
+		{"newline-terminated", "s3cret\n"},
+		{"CRLF-terminated", "s3cret\r\n"},
+		{"several trailing newlines", "s3cret\n\n"},
+		{"trailing spaces and tab", "s3cret \t\n"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			path := filepath.Join(t.TempDir(), "pw")
+			require.NoError(t, os.WriteFile(path, []byte(tc.content), 0o600))
+			got, err := ReadPasswordFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, "s3cret", got)
+		})
+	}
+
+	_, err := ReadPasswordFile(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestLoadConfiguration_BasicAuthPasswordPrecedence(t *testing.T) {
+	// Uses t.Setenv and captures the global logger: cannot run t.Parallel.
+	pwDir := t.TempDir()
+	configPwFile := filepath.Join(pwDir, "config-pw")
+	envPwFile := filepath.Join(pwDir, "env-pw")
+	require.NoError(t, os.WriteFile(configPwFile, []byte("config-file-password"), 0o600))
+	require.NoError(t, os.WriteFile(envPwFile, []byte("env-file-password"), 0o600))
+
+	cases := []struct {
+		name           string
+		inlinePassword string
+		inlinePwFile   string
+		envPassword    string
+		envPwFile      string
+		wantPassword   string
+		wantWarning    bool
+	}{
+		{name: "inline password only", inlinePassword: "inline", wantPassword: "inline"},
+		{name: "password file only", inlinePwFile: configPwFile, wantPassword: "config-file-password"},
+		{name: "password file wins over inline password", inlinePassword: "inline", inlinePwFile: configPwFile, wantPassword: "config-file-password", wantWarning: true},
+		{name: "env password replaces inline password", inlinePassword: "inline", envPassword: "env", wantPassword: "env", wantWarning: true},
+		{name: "password file wins over env password", inlinePwFile: configPwFile, envPassword: "env", wantPassword: "config-file-password", wantWarning: true},
+		{name: "env password file replaces config password file", inlinePwFile: configPwFile, envPwFile: envPwFile, wantPassword: "env-file-password", wantWarning: true},
+		{name: "env password file wins over inline password", inlinePassword: "inline", envPwFile: envPwFile, wantPassword: "env-file-password", wantWarning: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			t.Setenv("TRAEFIK_BASIC_AUTH_PASSWORD", tc.envPassword)
+			t.Setenv("TRAEFIK_BASIC_AUTH_PASSWORD_FILE", tc.envPwFile)
+			logs := captureLog(t)
+
+			yaml := fmt.Sprintf(`
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+    enable_basic_auth: true
+    basic_auth:
+      username: alice
+      password: %q
+      password_file: %q
+`, tc.inlinePassword, tc.inlinePwFile)
+			conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantPassword, conf.GetTraefikInstances()[0].BasicAuth.Password)
+			if tc.wantWarning {
+				assert.Contains(t, logs.String(), "multiple methods")
+			} else {
+				assert.NotContains(t, logs.String(), "multiple methods")
+			}
+		})
+	}
+}
+
 func TestLoadConfiguration_BasicAuthPasswordFileMissing(t *testing.T) {
 	clearConfigEnv(t)
 
@@ -929,3 +1262,436 @@ services:
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "must be a valid URL")
 }
+
+func TestLoadConfiguration_UserIconExtensionsNormalized(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+  user_icon_extensions: ["PNG", ".Svg", "", " ico "]
+`
+	path := writeConfigFile(t, yaml)
+
+	conf, err := LoadConfiguration(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{".png", ".svg", ".ico"}, conf.GetUserIconExtensions())
+	assert.True(t, conf.IsUserIconExtension(".ICO"))
+	assert.False(t, conf.IsUserIconExtension(".txt"))
+	assert.False(t, conf.IsUserIconExtension(""))
+}
+
+func TestBuildOverrideMap_ReportsDuplicates(t *testing.T) {
+	t.Parallel()
+	overrides := []ServiceOverride{
+		{Service: "svc-a", DisplayName: "First A"},
+		{Service: "svc-b", DisplayName: "B"},
+		{Service: "svc-a", DisplayName: "Second A"},
+		{Service: "svc-a", DisplayName: "Third A"},
+	}
+
+	overrideMap, duplicates := buildOverrideMap(overrides, false)
+	assert.Equal(t, []string{"svc-a"}, duplicates, "each duplicated service is reported once")
+	assert.Len(t, overrideMap, 2)
+	assert.Equal(t, "Third A", overrideMap["svc-a"].DisplayName, "the last definition wins")
+
+	_, duplicates = buildOverrideMap(overrides[:2], false)
+	assert.Empty(t, duplicates)
+}
+
+func TestSanitizeManualServices(t *testing.T) {
+	t.Parallel()
+	manual := []ManualService{
+		{Name: "", URL: "https://empty.example"},
+		{Name: "   ", URL: "https://blank.example"},
+		{Name: "  Wiki ", URL: " https://wiki.example \n", Priority: 3},
+	}
+
+	got := sanitizeManualServices(manual)
+	require.Len(t, got, 1, "services without a name are skipped")
+	assert.Equal(t, "Wiki", got[0].Name)
+	assert.Equal(t, "https://wiki.example", got[0].URL)
+	assert.Equal(t, 3, got[0].Priority)
+}
+
+func TestLoadConfiguration_TraefikProviders(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+    providers: ["Docker", "@file", " "]
+`
+	conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docker", "file"}, conf.GetTraefikProviders())
+
+	yaml = `
+version: "3.0"
+environment:
+  traefik:
+    providers: [kubernetescrd]
+    instances:
+      - name: a
+        api_host: "http://a.local"
+      - name: b
+        api_host: "http://b.local"
+`
+	conf, err = LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kubernetescrd"}, conf.GetTraefikProviders())
+}
+
+func TestLoadConfiguration_TraefikPreferredHostSuffix(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+    preferred_host_suffix: " .Example.COM "
+`
+	conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", conf.GetTraefikPreferredHostSuffix())
+}
+
+func TestNormalizeHostRewrites(t *testing.T) {
+	t.Parallel()
+	got := normalizeHostRewrites(map[string]string{
+		".Internal.": "Example.com",
+		" lab ":      ".lab.example.org",
+		"":           "example.net",
+		"home.arpa":  "",
+	})
+	assert.Equal(t, map[string]string{
+		"internal": "example.com",
+		"lab":      "lab.example.org",
+	}, got)
+}
+
+func TestNormalizeSelfhstIconURL(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+		warn string
+	}{
+		{"valid", "https://icons.example/", "https://icons.example/", ""},
+		{"adds trailing slash", "https://icons.example/svg", "https://icons.example/svg/", ""},
+		{"collapses trailing slashes", "https://icons.example//", "https://icons.example/", ""},
+		{"trims whitespace", " http://icons.example ", "http://icons.example/", ""},
+		{"adds scheme", "cdn.jsdelivr.net/gh/selfhst/icons", "https://cdn.jsdelivr.net/gh/selfhst/icons/", "has no scheme"},
+		{"malformed", "not-a-url", "not-a-url", "is not a valid URL"},
+		{"missing host", "https://", "https://", "is not a valid URL"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLog(t)
+			assert.Equal(t, tc.want, normalizeSelfhstIconURL(tc.in))
+			if tc.warn == "" {
+				assert.Empty(t, logs.String())
+			} else {
+				assert.Contains(t, logs.String(), tc.warn)
+			}
+		})
+	}
+}
+
+func TestGetPriorityOverride(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "grafana"
+      priority: 500
+    - service: "nas"
+      priority: 0
+    - service: "wiki"
+      group: "Docs"
+`
+	conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+
+	require.NotNil(t, conf.GetPriorityOverride("grafana"))
+	assert.Equal(t, 500, *conf.GetPriorityOverride("grafana"))
+	require.NotNil(t, conf.GetPriorityOverride("nas"), "an explicit zero is an override")
+	assert.Equal(t, 0, *conf.GetPriorityOverride("nas"))
+	assert.Nil(t, conf.GetPriorityOverride("wiki"), "overrides without priority use the router priority")
+	assert.Nil(t, conf.GetPriorityOverride("unknown"))
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"":              "",
+		"/":             "",
+		"trala":         "/trala",
+		"/trala/":       "/trala",
+		" /apps/trala ": "/apps/trala",
+	}
+	for input, want := range cases {
+		assert.Equal(t, want, normalizeBasePath(input), input)
+	}
+}
+
+func TestNormalizeDomains(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"lan", "home.example.com"}, normalizeDomains([]string{".LAN.", " ", "home.example.com "}))
+	assert.Empty(t, normalizeDomains(nil))
+}
+
+func TestNormalizeLinkRels(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"shortcut icon", "apple-touch-icon"}, normalizeLinkRels([]string{" Shortcut  Icon ", "", "APPLE-TOUCH-ICON"}))
+	assert.Empty(t, normalizeLinkRels(nil))
+}
+
+func TestGetExternalOverride(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "grafana"
+      external: true
+    - service: "nas"
+      external: false
+    - service: "wiki"
+      group: "Docs"
+`
+	conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+
+	require.NotNil(t, conf.GetExternalOverride("grafana"))
+	assert.True(t, *conf.GetExternalOverride("grafana"))
+	require.NotNil(t, conf.GetExternalOverride("nas"))
+	assert.False(t, *conf.GetExternalOverride("nas"))
+	assert.Nil(t, conf.GetExternalOverride("wiki"), "overrides without external classify by host")
+	assert.Nil(t, conf.GetExternalOverride("unknown"))
+}
+
+func TestLoadConfiguration_SkipsManualServicesWithoutName(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  manual:
+    - name: ""
+      url: "https://empty.example"
+    - name: "  "
+      url: "https://blank.example"
+    - name: " Wiki "
+      url: " https://wiki.example "
+`
+	path := writeConfigFile(t, yaml)
+	logs := captureLog(t)
+
+	conf, err := LoadConfiguration(path)
+	require.NoError(t, err)
+	manual := conf.GetManualServices()
+	require.Len(t, manual, 1)
+	assert.Equal(t, "Wiki", manual[0].Name)
+	assert.Equal(t, "https://wiki.example", manual[0].URL)
+	assert.Contains(t, logs.String(), "Skipping manual service #1 (url 'https://empty.example'): name is empty")
+	assert.Contains(t, logs.String(), "Skipping manual service #2 (url 'https://blank.example'): name is empty")
+}
+
+func TestLoadConfiguration_QuickLinks(t *testing.T) {
+	clearConfigEnv(t)
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  quick_links:
+    - name: " Docs "
+      url: " https://docs.example "
+      icon: "docs.svg"
+    - name: ""
+      url: "https://empty.example"
+`
+	conf, err := LoadConfiguration(writeConfigFile(t, yaml))
+	require.NoError(t, err)
+
+	assert.Equal(t, []QuickLink{{Name: "Docs", URL: "https://docs.example", Icon: "docs.svg"}}, conf.GetQuickLinks())
+	assert.Empty(t, conf.GetManualServices(), "quick links are not manual services")
+}
+
+func TestLoadConfiguration_ManualServicesFiles(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  manual_services_files: "services.d/*.yml"
+  manual:
+    - name: "Wiki"
+      url: "https://wiki.example"
+`)
+	dir := filepath.Join(filepath.Dir(path), "services.d")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	files := map[string]string{
+		"a-media.yml": "- name: Jellyfin\n  url: https://jellyfin.example\n  group: Media\n",
+		"b-docs.yml":  "- name: wiki\n  url: https://wiki2.example\n",
+		"c-bad.yml":   "name: [unclosed\n",
+		"ignored.txt": "- name: Ignored\n  url: https://ignored.example\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	logs := captureLog(t)
+
+	conf, err := LoadConfiguration(path)
+	require.NoError(t, err)
+
+	var names []string
+	for _, m := range conf.GetManualServices() {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"Wiki", "Jellyfin", "wiki"}, names, "file entries are appended in file name order")
+	assert.Equal(t, "Media", conf.GetManualServices()[1].Group)
+	assert.Contains(t, logs.String(), filepath.Join(dir, "b-docs.yml")+"[0]: name 'wiki' is also used by services.manual[0]")
+	assert.Contains(t, logs.String(), "Could not parse manual service file "+filepath.Join(dir, "c-bad.yml"))
+}
+
+func TestLoadConfiguration_ManualServicesFilesDuplicateAcrossFiles(t *testing.T) {
+	clearConfigEnv(t)
+	path := writeConfigFile(t, `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  manual_services_files: "services.d/*.yml"
+`)
+	dir := filepath.Join(filepath.Dir(path), "services.d")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.yml"), []byte("- name: NAS\n  url: https://nas.example\n- name: Grafana\n  url: https://grafana.example\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.yml"), []byte("- name: grafana\n  url: https://grafana2.example\n"), 0o600))
+	logs := captureLog(t)
+
+	conf, err := LoadConfiguration(path)
+	require.NoError(t, err)
+
+	assert.Len(t, conf.GetManualServices(), 3, "duplicates are reported, not dropped")
+	assert.Equal(t, 1, strings.Count(strings.ToLower(logs.String()), "'grafana'"), "a duplicate is reported once")
+	assert.Contains(t, logs.String(), filepath.Join(dir, "b.yml")+"[0]: name 'grafana' is also used by "+filepath.Join(dir, "a.yml")+"[1]")
+}
+
+func TestLoadConfiguration_ManualServicesFilesWithoutMatches(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("MANUAL_SERVICES_FILES", "/nonexistent/*.yml")
+	t.Setenv("TRAEFIK_API_HOST", "http://t.local")
+	logs := captureLog(t)
+
+	conf, err := LoadConfiguration(nonExistentPath(t))
+	require.NoError(t, err)
+
+	assert.Empty(t, conf.GetManualServices())
+	assert.Contains(t, logs.String(), "No manual service files match '/nonexistent/*.yml'")
+}
+
+func TestLoadConfiguration_CaseInsensitiveNames(t *testing.T) {
+	yaml := `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+  case_insensitive_names: %t
+services:
+  overrides:
+    - service: Grafana
+      display_name: "Dashboards"
+      group: Monitoring
+`
+	cases := []struct {
+		caseInsensitive bool
+		wantDisplayName string
+	}{
+		{false, ""},
+		{true, "Dashboards"},
+	}
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("case_insensitive_names=%t", tc.caseInsensitive), func(t *testing.T) {
+			clearConfigEnv(t)
+			conf, err := LoadConfiguration(writeConfigFile(t, fmt.Sprintf(yaml, tc.caseInsensitive)))
+			require.NoError(t, err)
+
+			assert.Equal(t, "Dashboards", conf.GetDisplayNameOverride("Grafana"), "exact case always matches")
+			assert.Equal(t, tc.wantDisplayName, conf.GetDisplayNameOverride("grafana"))
+			_, ok := conf.GetServiceOverride("GRAFANA")
+			assert.Equal(t, tc.caseInsensitive, ok)
+		})
+	}
+}
+
+func TestBuildOverrideMap_CaseInsensitiveDuplicates(t *testing.T) {
+	t.Parallel()
+	overrides := []ServiceOverride{
+		{Service: "Grafana", DisplayName: "First"},
+		{Service: "grafana", DisplayName: "Second"},
+	}
+
+	_, duplicates := buildOverrideMap(overrides, false)
+	assert.Empty(t, duplicates)
+
+	overrideMap, duplicates := buildOverrideMap(overrides, true)
+	assert.Equal(t, []string{"grafana"}, duplicates)
+	assert.Equal(t, "Second", overrideMap["grafana"].DisplayName)
+}
+
+func TestGetRevision_ChangesWithConfiguration(t *testing.T) {
+	t.Parallel()
+	a := &TralaConfiguration{Version: "4.0", Environment: EnvironmentConfiguration{RefreshIntervalSeconds: 30}}
+	b := &TralaConfiguration{Version: "4.0", Environment: EnvironmentConfiguration{RefreshIntervalSeconds: 30}}
+
+	require.NotEmpty(t, a.GetRevision())
+	assert.Equal(t, a.GetRevision(), b.GetRevision(), "equal configurations have the same revision")
+
+	b.Environment.RefreshIntervalSeconds = 60
+	assert.NotEqual(t, a.GetRevision(), b.GetRevision())
+}
+
+func TestGetRevision_LeavesOutSecrets(t *testing.T) {
+	t.Parallel()
+	a := &TralaConfiguration{Version: "4.0"}
+	a.Environment.Traefik.Instances = []TraefikInstanceConfig{{Name: "traefik", BasicAuth: TraefikBasicAuth{Username: "admin", Password: "secret"}}}
+	a.Environment.NotifyWebhookURL = "https://hooks.example/token-a"
+	b := &TralaConfiguration{Version: "4.0"}
+	b.Environment.Traefik.Instances = []TraefikInstanceConfig{{Name: "traefik", BasicAuth: TraefikBasicAuth{Username: "admin", Password: "other"}}}
+	b.Environment.NotifyWebhookURL = "https://hooks.example/token-b"
+
+	assert.Equal(t, a.GetRevision(), b.GetRevision(), "the published revision does not depend on secrets")
+}
+
+func TestLoadConfiguration_StoresRevision(t *testing.T) {
+	path := writeConfigFile(t, `
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+`)
+	c, err := LoadConfiguration(path)
+	require.NoError(t, err)
+	revision := c.GetRevision()
+	require.NotEmpty(t, revision)
+
+	c.Environment.SiteTitle = "Changed"
+	assert.Equal(t, revision, c.GetRevision(), "a loaded configuration is fingerprinted once")
+}
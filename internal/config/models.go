@@ -1,6 +1,9 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"net/url"
 	"strings"
 	"sync"
@@ -13,6 +16,7 @@ type TraefikInstanceConfig struct {
 	EnableBasicAuth    bool             `yaml:"enable_basic_auth"`
 	BasicAuth          TraefikBasicAuth `yaml:"basic_auth"`
 	InsecureSkipVerify bool             `yaml:"insecure_skip_verify"`
+	APIVersion         string           `yaml:"api_version,omitempty" validate:"omitempty,oneof=v2 v3"`
 }
 
 // TraefikConfig contains configuration for connecting to one or more Traefik instances.
@@ -23,10 +27,19 @@ type TraefikConfig struct {
 	EnableBasicAuth    bool             `yaml:"enable_basic_auth"`
 	BasicAuth          TraefikBasicAuth `yaml:"basic_auth"`
 	InsecureSkipVerify bool             `yaml:"insecure_skip_verify"`
+	APIVersion         string           `yaml:"api_version,omitempty"`
 
 	// Multi-instance fields (new format)
 	Instances []TraefikInstanceConfig `yaml:"instances" validate:"dive"`
 
+	// Providers limits discovered routers to these Traefik providers (e.g. "docker").
+	// It applies to all instances; empty means all providers.
+	Providers []string `yaml:"providers,omitempty"`
+
+	// PreferredHostSuffix selects the host of a rule with several Host matchers, such as
+	// Host(`app.example.com`) || Host(`app.internal`). It applies to all instances.
+	PreferredHostSuffix string `yaml:"preferred_host_suffix,omitempty"`
+
 	// Internal: set after parsing
 	IsMulti bool `yaml:"-"`
 }
@@ -38,23 +51,33 @@ type TraefikConfig struct {
 func (t TraefikConfig) MarshalYAML() (interface{}, error) {
 	if t.IsMulti {
 		return struct {
-			Instances []TraefikInstanceConfig `yaml:"instances"`
+			Instances           []TraefikInstanceConfig `yaml:"instances"`
+			Providers           []string                `yaml:"providers,omitempty"`
+			PreferredHostSuffix string                  `yaml:"preferred_host_suffix,omitempty"`
 		}{
-			Instances: t.Instances,
+			Instances:           t.Instances,
+			Providers:           t.Providers,
+			PreferredHostSuffix: t.PreferredHostSuffix,
 		}, nil
 	}
 	if len(t.Instances) > 0 {
 		inst := t.Instances[0]
 		return struct {
-			APIHost            string           `yaml:"api_host"`
-			EnableBasicAuth    bool             `yaml:"enable_basic_auth"`
-			BasicAuth          TraefikBasicAuth `yaml:"basic_auth"`
-			InsecureSkipVerify bool             `yaml:"insecure_skip_verify"`
+			APIHost             string           `yaml:"api_host"`
+			EnableBasicAuth     bool             `yaml:"enable_basic_auth"`
+			BasicAuth           TraefikBasicAuth `yaml:"basic_auth"`
+			InsecureSkipVerify  bool             `yaml:"insecure_skip_verify"`
+			APIVersion          string           `yaml:"api_version,omitempty"`
+			Providers           []string         `yaml:"providers,omitempty"`
+			PreferredHostSuffix string           `yaml:"preferred_host_suffix,omitempty"`
 		}{
-			APIHost:            inst.APIHost,
-			EnableBasicAuth:    inst.EnableBasicAuth,
-			BasicAuth:          inst.BasicAuth,
-			InsecureSkipVerify: inst.InsecureSkipVerify,
+			APIHost:             inst.APIHost,
+			EnableBasicAuth:     inst.EnableBasicAuth,
+			BasicAuth:           inst.BasicAuth,
+			InsecureSkipVerify:  inst.InsecureSkipVerify,
+			APIVersion:          inst.APIVersion,
+			Providers:           t.Providers,
+			PreferredHostSuffix: t.PreferredHostSuffix,
 		}, nil
 	}
 	return struct {
@@ -97,7 +120,10 @@ func (t *TraefikConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	t.EnableBasicAuth = aux.EnableBasicAuth
 	t.BasicAuth = aux.BasicAuth
 	t.InsecureSkipVerify = aux.InsecureSkipVerify
+	t.APIVersion = aux.APIVersion
 	t.Instances = aux.Instances
+	t.Providers = aux.Providers
+	t.PreferredHostSuffix = aux.PreferredHostSuffix
 	// Unlike the bare-list format above, an `instances:` key with a single entry is only
 	// multi-instance when no legacy single-instance fields are also set.
 	t.IsMulti = len(aux.Instances) > 1 || (len(aux.Instances) == 1 && aux.APIHost == "" && !aux.EnableBasicAuth)
@@ -108,17 +134,21 @@ func (t *TraefikConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 // Password can be provided directly or via a file path.
 type TraefikBasicAuth struct {
 	Username     string `yaml:"username"`
-	Password     string `yaml:"password"`
+	Password     string `yaml:"password" json:"-"`
 	PasswordFile string `yaml:"password_file"`
 }
 
 // ServiceOverride defines overrides for a specific service/router.
-// It allows customizing the display name, icon, and group for a service.
+// It allows customizing the display name, icon, group and external flag for a service, and
+// the host of a router whose rule does not name a single host.
 type ServiceOverride struct {
 	Service     string `yaml:"service" validate:"required"`
 	DisplayName string `yaml:"display_name,omitempty"`
 	Icon        string `yaml:"icon,omitempty"`
 	Group       string `yaml:"group,omitempty"`
+	External    *bool  `yaml:"external,omitempty"`
+	Priority    *int   `yaml:"priority,omitempty"`
+	Host        string `yaml:"host,omitempty"`
 }
 
 // ManualService defines a manually configured service.
@@ -130,21 +160,39 @@ type ManualService struct {
 	Priority int    `yaml:"priority,omitempty"`
 	Group    string `yaml:"group,omitempty"`
 	Host     string `yaml:"host,omitempty"`
+	External *bool  `yaml:"external,omitempty"`
+
+	// source is the file and index an entry from manual_services_files was loaded from, such
+	// as "services.d/media.yml[0]". It is empty for entries of the configuration file.
+	source string
+}
+
+// QuickLink defines a bookmark shown in the quick links section, apart from the services.
+type QuickLink struct {
+	Name string `yaml:"name" validate:"required"`
+	URL  string `yaml:"url" validate:"required,url"`
+	Icon string `yaml:"icon,omitempty"`
 }
 
-// ExcludeConfig defines patterns for excluding routers and entrypoints.
+// ExcludeConfig defines patterns for excluding routers, entrypoints and middlewares.
 // Supports wildcard patterns for flexible matching.
 type ExcludeConfig struct {
 	Routers     []string `yaml:"routers"`
 	Entrypoints []string `yaml:"entrypoints"`
+	Middlewares []string `yaml:"middlewares"`
 }
 
 // ServiceConfiguration contains service-related configuration options.
 // It includes exclusions, overrides, and manual service definitions.
 type ServiceConfiguration struct {
-	Exclude   ExcludeConfig     `yaml:"exclude"`
-	Overrides []ServiceOverride `yaml:"overrides" validate:"dive"`
-	Manual    []ManualService   `yaml:"manual" validate:"dive"`
+	Exclude             ExcludeConfig     `yaml:"exclude"`
+	Overrides           []ServiceOverride `yaml:"overrides" validate:"dive"`
+	Manual              []ManualService   `yaml:"manual" validate:"dive"`
+	QuickLinks          []QuickLink       `yaml:"quick_links" validate:"dive"`
+	ManualServicesFiles string            `yaml:"manual_services_files"`
+	IncludeDisabled     bool              `yaml:"include_disabled"`
+	IncludeTCP          bool              `yaml:"include_tcp"`
+	ManualPriority      int               `yaml:"manual_service_default_priority" validate:"gte=0"`
 }
 
 // GroupingConfig contains settings for automatic service grouping.
@@ -154,18 +202,95 @@ type GroupingConfig struct {
 	Columns               int     `yaml:"columns" validate:"gte=1,lte=6"`
 	TagFrequencyThreshold float64 `yaml:"tag_frequency_threshold" validate:"gt=0,lte=1"`
 	MinServicesPerGroup   int     `yaml:"min_services_per_group" validate:"gte=1"`
+	ManualGroup           string  `yaml:"manual_group"`
+}
+
+// IconProxyConfig contains settings for the server-side icon proxy.
+// The proxy only fetches icons from allow-listed hosts to avoid acting as an open proxy.
+type IconProxyConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	AllowedHosts []string `yaml:"allowed_hosts"`
+}
+
+// ExternalClientConfig contains connection pool limits for the HTTP client used for icon
+// discovery and the icon proxy. A value of 0 means no limit, except for MaxIdleConnsPerHost
+// where it selects the net/http default of 2.
+type ExternalClientConfig struct {
+	MaxIdleConns        int `yaml:"max_idle_conns" validate:"gte=0"`
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host" validate:"gte=0"`
+	MaxConnsPerHost     int `yaml:"max_conns_per_host" validate:"gte=0"`
+}
+
+// DefaultEntryPointConfig describes the entrypoint used for routers whose entrypoint is not
+// reported by Traefik. Name refers to an existing entrypoint; when it is empty or not found,
+// Port and Scheme describe an assumed one. A port of 0 disables the assumed entrypoint; an
+// empty scheme means https.
+type DefaultEntryPointConfig struct {
+	Name   string `yaml:"name"`
+	Port   int    `yaml:"port" validate:"gte=0,lte=65535"`
+	Scheme string `yaml:"scheme" validate:"omitempty,oneof=http https"`
 }
 
 // EnvironmentConfiguration contains environment-level configuration options.
 // These settings control the overall behavior of the application.
 type EnvironmentConfiguration struct {
-	SelfhstIconURL         string         `yaml:"selfhst_icon_url" validate:"required,url"`
-	SearchEngineURL        string         `yaml:"search_engine_url" validate:"required,url"`
-	RefreshIntervalSeconds int            `yaml:"refresh_interval_seconds" validate:"gte=1"`
-	LogLevel               string         `yaml:"log_level" validate:"oneof=info debug warn error"`
-	Traefik                TraefikConfig  `yaml:"traefik"`
-	Language               string         `yaml:"language"`
-	Grouping               GroupingConfig `yaml:"grouping"`
+	SelfhstIconURL           string                  `yaml:"selfhst_icon_url" validate:"required,url"`
+	UseSelfhstIcons          bool                    `yaml:"use_selfhst_icons"`
+	SearchEngineURL          string                  `yaml:"search_engine_url" validate:"required,url"`
+	SearchEngineIcon         string                  `yaml:"search_engine_icon"`
+	RefreshIntervalSeconds   int                     `yaml:"refresh_interval_seconds" validate:"gte=1"`
+	LogLevel                 string                  `yaml:"log_level" validate:"oneof=info debug warn error"`
+	Traefik                  TraefikConfig           `yaml:"traefik"`
+	Language                 string                  `yaml:"language"`
+	Grouping                 GroupingConfig          `yaml:"grouping"`
+	IconCacheMaxAgeSeconds   int                     `yaml:"icon_cache_max_age_seconds" validate:"gte=0"`
+	IconPlaceholder          string                  `yaml:"icon_placeholder"`
+	UserIconExtensions       []string                `yaml:"user_icon_extensions"`
+	UserIconStripPrefixes    []string                `yaml:"user_icon_strip_prefixes"`
+	UserIconStripSuffixes    []string                `yaml:"user_icon_strip_suffixes"`
+	IconProxy                IconProxyConfig         `yaml:"icon_proxy"`
+	NormalizeFavicons        bool                    `yaml:"normalize_favicons"`
+	ConvertICOFavicons       bool                    `yaml:"convert_ico_favicons"`
+	IconScrapeAllowedHosts   []string                `yaml:"icon_scrape_allowed_hosts"`
+	IconBlockedNetworks      []string                `yaml:"icon_blocked_networks"`
+	IconAllowedNetworks      []string                `yaml:"icon_allowed_networks"`
+	IconMinFuzzyLength       int                     `yaml:"icon_min_fuzzy_length" validate:"gte=0"`
+	IconCategoryMatching     bool                    `yaml:"icon_category_matching"`
+	IconMatchPath            bool                    `yaml:"icon_match_path"`
+	IconCacheDir             string                  `yaml:"icon_cache_dir"`
+	IconCacheTTLSeconds      int                     `yaml:"icon_cache_ttl_seconds" validate:"gte=0"`
+	ServerSideRender         bool                    `yaml:"server_side_render"`
+	RequestTimeoutSeconds    int                     `yaml:"request_timeout_seconds" validate:"gte=0"`
+	ServicesTimeoutSeconds   int                     `yaml:"services_timeout_seconds" validate:"gte=0"`
+	StatusCacheSeconds       int                     `yaml:"status_cache_seconds" validate:"gte=0"`
+	HealthCacheSeconds       int                     `yaml:"health_cache_seconds" validate:"gte=0"`
+	HealthCheckConfig        bool                    `yaml:"health_check_config"`
+	DebugEndpoints           bool                    `yaml:"debug_endpoints"`
+	RateLimitRetries         int                     `yaml:"rate_limit_retries" validate:"gte=0"`
+	RateLimitWaitSeconds     int                     `yaml:"rate_limit_max_wait_seconds" validate:"gte=0"`
+	WarmupConcurrency        int                     `yaml:"warmup_concurrency" validate:"gte=0"`
+	IconDiscoveryConcurrency int                     `yaml:"icon_discovery_concurrency" validate:"gte=1"`
+	MaxServices              int                     `yaml:"max_services" validate:"gte=0"`
+	HideUnhealthy            bool                    `yaml:"hide_unhealthy"`
+	StaleMaxAgeSeconds       int                     `yaml:"stale_max_age_seconds" validate:"gte=0"`
+	NotifyWebhookURL         string                  `yaml:"notify_webhook_url" validate:"omitempty,url" json:"-"`
+	NotifyDebounceSeconds    int                     `yaml:"notify_debounce_seconds" validate:"gte=0"`
+	CaseInsensitiveNames     bool                    `yaml:"case_insensitive_names"`
+	StripEntrypointPrefix    bool                    `yaml:"strip_entrypoint_prefix"`
+	HostRewrites             map[string]string       `yaml:"host_rewrites"`
+	DefaultDomain            string                  `yaml:"default_domain"`
+	DefaultEntryPoint        DefaultEntryPointConfig `yaml:"default_entrypoint"`
+	MiddlewareTags           bool                    `yaml:"middleware_tags"`
+	ExternalClient           ExternalClientConfig    `yaml:"external_client"`
+	TemplateDir              string                  `yaml:"template_dir"`
+	TranslationsDir          string                  `yaml:"translations_dir"`
+	MaintenanceMessage       string                  `yaml:"maintenance_message"`
+	SiteTitle                string                  `yaml:"site_title"`
+	LogoURL                  string                  `yaml:"logo_url"`
+	BasePath                 string                  `yaml:"base_path"`
+	InternalDomains          []string                `yaml:"internal_domains"`
+	IconContentTypes         []string                `yaml:"icon_content_types"`
+	IconLinkRels             []string                `yaml:"icon_link_rels"`
 }
 
 // TralaConfiguration is the root configuration structure.
@@ -174,6 +299,7 @@ type TralaConfiguration struct {
 	mu           sync.RWMutex
 	overrideMap  map[string]ServiceOverride
 	compatStatus ConfigStatus
+	revision     string
 
 	Version     string                   `yaml:"version" validate:"required"`
 	Environment EnvironmentConfiguration `yaml:"environment"`
@@ -215,18 +341,84 @@ func buildYAMLTagForPath() map[string]string {
 		fields   map[string]string
 	}{
 		{"EnvironmentConfiguration", map[string]string{
-			"SelfhstIconURL":         "selfhst_icon_url",
-			"SearchEngineURL":        "search_engine_url",
-			"RefreshIntervalSeconds": "refresh_interval_seconds",
-			"LogLevel":               "log_level",
-			"Traefik":                "traefik",
-			"Language":               "language",
-			"Grouping":               "grouping",
+			"SelfhstIconURL":           "selfhst_icon_url",
+			"UseSelfhstIcons":          "use_selfhst_icons",
+			"SearchEngineURL":          "search_engine_url",
+			"SearchEngineIcon":         "search_engine_icon",
+			"RefreshIntervalSeconds":   "refresh_interval_seconds",
+			"LogLevel":                 "log_level",
+			"Traefik":                  "traefik",
+			"Language":                 "language",
+			"Grouping":                 "grouping",
+			"IconCacheMaxAgeSeconds":   "icon_cache_max_age_seconds",
+			"IconPlaceholder":          "icon_placeholder",
+			"UserIconExtensions":       "user_icon_extensions",
+			"UserIconStripPrefixes":    "user_icon_strip_prefixes",
+			"UserIconStripSuffixes":    "user_icon_strip_suffixes",
+			"IconProxy":                "icon_proxy",
+			"NormalizeFavicons":        "normalize_favicons",
+			"ConvertICOFavicons":       "convert_ico_favicons",
+			"IconScrapeAllowedHosts":   "icon_scrape_allowed_hosts",
+			"IconBlockedNetworks":      "icon_blocked_networks",
+			"IconAllowedNetworks":      "icon_allowed_networks",
+			"IconMinFuzzyLength":       "icon_min_fuzzy_length",
+			"IconCategoryMatching":     "icon_category_matching",
+			"IconMatchPath":            "icon_match_path",
+			"IconCacheDir":             "icon_cache_dir",
+			"IconCacheTTLSeconds":      "icon_cache_ttl_seconds",
+			"ServerSideRender":         "server_side_render",
+			"RequestTimeoutSeconds":    "request_timeout_seconds",
+			"ServicesTimeoutSeconds":   "services_timeout_seconds",
+			"StatusCacheSeconds":       "status_cache_seconds",
+			"HealthCacheSeconds":       "health_cache_seconds",
+			"HealthCheckConfig":        "health_check_config",
+			"DebugEndpoints":           "debug_endpoints",
+			"RateLimitRetries":         "rate_limit_retries",
+			"RateLimitWaitSeconds":     "rate_limit_max_wait_seconds",
+			"WarmupConcurrency":        "warmup_concurrency",
+			"IconDiscoveryConcurrency": "icon_discovery_concurrency",
+			"MaxServices":              "max_services",
+			"HideUnhealthy":            "hide_unhealthy",
+			"StaleMaxAgeSeconds":       "stale_max_age_seconds",
+			"NotifyWebhookURL":         "notify_webhook_url",
+			"NotifyDebounceSeconds":    "notify_debounce_seconds",
+			"CaseInsensitiveNames":     "case_insensitive_names",
+			"StripEntrypointPrefix":    "strip_entrypoint_prefix",
+			"HostRewrites":             "host_rewrites",
+			"DefaultDomain":            "default_domain",
+			"DefaultEntryPoint":        "default_entrypoint",
+			"MiddlewareTags":           "middleware_tags",
+			"ExternalClient":           "external_client",
+			"TemplateDir":              "template_dir",
+			"TranslationsDir":          "translations_dir",
+			"MaintenanceMessage":       "maintenance_message",
+			"SiteTitle":                "site_title",
+			"BasePath":                 "base_path",
+			"LogoURL":                  "logo_url",
+			"InternalDomains":          "internal_domains",
+			"IconContentTypes":         "icon_content_types",
+			"IconLinkRels":             "icon_link_rels",
+		}},
+		{"ExternalClientConfig", map[string]string{
+			"MaxIdleConns":        "max_idle_conns",
+			"MaxIdleConnsPerHost": "max_idle_conns_per_host",
+			"MaxConnsPerHost":     "max_conns_per_host",
+		}},
+		{"DefaultEntryPointConfig", map[string]string{
+			"Name":   "name",
+			"Port":   "port",
+			"Scheme": "scheme",
+		}},
+		{"IconProxyConfig", map[string]string{
+			"Enabled":      "enabled",
+			"AllowedHosts": "allowed_hosts",
 		}},
 		{"TraefikConfig", map[string]string{
-			"Instances": "instances",
-			"Single":    "single",
-			"IsMulti":   "is_multi",
+			"Instances":           "instances",
+			"Single":              "single",
+			"IsMulti":             "is_multi",
+			"Providers":           "providers",
+			"PreferredHostSuffix": "preferred_host_suffix",
 		}},
 		{"TraefikInstanceConfig", map[string]string{
 			"Name":               "name",
@@ -234,7 +426,9 @@ func buildYAMLTagForPath() map[string]string {
 			"EnableBasicAuth":    "enable_basic_auth",
 			"BasicAuth":          "basic_auth",
 			"InsecureSkipVerify": "insecure_skip_verify",
+			"APIVersion":         "api_version",
 		}},
+
 		{"TraefikBasicAuth", map[string]string{
 			"Username":     "username",
 			"Password":     "password",
@@ -245,12 +439,15 @@ func buildYAMLTagForPath() map[string]string {
 			"Columns":               "columns",
 			"TagFrequencyThreshold": "tag_frequency_threshold",
 			"MinServicesPerGroup":   "min_services_per_group",
+			"ManualGroup":           "manual_group",
 		}},
 		{"ServiceOverride", map[string]string{
 			"Service":     "service",
 			"DisplayName": "display_name",
 			"Icon":        "icon",
 			"Group":       "group",
+			"External":    "external",
+			"Host":        "host",
 		}},
 		{"ManualService", map[string]string{
 			"Name":     "name",
@@ -259,6 +456,12 @@ func buildYAMLTagForPath() map[string]string {
 			"Priority": "priority",
 			"Group":    "group",
 			"Host":     "host",
+			"External": "external",
+		}},
+		{"QuickLink", map[string]string{
+			"Name": "name",
+			"URL":  "url",
+			"Icon": "icon",
 		}},
 	}
 
@@ -321,6 +524,15 @@ func (c *TralaConfiguration) GetExcludeEntrypoints() []string {
 	return result
 }
 
+// GetExcludeMiddlewares returns a copy of the list of middleware exclusion patterns.
+func (c *TralaConfiguration) GetExcludeMiddlewares() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Services.Exclude.Middlewares))
+	copy(result, c.Services.Exclude.Middlewares)
+	return result
+}
+
 // GetManualServices returns a copy of the list of manually configured services.
 func (c *TralaConfiguration) GetManualServices() []ManualService {
 	c.mu.RLock()
@@ -330,6 +542,15 @@ func (c *TralaConfiguration) GetManualServices() []ManualService {
 	return result
 }
 
+// GetQuickLinks returns a copy of the list of quick links.
+func (c *TralaConfiguration) GetQuickLinks() []QuickLink {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]QuickLink, len(c.Services.QuickLinks))
+	copy(result, c.Services.QuickLinks)
+	return result
+}
+
 // GetConfigCompatibilityStatus returns the configuration compatibility status.
 func (c *TralaConfiguration) GetConfigCompatibilityStatus() ConfigStatus {
 	c.mu.RLock()
@@ -349,6 +570,35 @@ func (c *TralaConfiguration) GetConfiguration() TralaConfiguration {
 	}
 }
 
+// GetRevision returns a short fingerprint of the current configuration. It changes whenever a
+// setting changes, so clients can detect configuration changes without comparing every value.
+// The revision is published on /api/status, so secrets such as passwords and the notification
+// webhook URL are left out of it. LoadConfiguration computes it once; a configuration built
+// another way, such as in tests, computes it on every call.
+func (c *TralaConfiguration) GetRevision() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.revision != "" {
+		return c.revision
+	}
+	return c.computeRevision()
+}
+
+// computeRevision fingerprints the settings, skipping fields tagged json:"-". The caller holds
+// c.mu.
+func (c *TralaConfiguration) computeRevision() string {
+	data, err := json.Marshal(struct {
+		Version     string
+		Environment EnvironmentConfiguration
+		Services    ServiceConfiguration
+	}{c.Version, c.Environment, c.Services})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
 // GetSelfhstIconURL returns the base URL for selfh.st icons.
 func (c *TralaConfiguration) GetSelfhstIconURL() string {
 	c.mu.RLock()
@@ -356,6 +606,13 @@ func (c *TralaConfiguration) GetSelfhstIconURL() string {
 	return c.Environment.SelfhstIconURL
 }
 
+// GetUseSelfhstIcons returns whether icons are looked up in the selfh.st icon library.
+func (c *TralaConfiguration) GetUseSelfhstIcons() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.UseSelfhstIcons
+}
+
 // GetLogLevel returns the configured log level.
 func (c *TralaConfiguration) GetLogLevel() string {
 	c.mu.RLock()
@@ -377,6 +634,14 @@ func (c *TralaConfiguration) GetSearchEngineURL() string {
 	return c.Environment.SearchEngineURL
 }
 
+// GetSearchEngineIcon returns the icon used when no icon is found for the search engine, or
+// empty string if none.
+func (c *TralaConfiguration) GetSearchEngineIcon() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.SearchEngineIcon
+}
+
 // GetRefreshIntervalSeconds returns the refresh interval in seconds.
 func (c *TralaConfiguration) GetRefreshIntervalSeconds() int {
 	c.mu.RLock()
@@ -384,6 +649,428 @@ func (c *TralaConfiguration) GetRefreshIntervalSeconds() int {
 	return c.Environment.RefreshIntervalSeconds
 }
 
+// GetIconCacheMaxAgeSeconds returns the Cache-Control max-age for served user icons.
+func (c *TralaConfiguration) GetIconCacheMaxAgeSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconCacheMaxAgeSeconds
+}
+
+// GetIconPlaceholder returns the path of the image served for missing user icons, or empty string if none.
+func (c *TralaConfiguration) GetIconPlaceholder() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconPlaceholder
+}
+
+// GetUserIconExtensions returns a copy of the file extensions accepted for user icons.
+func (c *TralaConfiguration) GetUserIconExtensions() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.UserIconExtensions))
+	copy(result, c.Environment.UserIconExtensions)
+	return result
+}
+
+// GetUserIconStripPrefixes returns a copy of the prefixes stripped from user icon names.
+func (c *TralaConfiguration) GetUserIconStripPrefixes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.UserIconStripPrefixes))
+	copy(result, c.Environment.UserIconStripPrefixes)
+	return result
+}
+
+// GetUserIconStripSuffixes returns a copy of the suffixes stripped from user icon names.
+func (c *TralaConfiguration) GetUserIconStripSuffixes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.UserIconStripSuffixes))
+	copy(result, c.Environment.UserIconStripSuffixes)
+	return result
+}
+
+// IsUserIconExtension reports whether ext (e.g. ".png") is an accepted user icon extension.
+// The comparison is case-insensitive.
+func (c *TralaConfiguration) IsUserIconExtension(ext string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, allowed := range c.Environment.UserIconExtensions {
+		if strings.EqualFold(allowed, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIconContentType reports whether mediaType (e.g. "application/octet-stream") is accepted
+// for discovered icons in addition to image types. The comparison is case-insensitive.
+func (c *TralaConfiguration) IsIconContentType(mediaType string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, allowed := range c.Environment.IconContentTypes {
+		if strings.EqualFold(strings.TrimSpace(allowed), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetIconLinkRels returns a copy of the <link rel> values searched for icons in a service's
+// HTML, in order of preference.
+func (c *TralaConfiguration) GetIconLinkRels() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.IconLinkRels))
+	copy(result, c.Environment.IconLinkRels)
+	return result
+}
+
+// GetIconProxyEnabled returns whether external icons are served through the icon proxy.
+func (c *TralaConfiguration) GetIconProxyEnabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconProxy.Enabled
+}
+
+// GetIconProxyAllowedHosts returns a copy of the hosts the icon proxy may fetch from.
+func (c *TralaConfiguration) GetIconProxyAllowedHosts() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.IconProxy.AllowedHosts))
+	copy(result, c.Environment.IconProxy.AllowedHosts)
+	return result
+}
+
+// GetIconScrapeAllowedHosts returns a copy of the service hosts TraLa may fetch favicons and
+// HTML from. An empty list allows every host.
+func (c *TralaConfiguration) GetIconScrapeAllowedHosts() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.IconScrapeAllowedHosts))
+	copy(result, c.Environment.IconScrapeAllowedHosts)
+	return result
+}
+
+// GetIconBlockedNetworks returns a copy of the networks external icon requests may not connect to.
+func (c *TralaConfiguration) GetIconBlockedNetworks() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.IconBlockedNetworks))
+	copy(result, c.Environment.IconBlockedNetworks)
+	return result
+}
+
+// GetIconAllowedNetworks returns a copy of the networks exempted from the blocked networks.
+func (c *TralaConfiguration) GetIconAllowedNetworks() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.IconAllowedNetworks))
+	copy(result, c.Environment.IconAllowedNetworks)
+	return result
+}
+
+// GetNormalizeFavicons returns whether discovered favicons are re-encoded to a uniform PNG.
+func (c *TralaConfiguration) GetNormalizeFavicons() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.NormalizeFavicons
+}
+
+// GetConvertICOFavicons returns whether discovered .ico favicons are converted to PNG.
+func (c *TralaConfiguration) GetConvertICOFavicons() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.ConvertICOFavicons
+}
+
+// GetIconMinFuzzyLength returns the minimum name length for fuzzy icon matching (0 disables the guard).
+func (c *TralaConfiguration) GetIconMinFuzzyLength() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconMinFuzzyLength
+}
+
+// GetIconCategoryMatching returns whether fuzzy selfh.st matches prefer icons in a category
+// suggested by the service's group or tags.
+func (c *TralaConfiguration) GetIconCategoryMatching() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconCategoryMatching
+}
+
+// GetIconMatchPath returns whether the last path segment of a service URL is used for icon
+// and tag matching when the router name has no exact selfh.st match.
+func (c *TralaConfiguration) GetIconMatchPath() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconMatchPath
+}
+
+// GetIconCacheDir returns the directory discovered icons are persisted in (empty disables the
+// disk cache).
+func (c *TralaConfiguration) GetIconCacheDir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconCacheDir
+}
+
+// GetIconCacheTTLSeconds returns how long a persisted icon is reused (0 keeps it forever).
+func (c *TralaConfiguration) GetIconCacheTTLSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconCacheTTLSeconds
+}
+
+// GetServerSideRender returns whether the initial service list is rendered into the HTML page.
+func (c *TralaConfiguration) GetServerSideRender() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.ServerSideRender
+}
+
+// GetRequestTimeoutSeconds returns the maximum duration of an API request. Zero disables the timeout.
+func (c *TralaConfiguration) GetRequestTimeoutSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.RequestTimeoutSeconds
+}
+
+// GetServicesTimeoutSeconds returns the time budget for building the service list, after which
+// the services processed so far are returned. Zero disables the budget.
+func (c *TralaConfiguration) GetServicesTimeoutSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.ServicesTimeoutSeconds
+}
+
+// GetStatusCacheSeconds returns how long the frontend configuration of /api/status is reused.
+// Zero disables the cache.
+func (c *TralaConfiguration) GetStatusCacheSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.StatusCacheSeconds
+}
+
+// GetHealthCacheSeconds returns how long the Traefik reachability result of /api/health is
+// reused. Zero disables the cache.
+func (c *TralaConfiguration) GetHealthCacheSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.HealthCacheSeconds
+}
+
+// GetHealthCheckConfig returns whether /api/health fails while the configuration is
+// incompatible with this version.
+func (c *TralaConfiguration) GetHealthCheckConfig() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.HealthCheckConfig
+}
+
+// GetDebugEndpoints returns whether requests may trigger an uncached discovery run, through
+// ?debug=1 on /api/services or POST /api/services/refresh.
+func (c *TralaConfiguration) GetDebugEndpoints() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.DebugEndpoints
+}
+
+// GetRateLimitRetries returns how often a Traefik API request answered with 429 is retried.
+// Zero disables retrying.
+func (c *TralaConfiguration) GetRateLimitRetries() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.RateLimitRetries
+}
+
+// GetRateLimitWaitSeconds returns the longest time in seconds waited before retrying a Traefik
+// API request answered with 429, regardless of its Retry-After header.
+func (c *TralaConfiguration) GetRateLimitWaitSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.RateLimitWaitSeconds
+}
+
+// GetWarmupConcurrency returns how many cache warmers run at the same time at startup.
+// Zero runs all of them at once.
+func (c *TralaConfiguration) GetWarmupConcurrency() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.WarmupConcurrency
+}
+
+// GetIconDiscoveryConcurrency returns how many routers of an instance are processed, and
+// have their icon discovered, at the same time. It is at least 1.
+func (c *TralaConfiguration) GetIconDiscoveryConcurrency() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.IconDiscoveryConcurrency
+}
+
+// GetMaxServices returns the maximum number of services returned to the dashboard. Zero means no limit.
+func (c *TralaConfiguration) GetMaxServices() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.MaxServices
+}
+
+// GetHideUnhealthy returns whether services without a healthy backend server are hidden.
+func (c *TralaConfiguration) GetHideUnhealthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.HideUnhealthy
+}
+
+// GetStaleMaxAgeSeconds returns how long the last successful service list of an unreachable
+// Traefik instance may still be served. Zero disables the fallback.
+func (c *TralaConfiguration) GetStaleMaxAgeSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.StaleMaxAgeSeconds
+}
+
+// GetNotifyWebhookURL returns the URL that receives service change notifications. Empty disables notifications.
+func (c *TralaConfiguration) GetNotifyWebhookURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.NotifyWebhookURL
+}
+
+// GetNotifyDebounceSeconds returns how long the service set must be unchanged before a notification is sent.
+func (c *TralaConfiguration) GetNotifyDebounceSeconds() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.NotifyDebounceSeconds
+}
+
+// GetCaseInsensitiveNames returns whether router names are matched case-insensitively
+// against overrides and exclude patterns.
+func (c *TralaConfiguration) GetCaseInsensitiveNames() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.CaseInsensitiveNames
+}
+
+// GetStripEntrypointPrefix returns whether a leading "<entrypoint>-" is removed from router names.
+func (c *TralaConfiguration) GetStripEntrypointPrefix() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.StripEntrypointPrefix
+}
+
+// GetHostRewrites returns a copy of the map of internal host suffixes to their external replacement.
+func (c *TralaConfiguration) GetHostRewrites() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make(map[string]string, len(c.Environment.HostRewrites))
+	for k, v := range c.Environment.HostRewrites {
+		result[k] = v
+	}
+	return result
+}
+
+// GetDefaultDomain returns the domain appended to host names without a dot. Empty disables it.
+func (c *TralaConfiguration) GetDefaultDomain() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.DefaultDomain
+}
+
+// GetDefaultEntryPoint returns the entrypoint used for routers whose entrypoint is not reported by Traefik.
+func (c *TralaConfiguration) GetDefaultEntryPoint() DefaultEntryPointConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.DefaultEntryPoint
+}
+
+// GetTraefikProviders returns a copy of the Traefik providers whose routers are shown.
+// An empty list means all providers.
+func (c *TralaConfiguration) GetTraefikProviders() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.Traefik.Providers))
+	copy(result, c.Environment.Traefik.Providers)
+	return result
+}
+
+// GetTraefikPreferredHostSuffix returns the domain suffix preferred among the hosts of a
+// rule with several Host matchers. Empty means no preference.
+func (c *TralaConfiguration) GetTraefikPreferredHostSuffix() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.Traefik.PreferredHostSuffix
+}
+
+// GetMiddlewareTags returns whether the middlewares of a router are added to its service's tags.
+func (c *TralaConfiguration) GetMiddlewareTags() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.MiddlewareTags
+}
+
+// GetExternalClient returns the connection pool limits of the external HTTP client.
+func (c *TralaConfiguration) GetExternalClient() ExternalClientConfig {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.ExternalClient
+}
+
+// GetTemplateDir returns the directory containing the index.html template.
+func (c *TralaConfiguration) GetTemplateDir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.TemplateDir
+}
+
+// GetTranslationsDir returns the directory containing the translation files.
+func (c *TralaConfiguration) GetTranslationsDir() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.TranslationsDir
+}
+
+// GetMaintenanceMessage returns the message shown as a banner on the dashboard, or "" for no
+// banner.
+func (c *TralaConfiguration) GetMaintenanceMessage() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.MaintenanceMessage
+}
+
+// GetSiteTitle returns the title that replaces the default page title and logo text, or "" to
+// keep the defaults.
+func (c *TralaConfiguration) GetSiteTitle() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.SiteTitle
+}
+
+// GetLogoURL returns the URL of the image that replaces the default logo, or "" to keep it.
+func (c *TralaConfiguration) GetLogoURL() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.LogoURL
+}
+
+// GetBasePath returns the URL path prefix under which TraLa is served, without a trailing
+// slash, or "" when it is served from the root.
+func (c *TralaConfiguration) GetBasePath() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.BasePath
+}
+
+// GetInternalDomains returns a copy of the domain suffixes of internal services. When empty,
+// every service is internal.
+func (c *TralaConfiguration) GetInternalDomains() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	result := make([]string, len(c.Environment.InternalDomains))
+	copy(result, c.Environment.InternalDomains)
+	return result
+}
+
 // GetGroupingEnabled returns whether grouping is enabled.
 func (c *TralaConfiguration) GetGroupingEnabled() bool {
 	c.mu.RLock()
@@ -412,6 +1099,35 @@ func (c *TralaConfiguration) GetMinServicesPerGroup() int {
 	return c.Environment.Grouping.MinServicesPerGroup
 }
 
+// GetIncludeDisabled returns whether routers whose Traefik status is not "enabled" are shown.
+func (c *TralaConfiguration) GetIncludeDisabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Services.IncludeDisabled
+}
+
+// GetIncludeTCP returns whether TCP routers are fetched and shown besides HTTP routers.
+func (c *TralaConfiguration) GetIncludeTCP() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Services.IncludeTCP
+}
+
+// GetManualServiceDefaultPriority returns the priority of manual services that set none.
+func (c *TralaConfiguration) GetManualServiceDefaultPriority() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Services.ManualPriority
+}
+
+// GetManualGroup returns the group of manual services without a group of their own, or empty
+// string if they are grouped by their tags like discovered services.
+func (c *TralaConfiguration) GetManualGroup() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Environment.Grouping.ManualGroup
+}
+
 // GetTraefikInstances returns all configured Traefik instances.
 func (c *TralaConfiguration) GetTraefikInstances() []TraefikInstanceConfig {
 	c.mu.RLock()
@@ -455,12 +1171,20 @@ func (c *TralaConfiguration) GetServiceOverrideMap() map[string]ServiceOverride
 	return result
 }
 
+// overrideKey returns the override map key for a router name. The caller must hold c.mu.
+func (c *TralaConfiguration) overrideKey(routerName string) string {
+	if c.Environment.CaseInsensitiveNames {
+		return strings.ToLower(routerName)
+	}
+	return routerName
+}
+
 // GetServiceOverride looks up a service override by router name.
 // Returns the override and true if found, or empty override and false if not.
 func (c *TralaConfiguration) GetServiceOverride(routerName string) (ServiceOverride, bool) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	override, ok := c.overrideMap[routerName]
+	override, ok := c.overrideMap[c.overrideKey(routerName)]
 	return override, ok
 }
 
@@ -468,7 +1192,7 @@ func (c *TralaConfiguration) GetServiceOverride(routerName string) (ServiceOverr
 func (c *TralaConfiguration) GetIconOverride(routerName string) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if override, ok := c.overrideMap[routerName]; ok {
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok {
 		return override.Icon
 	}
 	return ""
@@ -478,7 +1202,7 @@ func (c *TralaConfiguration) GetIconOverride(routerName string) string {
 func (c *TralaConfiguration) GetDisplayNameOverride(routerName string) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if override, ok := c.overrideMap[routerName]; ok {
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok {
 		return override.DisplayName
 	}
 	return ""
@@ -488,12 +1212,47 @@ func (c *TralaConfiguration) GetDisplayNameOverride(routerName string) string {
 func (c *TralaConfiguration) GetGroupOverride(routerName string) string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	if override, ok := c.overrideMap[routerName]; ok {
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok {
 		return override.Group
 	}
 	return ""
 }
 
+// GetHostOverride returns the host used for a router name whose rule does not name a single
+// host, or empty string if none.
+func (c *TralaConfiguration) GetHostOverride(routerName string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok {
+		return override.Host
+	}
+	return ""
+}
+
+// GetExternalOverride returns the external flag override for a router name, or nil if the
+// service should be classified by its host.
+func (c *TralaConfiguration) GetExternalOverride(routerName string) *bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok && override.External != nil {
+		external := *override.External
+		return &external
+	}
+	return nil
+}
+
+// GetPriorityOverride returns the dashboard priority override for a router name, or nil if
+// the router priority is used.
+func (c *TralaConfiguration) GetPriorityOverride(routerName string) *int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if override, ok := c.overrideMap[c.overrideKey(routerName)]; ok && override.Priority != nil {
+		priority := *override.Priority
+		return &priority
+	}
+	return nil
+}
+
 // DefaultInstanceName derives a default instance name from an API host URL.
 func DefaultInstanceName(apiHost string) string {
 	u, err := url.Parse(apiHost)
@@ -2,16 +2,161 @@ package config
 
 import (
 	"fmt"
+	"log"
+	"net"
+	"path"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// Severity describes how serious a configuration problem is.
+type Severity int
+
+const (
+	// SeverityWarning marks a problem that is logged but does not stop startup.
+	SeverityWarning Severity = iota
+	// SeverityFatal marks a problem that prevents the configuration from being loaded.
+	SeverityFatal
+)
+
+// String returns the lower-case name of the severity.
+func (s Severity) String() string {
+	if s == SeverityFatal {
+		return "fatal"
+	}
+	return "warning"
+}
+
+// ValidationIssue describes a single problem found while validating the configuration.
+type ValidationIssue struct {
+	Severity Severity
+	Message  string
+}
+
 // Validate checks the configuration struct against struct-tag rules.
 func Validate(c *TralaConfiguration) error {
+	verrs, err := validateStruct(c)
+	if err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if len(verrs) > 0 {
+		return fmt.Errorf("configuration validation failed: %s", formatValidationErrors(verrs))
+	}
+	return nil
+}
+
+// ValidateConfiguration runs the complete validation pass and returns every problem found,
+// instead of stopping at the first one. Struct-tag violations are fatal; the semantic
+// checks from ValidateSemantics are reported with their own severity.
+func ValidateConfiguration(c *TralaConfiguration) []ValidationIssue {
+	verrs, err := validateStruct(c)
+	if err != nil {
+		return []ValidationIssue{{Severity: SeverityFatal, Message: err.Error()}}
+	}
+
+	var issues []ValidationIssue
+	for _, verr := range verrs {
+		fullPath := buildYAMLPath(verr)
+		msg := formatValidationMessage(verr, fullPath, envVarForField(fullPath))
+		if !strings.Contains(msg, fullPath) {
+			msg = fullPath + ": " + msg
+		}
+		issues = append(issues, ValidationIssue{Severity: SeverityFatal, Message: msg})
+	}
+	return append(issues, ValidateSemantics(c)...)
+}
+
+// manualServiceLocation names where manual service i was defined: its index in services.manual,
+// or the file and index it was loaded from with manual_services_files.
+func manualServiceLocation(manual []ManualService, i int) string {
+	if manual[i].source != "" {
+		return manual[i].source
+	}
+	return fmt.Sprintf("services.manual[%d]", i)
+}
+
+// ValidateSemantics checks rules that span several fields and cannot be expressed as struct tags.
+func ValidateSemantics(c *TralaConfiguration) []ValidationIssue {
+	if c == nil {
+		return nil
+	}
+
+	var issues []ValidationIssue
+	warn := func(format string, args ...interface{}) {
+		issues = append(issues, ValidationIssue{Severity: SeverityWarning, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for i, o := range c.Services.Overrides {
+		if o.Service != "" && o.DisplayName == "" && o.Icon == "" && o.Group == "" && o.External == nil && o.Priority == nil {
+			warn("services.overrides[%d]: override for '%s' sets none of display_name, icon, group, external or priority and has no effect", i, o.Service)
+		}
+	}
+
+	seenManual := make(map[string]int, len(c.Services.Manual))
+	for i, m := range c.Services.Manual {
+		key := strings.ToLower(m.Name)
+		if key == "" {
+			continue
+		}
+		if first, ok := seenManual[key]; ok {
+			warn("%s: name '%s' is also used by %s", manualServiceLocation(c.Services.Manual, i), m.Name, manualServiceLocation(c.Services.Manual, first))
+			continue
+		}
+		seenManual[key] = i
+	}
+
+	for i, pattern := range c.Services.Exclude.Routers {
+		if strings.TrimSpace(pattern) == "" {
+			warn("services.exclude.routers[%d]: empty pattern is ignored", i)
+		}
+	}
+	for i, pattern := range c.Services.Exclude.Entrypoints {
+		if strings.TrimSpace(pattern) == "" {
+			warn("services.exclude.entrypoints[%d]: empty pattern is ignored", i)
+		}
+	}
+
+	if services, request := c.Environment.ServicesTimeoutSeconds, c.Environment.RequestTimeoutSeconds; services > 0 && request > 0 && services >= request {
+		warn("environment.services_timeout_seconds: %d is not below request_timeout_seconds (%d), so slow requests fail instead of returning partial results", services, request)
+	}
+
+	if c.Environment.IconProxy.Enabled && len(c.Environment.IconProxy.AllowedHosts) == 0 {
+		warn("environment.icon_proxy.allowed_hosts: icon proxy is enabled but no hosts are allowed, so no icons will be proxied")
+	}
+	for i, host := range c.Environment.IconProxy.AllowedHosts {
+		if strings.Contains(host, "/") {
+			warn("environment.icon_proxy.allowed_hosts[%d]: '%s' must be a host name without scheme or path", i, host)
+		}
+	}
+	for i, host := range c.Environment.IconScrapeAllowedHosts {
+		if strings.Contains(host, "/") {
+			warn("environment.icon_scrape_allowed_hosts[%d]: '%s' must be a host name or pattern without scheme or path", i, host)
+		} else if _, err := path.Match(strings.ToLower(strings.TrimSpace(host)), ""); err != nil {
+			warn("environment.icon_scrape_allowed_hosts[%d]: invalid pattern '%s': %v", i, host, err)
+		}
+	}
+	for i, network := range c.Environment.IconBlockedNetworks {
+		if !IsNetworkEntry(network) {
+			warn("environment.icon_blocked_networks[%d]: '%s' is not an IP address, CIDR range or one of %s", i, network, strings.Join(NetworkClasses, ", "))
+		}
+	}
+	for i, network := range c.Environment.IconAllowedNetworks {
+		if !IsNetworkEntry(network) {
+			warn("environment.icon_allowed_networks[%d]: '%s' is not an IP address, CIDR range or one of %s", i, network, strings.Join(NetworkClasses, ", "))
+		}
+	}
+
+	return issues
+}
+
+// validateStruct runs the struct-tag validator and returns its field errors.
+// The error is only set for failures that are not field validation errors.
+func validateStruct(c *TralaConfiguration) (validator.ValidationErrors, error) {
 	if c == nil {
-		return fmt.Errorf("configuration validation failed: nil config")
+		return nil, fmt.Errorf("nil config")
 	}
 
 	validate := validator.New()
@@ -27,11 +172,11 @@ func Validate(c *TralaConfiguration) error {
 
 	if err := validate.Struct(c); err != nil {
 		if verrs, ok := err.(validator.ValidationErrors); ok {
-			return fmt.Errorf("configuration validation failed: %s", formatValidationErrors(verrs))
+			return verrs, nil
 		}
-		return fmt.Errorf("configuration validation failed: %w", err)
+		return nil, err
 	}
-	return nil
+	return nil, nil
 }
 
 func formatValidationErrors(verrs validator.ValidationErrors) string {
@@ -119,3 +264,41 @@ func enrichmentMsg(envVar, fullPath, detail string) string {
 	}
 	return fmt.Sprintf("config field %s or env var %s: %s", fullPath, envVar, detail)
 }
+
+// reportValidationIssues logs all validation issues together and returns an error
+// listing the fatal ones, if any.
+func reportValidationIssues(issues []ValidationIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	var fatal []string
+	log.Printf("Configuration validation found %d problem(s):", len(issues))
+	for _, issue := range issues {
+		log.Printf("  - [%s] %s", issue.Severity, issue.Message)
+		if issue.Severity == SeverityFatal {
+			fatal = append(fatal, issue.Message)
+		}
+	}
+
+	if len(fatal) > 0 {
+		return fmt.Errorf("configuration validation failed: %s", strings.Join(fatal, "; "))
+	}
+	return nil
+}
+
+// NetworkClasses are the address classes accepted in the icon network lists besides IP
+// addresses and CIDR ranges.
+var NetworkClasses = []string{"loopback", "private", "link-local"}
+
+// IsNetworkEntry reports whether entry is an IP address, a CIDR range or a network class.
+func IsNetworkEntry(entry string) bool {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if slices.Contains(NetworkClasses, entry) {
+		return true
+	}
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return true
+	}
+	return net.ParseIP(entry) != nil
+}
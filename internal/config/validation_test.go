@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -200,6 +201,14 @@ func TestValidate_OutOfRangeValues(t *testing.T) {
 			wantField: "environment.grouping.min_services_per_group",
 			wantTag:   ">=",
 		},
+		{
+			name: "icon discovery concurrency zero",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconDiscoveryConcurrency = 0
+			},
+			wantField: "environment.icon_discovery_concurrency",
+			wantTag:   ">=",
+		},
 	}
 
 	for _, tc := range cases {
@@ -215,6 +224,18 @@ func TestValidate_OutOfRangeValues(t *testing.T) {
 	}
 }
 
+func TestValidate_InvalidTraefikAPIVersion(t *testing.T) {
+	t.Parallel()
+	c := newPopulatedConfig()
+	c.Environment.Traefik.Instances[0].APIVersion = "v1"
+	err := Validate(c)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "api_version")
+
+	c.Environment.Traefik.Instances[0].APIVersion = ""
+	require.NoError(t, Validate(c), "an empty api version means auto-detection")
+}
+
 func TestValidate_InvalidLogLevel(t *testing.T) {
 	t.Parallel()
 	c := newPopulatedConfig()
@@ -231,3 +252,122 @@ func TestValidate_NilConfig(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "nil config")
 }
+
+func TestValidateConfiguration_CollectsAllIssues(t *testing.T) {
+	t.Parallel()
+	c := newPopulatedConfig()
+	c.Environment.SelfhstIconURL = "not-a-url"
+	c.Environment.Grouping.Columns = 9
+	c.Services.Overrides = append(c.Services.Overrides, ServiceOverride{Service: "svc-c"})
+
+	issues := ValidateConfiguration(c)
+
+	var fatal, warnings []string
+	for _, issue := range issues {
+		if issue.Severity == SeverityFatal {
+			fatal = append(fatal, issue.Message)
+		} else {
+			warnings = append(warnings, issue.Message)
+		}
+	}
+	require.Len(t, fatal, 2)
+	assert.Contains(t, fatal[0]+fatal[1], "selfhst_icon_url")
+	assert.Contains(t, fatal[0]+fatal[1], "columns")
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "svc-c")
+}
+
+func TestValidateSemantics(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name     string
+		mutate   func(*TralaConfiguration)
+		wantPath string
+	}{
+		{
+			name: "override without effect",
+			mutate: func(c *TralaConfiguration) {
+				c.Services.Overrides[0] = ServiceOverride{Service: "svc-a"}
+			},
+			wantPath: "services.overrides[0]",
+		},
+		{
+			name: "duplicate manual service name",
+			mutate: func(c *TralaConfiguration) {
+				c.Services.Manual = append(c.Services.Manual, ManualService{Name: strings.ToUpper(c.Services.Manual[0].Name), URL: "https://other.example"})
+			},
+			wantPath: "services.manual[1]",
+		},
+		{
+			name: "empty exclude pattern",
+			mutate: func(c *TralaConfiguration) {
+				c.Services.Exclude.Routers = append(c.Services.Exclude.Routers, " ")
+			},
+			wantPath: "services.exclude.routers[2]",
+		},
+		{
+			name: "services timeout not below request timeout",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.RequestTimeoutSeconds = 10
+				c.Environment.ServicesTimeoutSeconds = 10
+			},
+			wantPath: "environment.services_timeout_seconds",
+		},
+		{
+			name: "icon proxy without allowed hosts",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconProxy = IconProxyConfig{Enabled: true}
+			},
+			wantPath: "environment.icon_proxy.allowed_hosts",
+		},
+		{
+			name: "icon proxy host with scheme",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconProxy = IconProxyConfig{Enabled: true, AllowedHosts: []string{"https://cdn.example"}}
+			},
+			wantPath: "environment.icon_proxy.allowed_hosts[0]",
+		},
+		{
+			name: "icon scrape host with scheme",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconScrapeAllowedHosts = []string{"home.lan", "http://nas.lan"}
+			},
+			wantPath: "environment.icon_scrape_allowed_hosts[1]",
+		},
+		{
+			name: "invalid icon scrape pattern",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconScrapeAllowedHosts = []string{"[.lan"}
+			},
+			wantPath: "environment.icon_scrape_allowed_hosts[0]",
+		},
+		{
+			name: "invalid blocked network",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconBlockedNetworks = []string{"private", "10.0.0.0/33"}
+			},
+			wantPath: "environment.icon_blocked_networks[1]",
+		},
+		{
+			name: "invalid allowed network",
+			mutate: func(c *TralaConfiguration) {
+				c.Environment.IconAllowedNetworks = []string{"metadata"}
+			},
+			wantPath: "environment.icon_allowed_networks[0]",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			c := newPopulatedConfig()
+			require.Empty(t, ValidateSemantics(c))
+			tc.mutate(c)
+			issues := ValidateSemantics(c)
+			require.Len(t, issues, 1)
+			assert.Equal(t, SeverityWarning, issues[0].Severity)
+			assert.Contains(t, issues[0].Message, tc.wantPath)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package debug
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Trace collects the debug messages of a single request, so they can be returned to the
+// client without raising the global log level.
+type Trace struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+type traceKey struct{}
+
+// WithTrace returns a context that records the messages passed to Tracef in a new Trace.
+func WithTrace(ctx context.Context) (context.Context, *Trace) {
+	trace := &Trace{}
+	return context.WithValue(ctx, traceKey{}, trace), trace
+}
+
+// Tracef logs a message like Debugf and, when ctx carries a Trace, also records it there.
+func Tracef(ctx context.Context, format string, v ...interface{}) {
+	Debugf(format, v...)
+	if trace, ok := ctx.Value(traceKey{}).(*Trace); ok {
+		trace.mu.Lock()
+		trace.messages = append(trace.messages, fmt.Sprintf(format, v...))
+		trace.mu.Unlock()
+	}
+}
+
+// Messages returns a copy of the recorded messages in the order they were traced.
+func (t *Trace) Messages() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string{}, t.messages...)
+}
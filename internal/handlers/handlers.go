@@ -3,7 +3,8 @@
 package handlers
 
 import (
-	"context"
+	_ "embed"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -11,21 +12,18 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"sort"
+	runtimedebug "runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/nicksnyder/go-i18n/v2/i18n"
-
 	"server/internal/config"
 	"server/internal/debug"
 	appi18n "server/internal/i18n"
-	"server/internal/icons"
 	"server/internal/models"
-	"server/internal/providers"
 	"server/internal/services"
-	"server/internal/traefik"
+	"server/web"
 )
 
 // --- Version Information ---
@@ -62,32 +60,23 @@ var (
 	parsedTemplate *template.Template
 )
 
-// LoadHTMLTemplate reads the index.html file into memory once and parses it.
-// The template is parsed with i18n support via a "T" function that accepts a localizer.
+// LoadHTMLTemplate reads the index.html file into memory once and parses it. When the file does
+// not exist, the template embedded in the binary is used.
+// The template is parsed with i18n support via "T" and "TN" (plural) functions that accept a
+// localizer, plus a "FormatDate" function that formats the build time for a language code.
 func LoadHTMLTemplate(templatePath string) {
 	htmlOnce.Do(func() {
 		var err error
 		templatePath := filepath.Join(templatePath, "index.html")
 		htmlTemplate, err = os.ReadFile(templatePath)
+		if os.IsNotExist(err) {
+			log.Printf("Template %s not found, using built-in template", templatePath)
+			htmlTemplate, err = web.IndexHTML, nil
+		}
 		if err != nil {
 			log.Fatalf("FATAL: Could not read index.html template at %s: %v", templatePath, err)
 		}
-		// Parse template once and register a T function that expects a *i18n.Localizer
-		// as first argument. The handler will pass the request-local Localizer via
-		// the template data as "Localizer".
-		tmpl, err := template.New("index").Funcs(template.FuncMap{
-			"T": func(localizer *i18n.Localizer, id string) string {
-				if localizer == nil {
-					return id
-				}
-				msg, err := localizer.Localize(&i18n.LocalizeConfig{MessageID: id})
-				if err != nil {
-					return id
-				}
-				return msg
-			},
-		}).Parse(string(htmlTemplate))
-
+		tmpl, err := parseHTMLTemplate(string(htmlTemplate))
 		if err != nil {
 			log.Fatalf("FATAL: Could not parse index.html: %v", err)
 		}
@@ -95,6 +84,17 @@ func LoadHTMLTemplate(templatePath string) {
 	})
 }
 
+// parseHTMLTemplate parses an HTML template with the i18n template functions. "T" and "TN" take
+// the request-local localizer, passed in the template data as "Localizer", as first argument
+// and return the message ID when it is nil or the message is missing.
+func parseHTMLTemplate(content string) (*template.Template, error) {
+	return template.New("index").Funcs(template.FuncMap{
+		"T":          appi18n.LocalizeFunc,
+		"TN":         appi18n.LocalizePluralFunc,
+		"FormatDate": appi18n.FormatBuildTime,
+	}).Parse(content)
+}
+
 // --- Security Middleware ---
 
 // SecurityHeaders wraps an http.Handler to add security headers to all responses.
@@ -109,6 +109,62 @@ func SecurityHeaders(next http.Handler) http.Handler {
 	})
 }
 
+// Recover wraps an http.Handler so a panic in a handler is logged with its stack trace and
+// answered with a plain 500 instead of an aborted connection. When the handler had already
+// started its response, the connection is aborted after all, so the client sees a truncated
+// response instead of an error message appended to it.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverWriter{ResponseWriter: w}
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				log.Printf("ERROR: Panic while serving %s %s: %v\n%s", r.Method, r.URL.Path, rec, runtimedebug.Stack())
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// recoverWriter records whether a handler started its response.
+type recoverWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (w *recoverWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recoverWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController can reach it.
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RequestTimeout wraps an http.Handler so requests that take longer than the configured
+// request timeout are answered with 503 Service Unavailable instead of hanging.
+// A timeout of zero returns next unchanged. The timeout is read once, when the handler is
+// wrapped. Not suited for streaming responses.
+func RequestTimeout(c *config.TralaConfiguration, next http.Handler) http.Handler {
+	seconds := c.GetRequestTimeoutSeconds()
+	if seconds <= 0 {
+		return next
+	}
+	return http.TimeoutHandler(next, time.Duration(seconds)*time.Second, "Request timed out")
+}
+
 // --- HTTP Handlers ---
 
 // ServeHTMLTemplate renders the HTML template with i18n support using go-i18n.
@@ -124,8 +180,21 @@ func ServeHTMLTemplate(c *config.TralaConfiguration) http.HandlerFunc {
 
 		// Execute the pre-parsed template and pass the request-local Localizer in data.
 		// Templates must call the function like: {{ T .Localizer "message.id" }}
+		// or, for plurals: {{ TN .Localizer "message.id" 3 }}
+		// Locale-aware formatting uses the language code: {{ FormatDate .Lang .BuildTime }}
+		// SiteTitle and LogoURL are empty unless the instance is branded.
 		data := map[string]interface{}{
 			"Localizer": localizer,
+			"Lang":      lang,
+			"BuildTime": buildTime,
+			"SiteTitle": c.GetSiteTitle(),
+			"LogoURL":   c.GetLogoURL(),
+		}
+
+		// Optionally render the initial service list server-side for no-JS clients and a
+		// faster first paint. The frontend replaces it once its own fetch completes.
+		if c.GetServerSideRender() {
+			data["Services"] = services.CachedServiceList(r.Context(), c).Services
 		}
 		if err := parsedTemplate.Execute(w, data); err != nil {
 			http.Error(w, "Template execution error", http.StatusInternalServerError)
@@ -133,49 +202,156 @@ func ServeHTMLTemplate(c *config.TralaConfiguration) http.HandlerFunc {
 	}
 }
 
-// ServicesHandler is the main API endpoint. It fetches, processes, and returns all service data.
+// ServicesHandler is the main API endpoint. It returns all service data from the cached service
+// list, see services.CachedServiceList. When debug_endpoints is enabled, ?debug=1 runs
+// discovery for the request and wraps the services in an object together with its debug
+// messages, without changing the global log level. Otherwise the parameter is ignored, so anonymous requests
+// cannot bypass the cache. With ?envelope=1, the services are wrapped in an object that also
+// reports whether max_services truncated them.
 func ServicesHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		instances := c.GetTraefikInstances()
-		var allServices []models.Service
-
-		for _, instance := range instances {
-			provider := providers.NewTraefikProvider(instance)
-			services, err := provider.FetchServices(r.Context())
-			if err != nil {
-				log.Printf("WARNING: Failed to fetch services from instance %s: %v", instance.Name, err)
-				continue
-			}
-		for _, svc := range services {
-			allServices = append(allServices, models.Service{
-				Name:     svc.Name,
-				URL:      svc.URL,
-				Priority: svc.Priority,
-				Icon:     svc.Icon,
-				Tags:     svc.Tags,
-				Group:    svc.Group,
-				Host:     instance.Name,
-			})
+		serveServiceList(w, r, c, false)
+	}
+}
+
+// RefreshServicesHandler runs the discovery pipeline on a POST request and returns the fresh
+// service list like ServicesHandler. Unreachable instances are not served from their last
+// snapshot; the request fails with 502 instead, so scripts can check that discovery works.
+// A successful refresh replaces the cached service list. The handler returns 404 unless
+// debug_endpoints is enabled.
+func RefreshServicesHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.GetDebugEndpoints() {
+			http.NotFound(w, r)
+			return
 		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
+		serveServiceList(w, r, c, true)
+	}
+}
 
-		manualServices := services.GetManualServices()
-		finalServices := make([]models.Service, 0, len(allServices)+len(manualServices))
-		finalServices = append(finalServices, allServices...)
-		finalServices = append(finalServices, manualServices...)
+// serveServiceList writes the cached service list as JSON. With fresh set, the list is built
+// without snapshots and an instance that cannot be fetched fails the request. The body is a
+// plain array unless the request asks for the envelope object.
+func serveServiceList(w http.ResponseWriter, r *http.Request, c *config.TralaConfiguration, fresh bool) {
+	ctx := r.Context()
+	var trace *debug.Trace
+	if traced, _ := strconv.ParseBool(r.URL.Query().Get("debug")); traced && c.GetDebugEndpoints() {
+		ctx, trace = debug.WithTrace(ctx)
+	}
+	var list services.ServiceList
+	switch {
+	case fresh:
+		list = services.RefreshServiceList(ctx, c)
+		if len(list.Failed) > 0 {
+			http.Error(w, "Could not fetch services from "+strings.Join(list.Failed, ", "), http.StatusBadGateway)
+			return
+		}
+	case trace != nil:
+		// A traced request runs discovery itself to collect its debug messages.
+		list = services.BuildServiceList(ctx, c, true)
+	default:
+		list = services.CachedServiceList(ctx, c)
+	}
 
-		finalServices = services.CalculateGroups(finalServices)
+	// Truncation by max_services, stale and partial data are reported in headers.
+	truncated := len(list.Services) < list.Total
+	w.Header().Set("X-Total-Count", strconv.Itoa(list.Total))
+	if truncated {
+		w.Header().Set("X-Services-Truncated", "true")
+	}
+	if list.Stale {
+		w.Header().Set("X-Services-Stale", "true")
+	}
+	if list.Partial {
+		w.Header().Set("X-Services-Partial", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if trace != nil {
+		json.NewEncoder(w).Encode(models.ServicesTrace{Services: list.Services, Total: list.Total, Truncated: truncated, Debug: trace.Messages()})
+		return
+	}
+	// Only clients that ask for the envelope get an object, so existing clients keep working.
+	if envelope, _ := strconv.ParseBool(r.URL.Query().Get("envelope")); envelope {
+		json.NewEncoder(w).Encode(models.ServiceListResponse{Services: list.Services, Total: list.Total, Truncated: truncated})
+		return
+	}
+	json.NewEncoder(w).Encode(list.Services)
+}
 
-		sort.Slice(finalServices, func(i, j int) bool {
-			return finalServices[i].Priority > finalServices[j].Priority
-		})
+// ServiceHandler returns the service whose ID is in the path, looked up in the same list as
+// /api/services, or 404 when that list has no such service.
+func ServiceHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		list := services.CachedServiceList(r.Context(), c)
+		for _, svc := range list.Services {
+			if svc.ID == id {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(svc)
+				return
+			}
+		}
+		http.Error(w, "Service not found", http.StatusNotFound)
+	}
+}
 
+// LinksHandler returns the configured quick links with resolved icons.
+func LinksHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(finalServices)
+		json.NewEncoder(w).Encode(services.GetQuickLinks())
+	}
+}
+
+// openAPISpec is the hand-maintained OpenAPI description of the /api endpoints.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler serves the OpenAPI description of the /api endpoints.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// ServicesCSVHandler exports the service list as a CSV download with the columns
+// name, url, group, tags, priority and host. Tags are separated by semicolons. Text cells are
+// escaped with csvCell, as names and tags come from router labels.
+func ServicesCSVHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		list := services.CachedServiceList(r.Context(), c)
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="trala-services.csv"`)
+
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"name", "url", "group", "tags", "priority", "host"})
+		for _, svc := range list.Services {
+			cw.Write([]string{csvCell(svc.Name), csvCell(svc.URL), csvCell(svc.Group), csvCell(strings.Join(svc.Tags, ";")), strconv.Itoa(svc.Priority), csvCell(svc.Host)})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			log.Printf("ERROR: Could not write CSV export: %v", err)
+		}
 	}
 }
 
-// HealthHandler performs health checks and returns the status.
+// csvCell prefixes a cell that a spreadsheet would evaluate as a formula with a single quote,
+// so an exported value such as "=HYPERLINK(...)" is shown as text (CSV formula injection).
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// HealthHandler performs health checks and returns the status. The reachability of the
+// Traefik instances is reused for health_cache_seconds.
 func HealthHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		instances := c.GetTraefikInstances()
@@ -198,29 +374,22 @@ func HealthHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *
 			return
 		}
 
-		// One shared client per insecure-skip-verify setting, reused across instances.
-		clients := map[bool]*http.Client{}
-		getClient := func(skip bool) *http.Client {
-			if clients[skip] == nil {
-				clients[skip] = traefik.CreateHTTPClientForInstance(skip)
+		if c.GetHealthCheckConfig() {
+			if status := c.GetConfigCompatibilityStatus(); !status.IsCompatible {
+				http.Error(w, "Configuration is incompatible: "+status.WarningMessage, http.StatusInternalServerError)
+				return
 			}
-			return clients[skip]
 		}
 
-		var failedInstances []string
-		for _, instance := range instances {
-			entryPointsURL := fmt.Sprintf("%s/api/entrypoints", instance.APIHost)
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-			_, err := traefik.CreateAndExecuteHTTPRequestWithInstance(ctx, getClient(instance.InsecureSkipVerify), "GET", entryPointsURL, instance)
-			cancel()
-			if err != nil {
-				failedInstances = append(failedInstances, instance.Name)
-				log.Printf("WARNING: Health check failed for Traefik instance %s: %v", instance.Name, err)
-			}
+		// Misconfiguration is reported as 500 and a temporarily unreachable Traefik as 503, so
+		// orchestrators can tell permanent from transient failures.
+		health := cachedInstanceHealth(c)
+		if len(health.Rejected) > 0 {
+			http.Error(w, fmt.Sprintf("Traefik instances rejected the credentials: %s", strings.Join(health.Rejected, ", ")), http.StatusInternalServerError)
+			return
 		}
-
-		if len(failedInstances) > 0 {
-			http.Error(w, fmt.Sprintf("Traefik instances unreachable: %s", strings.Join(failedInstances, ", ")), http.StatusServiceUnavailable)
+		if len(health.Unreachable) > 0 {
+			http.Error(w, fmt.Sprintf("Traefik instances unreachable: %s", strings.Join(health.Unreachable, ", ")), http.StatusServiceUnavailable)
 			return
 		}
 
@@ -231,38 +400,10 @@ func HealthHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *
 // StatusHandler returns combined application status information.
 func StatusHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		versionInfo := GetVersionInfo()
-		configStatus := c.GetConfigCompatibilityStatus()
-		searchEngineURL := c.GetSearchEngineURL()
-		refreshIntervalSeconds := c.GetRefreshIntervalSeconds()
-
-		searchEngineIconURL := ""
-		if searchEngineURL != "" {
-			serviceName := services.ExtractServiceNameFromURL(searchEngineURL)
-			if serviceName != "" {
-				displayNameReplaced := strings.ReplaceAll(serviceName, " ", "-")
-				reference := icons.ResolveSelfHstReference(displayNameReplaced)
-				searchEngineIconURL = icons.FindIcon(serviceName, searchEngineURL, serviceName, reference)
-			}
-		}
-
-		instances := c.GetTraefikInstances()
-		multiHost := len(instances) > 1
-
-		frontendConfig := models.FrontendConfig{
-			SearchEngineURL:        searchEngineURL,
-			SearchEngineIconURL:    searchEngineIconURL,
-			RefreshIntervalSeconds: refreshIntervalSeconds,
-			GroupingEnabled:        c.GetGroupingEnabled(),
-			GroupingColumns:        c.GetGroupingColumns(),
-			MultiHost:              multiHost,
-			MixServices:            false,
-		}
-
 		status := models.ApplicationStatus{
-			Version:  versionInfo,
-			Config:   configStatus,
-			Frontend: frontendConfig,
+			Version:  GetVersionInfo(),
+			Config:   c.GetConfigCompatibilityStatus(),
+			Frontend: cachedFrontendConfig(c),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+	"server/internal/services"
+)
+
+// useInstanceFetcher initializes the services package with c and replaces the instance fetcher
+// of the discovery pipeline with fetch, counting its calls. Each test names its instances
+// differently, so the cached service lists of different tests never match a revision.
+func useInstanceFetcher(t *testing.T, c *config.TralaConfiguration, fetch services.InstanceFetcher) *atomic.Int32 {
+	t.Helper()
+	var fetches atomic.Int32
+	services.Init(c)
+	services.SetInstanceFetcher(func(ctx context.Context, instance config.TraefikInstanceConfig) ([]models.Service, error) {
+		fetches.Add(1)
+		return fetch(ctx, instance)
+	})
+	t.Cleanup(func() {
+		services.SetInstanceFetcher(nil)
+		services.Init(nil)
+	})
+	return &fetches
+}
+
+// fetchServices returns an instance fetcher that always returns svcs.
+func fetchServices(svcs ...models.Service) services.InstanceFetcher {
+	return func(context.Context, config.TraefikInstanceConfig) ([]models.Service, error) {
+		return svcs, nil
+	}
+}
+
+func TestRefreshServicesHandler_IgnoresSnapshots(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "refresh-home"}}
+	c.Environment.StaleMaxAgeSeconds = 600
+	c.Environment.DebugEndpoints = true
+	var unreachable atomic.Bool
+	useInstanceFetcher(t, c, func(context.Context, config.TraefikInstanceConfig) ([]models.Service, error) {
+		if unreachable.Load() {
+			return nil, errors.New("connection refused")
+		}
+		return []models.Service{{Name: "grafana", URL: "https://grafana.lan", Host: "refresh-home"}}, nil
+	})
+
+	rec := httptest.NewRecorder()
+	ServicesHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/services", nil))
+	require.Equal(t, http.StatusOK, rec.Code, "a successful fetch stores the snapshot")
+
+	unreachable.Store(true)
+	rec = httptest.NewRecorder()
+	ServicesHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/services", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("X-Services-Stale"), "the regular endpoint serves the snapshot")
+
+	rec = httptest.NewRecorder()
+	RefreshServicesHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/services/refresh", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+
+	rec = httptest.NewRecorder()
+	RefreshServicesHandler(c)(rec, httptest.NewRequest(http.MethodPost, "/api/services/refresh", nil))
+	assert.Equal(t, http.StatusBadGateway, rec.Code, "a refresh does not fall back to the snapshot")
+	assert.Contains(t, rec.Body.String(), "refresh-home")
+}
+
+func TestRefreshServicesHandler_RequiresDebugEndpoints(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "refresh-gate"}}
+	fetches := useInstanceFetcher(t, c, fetchServices(models.Service{Name: "grafana", URL: "https://grafana.lan"}))
+
+	rec := httptest.NewRecorder()
+	RefreshServicesHandler(c)(rec, httptest.NewRequest(http.MethodPost, "/api/services/refresh", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Zero(t, fetches.Load(), "a disabled refresh does not run discovery")
+
+	c.Environment.DebugEndpoints = true
+	rec = httptest.NewRecorder()
+	RefreshServicesHandler(c)(rec, httptest.NewRequest(http.MethodPost, "/api/services/refresh", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestServiceHandler(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "details"}}
+	grafana := models.Service{Name: "grafana", URL: "https://grafana.lan", Host: "details"}
+	useInstanceFetcher(t, c, fetchServices(grafana, models.Service{Name: "nas", URL: "https://nas.lan", Host: "details"}))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/services/{id}", ServiceHandler(c))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/services/"+services.ServiceID(grafana), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got models.Service
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, services.ServiceID(grafana), got.ID)
+	assert.Equal(t, "grafana", got.Name)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/services/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServicesHandler_DebugRequiresDebugEndpoints(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "debug"}}
+	c.Environment.RefreshIntervalSeconds = 30
+	fetches := useInstanceFetcher(t, c, fetchServices(models.Service{Name: "grafana", URL: "https://grafana.lan"}))
+
+	get := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		ServicesHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/services?debug=1", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	var list []models.Service
+	require.NoError(t, json.Unmarshal(get().Body.Bytes(), &list), "the parameter is ignored while debug_endpoints is off")
+	require.NoError(t, json.Unmarshal(get().Body.Bytes(), &list))
+	assert.Equal(t, int32(1), fetches.Load(), "ignored debug requests are served from the cache")
+
+	c.Environment.DebugEndpoints = true
+	var traced models.ServicesTrace
+	require.NoError(t, json.Unmarshal(get().Body.Bytes(), &traced))
+	assert.Equal(t, "grafana", traced.Services[0].Name)
+	assert.Equal(t, int32(2), fetches.Load(), "a traced request runs discovery itself")
+}
+
+func TestServicesHandler_ReportsTruncation(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "truncation"}}
+	useInstanceFetcher(t, c, fetchServices(
+		models.Service{Name: "grafana", URL: "https://grafana.lan", Priority: 10},
+		models.Service{Name: "nas", URL: "https://nas.lan", Priority: 5},
+		models.Service{Name: "plex", URL: "https://plex.lan"},
+	))
+
+	get := func(maxServices int, target string) *httptest.ResponseRecorder {
+		c.Environment.MaxServices = maxServices
+		rec := httptest.NewRecorder()
+		ServicesHandler(c)(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	rec := get(2, "/api/services")
+	var list []models.Service
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list), "max_services alone keeps the body a plain array")
+	require.Len(t, list, 2)
+	assert.Equal(t, "nas", list[1].Name, "the services with the highest priority are kept")
+	assert.Equal(t, "true", rec.Header().Get("X-Services-Truncated"))
+	assert.Equal(t, "3", rec.Header().Get("X-Total-Count"))
+
+	rec = get(2, "/api/services?envelope=1")
+	var truncated models.ServiceListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &truncated))
+	assert.True(t, truncated.Truncated)
+	assert.Equal(t, 3, truncated.Total)
+	assert.Len(t, truncated.Services, 2)
+
+	rec = get(5, "/api/services?envelope=1")
+	var complete models.ServiceListResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &complete))
+	assert.False(t, complete.Truncated)
+	assert.Len(t, complete.Services, 3)
+	assert.Empty(t, rec.Header().Get("X-Services-Truncated"))
+
+	var unlimited models.ServiceListResponse
+	require.NoError(t, json.Unmarshal(get(0, "/api/services?envelope=1").Body.Bytes(), &unlimited), "the envelope does not depend on max_services")
+	assert.False(t, unlimited.Truncated)
+	assert.Equal(t, 3, unlimited.Total)
+	assert.Len(t, unlimited.Services, 3)
+}
+
+func TestServicesCSVHandler(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "csv"}}
+	c.Environment.Grouping.Enabled = true
+	useInstanceFetcher(t, c, fetchServices(
+		models.Service{Name: "Grafana", URL: "https://grafana.lan", Group: "Monitoring", Tags: []string{"monitoring", "auth"}, Priority: 10, Host: "home"},
+		models.Service{Name: "=HYPERLINK(\"https://evil.example\")", URL: "https://x.lan", Group: "+cmd", Tags: []string{"-1+1"}, Priority: -5, Host: "@home"},
+		models.Service{Name: "\tTab", URL: "https://y.lan", Group: "\rReturn"},
+	))
+
+	rec := httptest.NewRecorder()
+	ServicesCSVHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/services.csv", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="trala-services.csv"`, rec.Header().Get("Content-Disposition"))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "url", "group", "tags", "priority", "host"},
+		{"Grafana", "https://grafana.lan", "Monitoring", "monitoring;auth", "10", "home"},
+		{"'\tTab", "https://y.lan", "'\rReturn", "", "0", ""},
+		{"'=HYPERLINK(\"https://evil.example\")", "https://x.lan", "'+cmd", "'-1+1", "-5", "'@home"},
+	}, records)
+}
+
+func TestRequestTimeout(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.RequestTimeoutSeconds = 1
+	handler := RequestTimeout(c, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("slow") {
+			// The request context ends when the timeout fires.
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	// The timeout was read when the handler was wrapped.
+	c.Environment.RequestTimeoutSeconds = 0
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/services", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String(), "a fast handler passes through")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/services?slow", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "Request timed out", rec.Body.String())
+
+	unwrapped := http.RedirectHandler("/", http.StatusFound)
+	assert.Same(t, unwrapped, RequestTimeout(c, unwrapped), "a timeout of zero returns the handler unchanged")
+}
+
+func TestRecover(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/panic", func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/partial", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		panic("boom")
+	})
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	handler := Recover(mux)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "Internal Server Error\n", rec.Body.String())
+	assert.Contains(t, logs.String(), "Panic while serving GET /panic: boom")
+	assert.Contains(t, logs.String(), "handlers.TestRecover", "the stack trace is logged")
+
+	rec = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/partial", nil))
+	}, "a started response is aborted")
+	assert.Equal(t, "partial", rec.Body.String(), "no error message is appended to a started response")
+
+	log.SetOutput(io.Discard)
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+	for _, path := range []string{"/panic", "/partial"} {
+		if resp, err := srv.Client().Get(srv.URL + path); err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+	resp, err := srv.Client().Get(srv.URL + "/ok")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body), "the server keeps serving after a panic")
+}
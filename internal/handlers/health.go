@@ -0,0 +1,89 @@
+// Package handlers provides HTTP handlers for the Trala dashboard.
+// This file contains the Traefik reachability check of the health endpoint and its cache.
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"server/internal/config"
+	"server/internal/traefik"
+)
+
+// instanceHealth is the result of a Traefik reachability check.
+type instanceHealth struct {
+	Unreachable []string // instances that did not answer successfully
+	Rejected    []string // instances that rejected the configured credentials
+}
+
+// healthCache holds the result of the last Traefik reachability check. It is valid while the
+// configuration revision is unchanged and it is younger than health_cache_seconds.
+var healthCache struct {
+	mu        sync.Mutex
+	health    instanceHealth
+	revision  string
+	checkedAt time.Time
+}
+
+// cachedInstanceHealth checks the reachability of the Traefik instances, reusing the last
+// result when it is still valid. Concurrent probes wait for a running check instead of
+// starting their own, so a burst of probes contacts Traefik only once.
+func cachedInstanceHealth(c *config.TralaConfiguration) instanceHealth {
+	maxAge := time.Duration(c.GetHealthCacheSeconds()) * time.Second
+	if maxAge <= 0 {
+		return checkInstances(c.GetTraefikInstances())
+	}
+
+	revision := c.GetRevision()
+
+	healthCache.mu.Lock()
+	defer healthCache.mu.Unlock()
+	if !healthCache.checkedAt.IsZero() &&
+		healthCache.revision == revision &&
+		time.Since(healthCache.checkedAt) < maxAge {
+		return healthCache.health
+	}
+
+	health := checkInstances(c.GetTraefikInstances())
+	healthCache.health = health
+	healthCache.revision = revision
+	healthCache.checkedAt = time.Now()
+	return health
+}
+
+// checkInstances requests the entrypoints of every instance within five seconds. Instances
+// answering 401 or 403 are reported as rejecting the credentials, instances failing otherwise
+// as unreachable.
+func checkInstances(instances []config.TraefikInstanceConfig) instanceHealth {
+	// One shared client per insecure-skip-verify setting, reused across instances.
+	clients := map[bool]*http.Client{}
+	getClient := func(skip bool) *http.Client {
+		if clients[skip] == nil {
+			clients[skip] = traefik.CreateHTTPClientForInstance(skip)
+		}
+		return clients[skip]
+	}
+
+	var health instanceHealth
+	for _, instance := range instances {
+		entryPointsURL := instance.APIHost + "/api/entrypoints"
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		resp, err := traefik.CreateAndExecuteHTTPRequestWithInstance(ctx, getClient(instance.InsecureSkipVerify), "GET", entryPointsURL, instance)
+		if err == nil {
+			resp.Body.Close()
+		}
+		cancel()
+		switch {
+		case traefik.IsAuthError(err):
+			health.Rejected = append(health.Rejected, instance.Name)
+			log.Printf("WARNING: Health check failed for Traefik instance %s, check its basic auth credentials: %v", instance.Name, err)
+		case err != nil:
+			health.Unreachable = append(health.Unreachable, instance.Name)
+			log.Printf("WARNING: Health check failed for Traefik instance %s: %v", instance.Name, err)
+		}
+	}
+	return health
+}
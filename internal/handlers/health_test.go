@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+)
+
+// newHealthTestConfig returns a valid configuration with a single Traefik instance at apiHost
+// and resets the health cache for the duration of the test.
+func newHealthTestConfig(t *testing.T, apiHost string) *config.TralaConfiguration {
+	t.Helper()
+	resetHealthCache()
+	t.Cleanup(resetHealthCache)
+
+	c := &config.TralaConfiguration{}
+	c.Environment.SearchEngineURL = "https://search.example/?q="
+	c.Environment.SelfhstIconURL = "https://icons.example/"
+	c.Environment.Traefik.Instances = []config.TraefikInstanceConfig{{Name: "home", APIHost: apiHost}}
+	return c
+}
+
+func resetHealthCache() {
+	healthCache.mu.Lock()
+	defer healthCache.mu.Unlock()
+	healthCache.checkedAt = time.Time{}
+}
+
+func TestHealthHandler_ReusesRecentCheck(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	c := newHealthTestConfig(t, server.URL)
+	c.Environment.HealthCacheSeconds = 60
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		HealthHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Equal(t, int32(1), requests.Load(), "probes within the window reuse the first check")
+
+	c.Environment.HealthCacheSeconds = 0
+	rec := httptest.NewRecorder()
+	HealthHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, int32(2), requests.Load(), "without the cache every probe contacts Traefik")
+}
+
+func TestHealthHandler_StatusCodes(t *testing.T) {
+	respond := func(status int) string {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`[]`))
+		}))
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	cases := []struct {
+		name    string
+		apiHost string
+		mutate  func(c *config.TralaConfiguration)
+		want    int
+	}{
+		{"healthy", respond(http.StatusOK), nil, http.StatusOK},
+		{"no instances", "", func(c *config.TralaConfiguration) { c.Environment.Traefik.Instances = nil }, http.StatusInternalServerError},
+		{"invalid search engine URL", respond(http.StatusOK), func(c *config.TralaConfiguration) { c.Environment.SearchEngineURL = "not a url" }, http.StatusInternalServerError},
+		{"credentials rejected", respond(http.StatusUnauthorized), nil, http.StatusInternalServerError},
+		{"access forbidden", respond(http.StatusForbidden), nil, http.StatusInternalServerError},
+		{"Traefik error", respond(http.StatusBadGateway), nil, http.StatusServiceUnavailable},
+		{"Traefik unreachable", closed.URL, nil, http.StatusServiceUnavailable},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newHealthTestConfig(t, tc.apiHost)
+			if tc.mutate != nil {
+				tc.mutate(c)
+			}
+
+			rec := httptest.NewRecorder()
+			HealthHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+			assert.Equal(t, tc.want, rec.Code, rec.Body.String())
+		})
+	}
+}
+
+func TestHealthHandler_StrictConfigCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	// load reads a configuration file, so the compatibility status is set as in production.
+	load := func(t *testing.T, version string, strict bool) *config.TralaConfiguration {
+		t.Helper()
+		resetHealthCache()
+		t.Cleanup(resetHealthCache)
+		path := filepath.Join(t.TempDir(), "configuration.yml")
+		yaml := fmt.Sprintf("%s\nenvironment:\n  health_check_config: %t\n  traefik:\n    api_host: %s\n", version, strict, server.URL)
+		require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+		c, err := config.LoadConfiguration(path)
+		require.NoError(t, err)
+		return c
+	}
+
+	cases := []struct {
+		name    string
+		version string
+		strict  bool
+		want    int
+	}{
+		{"compatible", "version: " + config.MinimumConfigVersion, true, http.StatusOK},
+		{"no version, strict", "", true, http.StatusInternalServerError},
+		{"outdated version, strict", "version: 1.0", true, http.StatusInternalServerError},
+		{"no version, default", "", false, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := load(t, tc.version, tc.strict)
+
+			rec := httptest.NewRecorder()
+			HealthHandler(c)(rec, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+			assert.Equal(t, tc.want, rec.Code, rec.Body.String())
+			if tc.want != http.StatusOK {
+				assert.Contains(t, rec.Body.String(), "Configuration is incompatible")
+			}
+		})
+	}
+}
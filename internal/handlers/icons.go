@@ -0,0 +1,163 @@
+// Package handlers provides HTTP handlers for the Trala dashboard.
+// This file contains the file server for user-provided icons and the external icon proxy.
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"server/internal/config"
+	"server/internal/icons"
+)
+
+// IconFileServer serves user icons from dir with caching headers.
+// Every response carries a Cache-Control max-age taken from the configuration plus
+// Last-Modified and ETag validators derived from the file, so browsers can reuse
+// icons across dashboard loads and revalidate them cheaply. Directory listings are
+// never served and only files with an accepted user icon extension are exposed.
+// When a requested icon does not exist and a placeholder image is configured, the
+// placeholder is served instead of a 404 so the tile keeps an icon.
+// The handler expects the route prefix to be stripped already.
+func IconFileServer(c *config.TralaConfiguration, dir string) http.Handler {
+	root := http.Dir(dir)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Path
+		if name == "" || strings.HasSuffix(name, "/") {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Only serve the image types that ScanUserIcons indexes; anything else mounted
+		// in the icons directory stays private.
+		if !c.IsUserIconExtension(filepath.Ext(name)) {
+			debugf("Refusing to serve non-icon file from icons directory: %s", name)
+			http.NotFound(w, r)
+			return
+		}
+
+		f, info, err := openIconFile(root, path.Clean("/"+name))
+		if err != nil {
+			servePlaceholderIcon(w, r, c.GetIconPlaceholder())
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", c.GetIconCacheMaxAgeSeconds()))
+		w.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+
+		// ServeContent sets Last-Modified and answers If-None-Match/If-Modified-Since with 304.
+		http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+	})
+}
+
+// servePlaceholderIcon serves the configured placeholder image, or a 404 when none is configured.
+// The placeholder is marked no-cache so the real icon shows up as soon as the file reappears.
+func servePlaceholderIcon(w http.ResponseWriter, r *http.Request, placeholder string) {
+	if placeholder == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, info, err := openIconFile(http.Dir(filepath.Dir(placeholder)), "/"+filepath.Base(placeholder))
+	if err != nil {
+		debugf("Could not serve icon placeholder %s: %v", placeholder, err)
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	debugf("Serving icon placeholder for missing icon: %s", r.URL.Path)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// openIconFile opens name within root and returns it with its file info.
+// Directories are reported as os.ErrNotExist.
+func openIconFile(root http.FileSystem, name string) (http.File, os.FileInfo, error) {
+	f, err := root.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil, os.ErrNotExist
+	}
+	return f, info, nil
+}
+
+// proxiedIconCSP is the Content-Security-Policy of proxied icons. They are served from the
+// dashboard origin, so an SVG opened directly must not run scripts or load other resources.
+const proxiedIconCSP = "default-src 'none'; style-src 'unsafe-inline'; sandbox"
+
+// IconProxyHandler fetches an allow-listed external icon server-side and streams it back.
+// It is used for icons the browser cannot load directly (CORS, mixed content). The handler
+// returns 404 while the proxy is disabled and 403 for hosts outside the allow-list.
+func IconProxyHandler(c *config.TralaConfiguration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.GetIconProxyEnabled() {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		rawURL := r.URL.Query().Get("url")
+		u, err := url.Parse(rawURL)
+		if rawURL == "" || err != nil || !icons.IsProxyAllowedHost(u.Hostname()) {
+			http.Error(w, "Icon URL is not allowed", http.StatusForbidden)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		defer cancel()
+
+		body, contentType, err := icons.FetchProxiedIcon(ctx, rawURL)
+		if err != nil {
+			log.Printf("WARNING: Icon proxy could not fetch %s: %v", rawURL, err)
+			http.Error(w, "Could not fetch icon", http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Security-Policy", proxiedIconCSP)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", c.GetIconCacheMaxAgeSeconds()))
+		if _, err := io.Copy(w, body); err != nil {
+			debugf("Icon proxy stream for %s interrupted: %v", rawURL, err)
+		}
+	}
+}
+
+// NormalizedIconHandler serves favicons normalized by the icons package.
+// The handler expects the route prefix to be stripped already, leaving "<key>.png".
+func NormalizedIconHandler(c *config.TralaConfiguration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSuffix(r.URL.Path, ".png")
+		data, ok := icons.GetNormalizedIcon(key)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", c.GetIconCacheMaxAgeSeconds()))
+		w.Write(data)
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/icons"
+)
+
+// useIconProxy enables the icon proxy for the host of an upstream server running handler and
+// returns the configuration and the server URL.
+func useIconProxy(t *testing.T, handler http.HandlerFunc) (*config.TralaConfiguration, string) {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	c := &config.TralaConfiguration{}
+	c.Environment.IconProxy.Enabled = true
+	c.Environment.IconProxy.AllowedHosts = []string{"127.0.0.1"}
+	icons.Init(c)
+	icons.InitHTTPClient(upstream.Client())
+	t.Cleanup(func() {
+		icons.Init(nil)
+		icons.InitHTTPClient(nil)
+	})
+	return c, upstream.URL
+}
+
+func TestIconProxyHandler(t *testing.T) {
+	c, upstream := useIconProxy(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/icon.svg":
+			w.Header().Set("Content-Type", "image/svg+xml")
+			w.Write([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/moved.svg":
+			http.Redirect(w, r, "/icon.svg", http.StatusFound)
+		case "/elsewhere.svg":
+			http.Redirect(w, r, "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+"/icon.svg", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	proxy := func(iconURL string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		IconProxyHandler(c)(rec, httptest.NewRequest(http.MethodGet, icons.IconProxyPath+"?url="+url.QueryEscape(iconURL), nil))
+		return rec
+	}
+
+	rec := proxy(upstream + "/icon.svg")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Header().Get("Content-Security-Policy"), "sandbox", "active SVG content is sandboxed")
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+
+	cases := []struct {
+		name string
+		url  string
+		want int
+	}{
+		{"redirect within the allow-list", upstream + "/moved.svg", http.StatusOK},
+		{"host not allowed", "https://evil.example/icon.svg", http.StatusForbidden},
+		{"missing url", "", http.StatusForbidden},
+		{"non-image content", upstream + "/page.html", http.StatusBadGateway},
+		{"redirect to a host not allowed", upstream + "/elsewhere.svg", http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, proxy(tc.url).Code, tc.name)
+	}
+
+	c.Environment.IconProxy.Enabled = false
+	assert.Equal(t, http.StatusNotFound, proxy(upstream+"/icon.svg").Code, "the proxy is off while disabled")
+}
+
+func TestIconFileServer(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "grafana.svg"), []byte("<svg/>"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo.PNG"), []byte("png"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets.txt"), []byte("password"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "apps.svg"), 0o755))
+	placeholder := filepath.Join(t.TempDir(), "placeholder.svg")
+	require.NoError(t, os.WriteFile(placeholder, []byte("<svg>?</svg>"), 0o644))
+	c := &config.TralaConfiguration{}
+	c.Environment.IconCacheMaxAgeSeconds = 3600
+	c.Environment.UserIconExtensions = []string{".png", ".svg"}
+	server := http.StripPrefix(icons.UserIconPath, IconFileServer(c, dir))
+
+	get := func(name, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, icons.UserIconPath+name, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+	etag := get("grafana.svg", "").Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	cases := []struct {
+		name         string
+		path         string
+		ifNoneMatch  string
+		placeholder  string
+		wantCode     int
+		wantBody     string
+		cacheControl string
+	}{
+		{"icon", "grafana.svg", "", "", http.StatusOK, "<svg/>", "public, max-age=3600"},
+		{"matching etag", "grafana.svg", etag, "", http.StatusNotModified, "", "public, max-age=3600"},
+		{"outdated etag", "grafana.svg", `"outdated"`, "", http.StatusOK, "<svg/>", "public, max-age=3600"},
+		{"missing icon", "deleted.svg", "", "", http.StatusNotFound, "404 page not found\n", ""},
+		{"missing icon with placeholder", "deleted.svg", "", placeholder, http.StatusOK, "<svg>?</svg>", "no-cache"},
+		{"existing icon with placeholder", "grafana.svg", "", placeholder, http.StatusOK, "<svg/>", "public, max-age=3600"},
+		{"extension in another case", "logo.PNG", "", "", http.StatusOK, "png", "public, max-age=3600"},
+		{"disallowed extension", "secrets.txt", "", placeholder, http.StatusNotFound, "404 page not found\n", ""},
+		{"no extension", "secrets", "", placeholder, http.StatusNotFound, "404 page not found\n", ""},
+		{"directory listing", "", "", "", http.StatusNotFound, "404 page not found\n", ""},
+		{"directory with an icon extension", "apps.svg", "", "", http.StatusNotFound, "404 page not found\n", ""},
+		{"missing placeholder", "deleted.svg", "", filepath.Join(dir, "missing.svg"), http.StatusNotFound, "404 page not found\n", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c.Environment.IconPlaceholder = tc.placeholder
+			rec := get(tc.path, tc.ifNoneMatch)
+			assert.Equal(t, tc.wantCode, rec.Code)
+			assert.Equal(t, tc.cacheControl, rec.Header().Get("Cache-Control"))
+			assert.Equal(t, tc.wantBody, rec.Body.String())
+			if tc.wantCode == http.StatusOK {
+				assert.NotEmpty(t, rec.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/models"
+)
+
+// TestOpenAPISpec_SchemasMatchModels keeps the hand-maintained spec in sync with the models it
+// describes.
+func TestOpenAPISpec_SchemasMatchModels(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]json.RawMessage `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+
+	schemas := map[string]interface{}{
+		"Service":             models.Service{},
+		"QuickLink":           models.QuickLink{},
+		"ServiceListResponse": models.ServiceListResponse{},
+		"ServicesTrace":       models.ServicesTrace{},
+	}
+	for schema, model := range schemas {
+		t.Run(schema, func(t *testing.T) {
+			var want []string
+			st := reflect.TypeOf(model)
+			for i := 0; i < st.NumField(); i++ {
+				want = append(want, strings.Split(st.Field(i).Tag.Get("json"), ",")[0])
+			}
+
+			var got []string
+			for name := range spec.Components.Schemas[schema].Properties {
+				got = append(got, name)
+			}
+			assert.ElementsMatch(t, want, got)
+		})
+	}
+}
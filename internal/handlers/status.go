@@ -0,0 +1,93 @@
+// Package handlers provides HTTP handlers for the Trala dashboard.
+// This file contains the frontend configuration of the status endpoint and its cache.
+package handlers
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/config"
+	"server/internal/icons"
+	"server/internal/models"
+	"server/internal/services"
+)
+
+// frontendConfigCache holds the last assembled frontend configuration. It is valid while the
+// configuration revision and the icon cache generation are unchanged and it is younger than
+// status_cache_seconds.
+var frontendConfigCache struct {
+	mu         sync.Mutex
+	config     models.FrontendConfig
+	revision   string
+	generation uint64
+	builtAt    time.Time
+}
+
+// buildFrontendConfigFunc assembles the frontend configuration; it is a variable so tests can
+// replace it.
+var buildFrontendConfigFunc = buildFrontendConfig
+
+// cachedFrontendConfig returns the frontend configuration, reusing the cached one when it is
+// still valid. Resolving the search engine icon takes a fuzzy search over the icon index, which
+// is too expensive to repeat on every status poll.
+func cachedFrontendConfig(c *config.TralaConfiguration) models.FrontendConfig {
+	maxAge := time.Duration(c.GetStatusCacheSeconds()) * time.Second
+	if maxAge <= 0 {
+		return buildFrontendConfigFunc(c)
+	}
+
+	revision := c.GetRevision()
+	generation := icons.CacheGeneration()
+
+	frontendConfigCache.mu.Lock()
+	defer frontendConfigCache.mu.Unlock()
+	if !frontendConfigCache.builtAt.IsZero() &&
+		frontendConfigCache.revision == revision &&
+		frontendConfigCache.generation == generation &&
+		time.Since(frontendConfigCache.builtAt) < maxAge {
+		return frontendConfigCache.config
+	}
+
+	frontendConfig := buildFrontendConfigFunc(c)
+	// Resolving the icon may itself refresh the icon index, so the generation is read again
+	// to avoid rebuilding on the next call.
+	frontendConfigCache.config = frontendConfig
+	frontendConfigCache.revision = revision
+	frontendConfigCache.generation = icons.CacheGeneration()
+	frontendConfigCache.builtAt = time.Now()
+	return frontendConfig
+}
+
+// buildFrontendConfig assembles the settings the frontend needs, including the icon of the
+// configured search engine. When none is found, search_engine_icon is used.
+func buildFrontendConfig(c *config.TralaConfiguration) models.FrontendConfig {
+	searchEngineURL := c.GetSearchEngineURL()
+
+	searchEngineIconURL := ""
+	if searchEngineURL != "" {
+		serviceName := services.ExtractServiceNameFromURL(searchEngineURL)
+		if serviceName != "" {
+			displayNameReplaced := strings.ReplaceAll(serviceName, " ", "-")
+			reference := icons.ResolveSelfHstReference(displayNameReplaced)
+			searchEngineIconURL, _ = icons.FindIcon(serviceName, searchEngineURL, "", serviceName, reference)
+		}
+	}
+	if searchEngineIconURL == "" {
+		searchEngineIconURL = services.ResolveIcon(c.GetSearchEngineIcon())
+	}
+
+	return models.FrontendConfig{
+		SearchEngineURL:        searchEngineURL,
+		SearchEngineIconURL:    searchEngineIconURL,
+		RefreshIntervalSeconds: c.GetRefreshIntervalSeconds(),
+		GroupingEnabled:        c.GetGroupingEnabled(),
+		GroupingColumns:        c.GetGroupingColumns(),
+		MultiHost:              len(c.GetTraefikInstances()) > 1,
+		MixServices:            false,
+		MaintenanceMessage:     c.GetMaintenanceMessage(),
+		SiteTitle:              c.GetSiteTitle(),
+		LogoURL:                c.GetLogoURL(),
+		ConfigRevision:         c.GetRevision(),
+	}
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// countFrontendConfigBuilds replaces the frontend config builder with one that counts its
+// calls and resets the cache for the duration of the test.
+func countFrontendConfigBuilds(t *testing.T) *int {
+	t.Helper()
+	builds := 0
+	previous := buildFrontendConfigFunc
+	buildFrontendConfigFunc = func(c *config.TralaConfiguration) models.FrontendConfig {
+		builds++
+		return models.FrontendConfig{SiteTitle: c.GetSiteTitle()}
+	}
+	resetFrontendConfigCache()
+	t.Cleanup(func() {
+		buildFrontendConfigFunc = previous
+		resetFrontendConfigCache()
+	})
+	return &builds
+}
+
+func resetFrontendConfigCache() {
+	frontendConfigCache.mu.Lock()
+	defer frontendConfigCache.mu.Unlock()
+	frontendConfigCache.builtAt = time.Time{}
+}
+
+func TestCachedFrontendConfig_ReusedUntilConfigChanges(t *testing.T) {
+	builds := countFrontendConfigBuilds(t)
+	c := &config.TralaConfiguration{}
+	c.Environment.StatusCacheSeconds = 300
+	c.Environment.SiteTitle = "Home"
+
+	assert.Equal(t, "Home", cachedFrontendConfig(c).SiteTitle)
+	assert.Equal(t, "Home", cachedFrontendConfig(c).SiteTitle)
+	assert.Equal(t, 1, *builds, "the second call is served from the cache")
+
+	c.Environment.SiteTitle = "Lab"
+	assert.Equal(t, "Lab", cachedFrontendConfig(c).SiteTitle, "a configuration change invalidates the cache")
+	assert.Equal(t, 2, *builds)
+
+	frontendConfigCache.mu.Lock()
+	frontendConfigCache.builtAt = time.Now().Add(-time.Hour)
+	frontendConfigCache.mu.Unlock()
+	cachedFrontendConfig(c)
+	assert.Equal(t, 3, *builds, "an expired entry is rebuilt")
+}
+
+func TestCachedFrontendConfig_Disabled(t *testing.T) {
+	builds := countFrontendConfigBuilds(t)
+	c := &config.TralaConfiguration{}
+
+	cachedFrontendConfig(c)
+	cachedFrontendConfig(c)
+	assert.Equal(t, 2, *builds, "a cache time of zero rebuilds on every call")
+}
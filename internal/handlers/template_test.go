@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	appi18n "server/internal/i18n"
+)
+
+// TestLoadHTMLTemplate_FallsBackToEmbeddedTemplate checks that the built-in template is used
+// and parses when the template directory has no index.html.
+func TestLoadHTMLTemplate_FallsBackToEmbeddedTemplate(t *testing.T) {
+	LoadHTMLTemplate(t.TempDir())
+	require.NotNil(t, parsedTemplate)
+	assert.NotEmpty(t, htmlTemplate)
+}
+
+const fixtureTemplate = `<h1>{{ T .Localizer "page.title" }}</h1><p>{{ TN .Localizer "page.items" 3 }}</p><p>{{ T .Localizer "page.missing" }}</p>`
+
+const fixtureTranslation = `
+page.title: Welkom
+page.items:
+  one: "{{.Count}} item"
+  other: "{{.Count}} items"
+`
+
+// TestServeHTMLTemplate_Localizes renders a fixture template through ServeHTMLTemplate with
+// translations loaded by i18n.Init.
+func TestServeHTMLTemplate_Localizes(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "nl.yaml"), []byte(fixtureTranslation), 0o600))
+	c := &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{Language: "nl", TranslationsDir: dir}}
+	appi18n.Init(c)
+
+	tmpl, err := parseHTMLTemplate(fixtureTemplate)
+	require.NoError(t, err)
+	previous := parsedTemplate
+	parsedTemplate = tmpl
+	t.Cleanup(func() { parsedTemplate = previous })
+
+	rec := httptest.NewRecorder()
+	ServeHTMLTemplate(c)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "<h1>Welkom</h1><p>3 items</p><p>page.missing</p>", rec.Body.String(),
+		"messages are localized and missing ones fall back to their ID")
+}
+
+// TestServeHTMLTemplate_Branding checks that the embedded template uses the configured site title
+// and logo instead of the defaults.
+func TestServeHTMLTemplate_Branding(t *testing.T) {
+	previous := parsedTemplate
+	LoadHTMLTemplate(t.TempDir())
+	t.Cleanup(func() { parsedTemplate = previous })
+
+	c := &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{
+		SiteTitle: "Home Lab",
+		LogoURL:   "/icons/logo.svg",
+	}}
+	rec := httptest.NewRecorder()
+	ServeHTMLTemplate(c)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	body := rec.Body.String()
+	assert.Contains(t, body, "<title>Home Lab</title>")
+	assert.Contains(t, body, `src="/icons/logo.svg"`)
+	assert.NotContains(t, body, "static/img/gopher.svg\" alt=\"Logo\"")
+}
+
+func TestParseHTMLTemplate_NilLocalizerFallsBackToMessageIDs(t *testing.T) {
+	tmpl, err := parseHTMLTemplate(fixtureTemplate)
+	require.NoError(t, err)
+
+	var out strings.Builder
+	data := struct{ Localizer *i18n.Localizer }{}
+	require.NoError(t, tmpl.Execute(&out, data))
+	assert.Equal(t, "<h1>page.title</h1><p>page.items</p><p>page.missing</p>", out.String())
+}
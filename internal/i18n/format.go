@@ -0,0 +1,42 @@
+// Package i18n provides internationalization support for the Trala dashboard.
+// This file contains locale-aware formatting helpers for dates.
+package i18n
+
+import (
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+// dateTimeLayouts maps a base language to its conventional numeric date/time layout.
+var dateTimeLayouts = map[string]string{
+	"en": "01/02/2006 15:04 MST",
+	"de": "02.01.2006 15:04 MST",
+	"nl": "02-01-2006 15:04 MST",
+	"fr": "02/01/2006 15:04 MST",
+}
+
+// defaultDateTimeLayout is used for languages without a specific layout.
+const defaultDateTimeLayout = "2006-01-02 15:04 MST"
+
+// FormatDateTime formats t using the date/time conventions of lang.
+// Unknown or empty languages use an ISO-like layout.
+func FormatDateTime(lang string, t time.Time) string {
+	// Base guesses English with low confidence for empty and unknown languages.
+	base, confidence := language.Make(lang).Base()
+	layout, ok := dateTimeLayouts[base.String()]
+	if !ok || confidence < language.High {
+		layout = defaultDateTimeLayout
+	}
+	return t.Format(layout)
+}
+
+// FormatBuildTime formats an RFC 3339 timestamp (as set at build time) for lang.
+// Values that are not valid timestamps, such as "unknown", are returned unchanged.
+func FormatBuildTime(lang string, value string) string {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return value
+	}
+	return FormatDateTime(lang, t)
+}
@@ -0,0 +1,48 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatDateTime(t *testing.T) {
+	t.Parallel()
+	moment := time.Date(2026, 3, 7, 14, 5, 0, 0, time.UTC)
+	cases := []struct {
+		lang string
+		want string
+	}{
+		{"en", "03/07/2026 14:05 UTC"},
+		{"en-GB", "03/07/2026 14:05 UTC"},
+		{"de", "07.03.2026 14:05 UTC"},
+		{"de-AT", "07.03.2026 14:05 UTC"},
+		{"nl", "07-03-2026 14:05 UTC"},
+		{"fr-CA", "07/03/2026 14:05 UTC"},
+		{"ja", "2026-03-07 14:05 UTC"},
+		{"", "2026-03-07 14:05 UTC"},
+		{"not a language", "2026-03-07 14:05 UTC"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, FormatDateTime(tc.lang, moment), tc.lang)
+	}
+}
+
+func TestFormatBuildTime(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		lang  string
+		value string
+		want  string
+	}{
+		{"de", "2026-03-07T14:05:00Z", "07.03.2026 14:05 UTC"},
+		{"en", "2026-03-07T14:05:00+01:00", "03/07/2026 14:05 +0100"},
+		{"nl", "unknown", "unknown"},
+		{"nl", "", ""},
+		{"fr", "2026-03-07", "2026-03-07"},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, FormatBuildTime(tc.lang, tc.value), tc.lang+" "+tc.value)
+	}
+}
@@ -3,114 +3,239 @@
 package i18n
 
 import (
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"go.yaml.in/yaml/v4"
 	"golang.org/x/text/language"
 
 	"server/internal/config"
+	"server/translations"
 )
 
-// Translation directory path
-const translationDir = "/app/translations"
+// Translation directory used when the configuration does not set one
+const defaultTranslationDir = "/app/translations"
 
 // Default fallback language
 const fallbackLang = "en"
 
-// Global bundle and default localizer
+// Global bundle and default localizer. They are replaced as a whole by Reload, so every
+// access goes through mu.
 var (
+	mu        sync.RWMutex
 	bundle    *i18n.Bundle
 	localizer *i18n.Localizer
+	conf      *config.TralaConfiguration
 )
 
 // Init initializes the i18n bundle and loads the appropriate translation file.
 // It falls back to English if the desired language file is missing.
 func Init(c *config.TralaConfiguration) {
-	// Get the language from environment configuration
-	lang := c.GetLanguage()
-	if lang == "" {
-		log.Printf("Language not set - using fallback language: %s", fallbackLang)
-		lang = fallbackLang
+	conf = c
+	b, loc, err := load(c.GetLanguage())
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
 	}
+	setBundle(b, loc)
+}
 
-	// Validate language code to prevent path traversal
-	if strings.ContainsAny(lang, "/\\.") || len(lang) > 10 {
-		log.Printf("Warning: Invalid language code '%s', falling back to '%s'", lang, fallbackLang)
-		lang = fallbackLang
+// Reload rebuilds the bundle from the translation files on disk, so edited translations take
+// effect without a restart. On error the current translations are kept.
+func Reload() error {
+	if conf == nil {
+		return fmt.Errorf("i18n is not initialized")
 	}
+	b, loc, err := load(conf.GetLanguage())
+	if err != nil {
+		return err
+	}
+	setBundle(b, loc)
+	log.Printf("Translations reloaded")
+	return nil
+}
 
-	// Build the path to the translation file for the selected language
-	translationFile := filepath.Join(translationDir, lang+".yaml")
-	log.Printf("Attempting to load translation file: %s", translationFile)
-
-	// Check if the translation file exists
-	if _, err := os.Stat(translationFile); os.IsNotExist(err) {
-		log.Printf("Translation file not found for language '%s': %s", lang, translationFile)
+// setBundle replaces the bundle and default localizer. Readers holding the previous ones keep
+// using them safely; bundles are never modified after they have been published.
+func setBundle(b *i18n.Bundle, loc *i18n.Localizer) {
+	mu.Lock()
+	defer mu.Unlock()
+	bundle, localizer = b, loc
+}
 
-		// Fallback to default language if the desired file is missing
+// load builds a bundle and default localizer for the configured language lang.
+func load(lang string) (*i18n.Bundle, *i18n.Localizer, error) {
+	if lang == "" {
+		lang = languageFromEnv()
+		if lang != "" {
+			log.Printf("Language not set - using language from the system locale: %s", lang)
+		}
+	}
+	if lang == "" {
+		log.Printf("Language not set - using fallback language: %s", fallbackLang)
 		lang = fallbackLang
-		translationFile = filepath.Join(translationDir, lang+".yaml")
-		log.Printf("Falling back to default translation file: %s", translationFile)
+	}
 
-		// If fallback file is also missing, terminate the application
-		if _, err := os.Stat(translationFile); os.IsNotExist(err) {
-			log.Fatalf("FATAL: Fallback translation file also not found: %s", translationFile)
-			return
-		}
+	// Find the translation file for the language, falling back through its parent languages
+	// (pt-BR -> pt) and the closest available translation before using English.
+	fsys := translationFS()
+	lang, translationFile, ok := resolveTranslationFile(fsys, lang)
+	if !ok {
+		return nil, nil, fmt.Errorf("fallback translation file also not found: %s", translationFile)
 	}
 
 	log.Printf("Language set to: %s", lang)
 
 	// Create a new i18n bundle with the selected language
-	bundle = i18n.NewBundle(language.Make(lang))
+	b := i18n.NewBundle(language.Make(lang))
 
 	// Register the YAML unmarshal function to read translation files
-	bundle.RegisterUnmarshalFunc("yaml", yaml.Unmarshal)
+	b.RegisterUnmarshalFunc("yaml", yaml.Unmarshal)
 
 	// Load the translation file into the bundle
-	if _, err := bundle.LoadMessageFile(translationFile); err != nil {
-		log.Fatalf("Failed to load translation file '%s': %v", translationFile, err)
+	if _, err := b.LoadMessageFileFS(fsys, translationFile); err != nil {
+		return nil, nil, fmt.Errorf("failed to load translation file '%s': %w", translationFile, err)
 	}
 
 	// Create a localizer for the current language
-	localizer = i18n.NewLocalizer(bundle, lang)
+	return b, i18n.NewLocalizer(b, lang), nil
 }
 
-// T is a helper function for localization. It takes a message ID and returns the localized string.
-// If the localization fails, it returns the message ID as a fallback.
-func T(id string) string {
-	if localizer == nil {
-		return id
+// translationFS returns the translation directory, or the translations embedded in the binary
+// when the directory does not exist.
+func translationFS() fs.FS {
+	dir := defaultTranslationDir
+	if conf != nil && conf.GetTranslationsDir() != "" {
+		dir = conf.GetTranslationsDir()
+	}
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return os.DirFS(dir)
 	}
-	msg, err := localizer.Localize(&i18n.LocalizeConfig{MessageID: id})
+	log.Printf("Translation directory %s not found, using built-in translations", dir)
+	return translations.FS
+}
+
+// resolveTranslationFile returns the language and name of the translation file in fsys to use
+// for lang. It tries lang itself, then its parents with subtags removed (pt-BR -> pt), then the
+// best match among the available files, and finally the fallback language. ok is false when
+// not even the fallback file exists.
+func resolveTranslationFile(fsys fs.FS, lang string) (resolved, path string, ok bool) {
+	tag, err := language.Parse(lang)
 	if err != nil {
-		// If localization fails, return the message ID as a fallback.
-		return id
+		log.Printf("Warning: Invalid language code '%s', falling back to '%s'", lang, fallbackLang)
+		tag = language.Make(fallbackLang)
 	}
-	return msg
+
+	for t := tag; !t.IsRoot(); t = t.Parent() {
+		path = t.String() + ".yaml"
+		if _, err := fs.Stat(fsys, path); err == nil {
+			if t != tag {
+				log.Printf("Translation file not found for language '%s', using '%s'", tag, t)
+			}
+			return t.String(), path, true
+		}
+	}
+
+	if available := availableLanguages(fsys); len(available) > 0 {
+		_, index, confidence := language.NewMatcher(available).Match(tag)
+		if confidence != language.No {
+			match := available[index]
+			log.Printf("Translation file not found for language '%s', using closest match '%s'", tag, match)
+			return match.String(), match.String() + ".yaml", true
+		}
+	}
+
+	path = fallbackLang + ".yaml"
+	log.Printf("Translation file not found for language '%s', falling back to default translation file: %s", tag, path)
+	if _, err := fs.Stat(fsys, path); err != nil {
+		return fallbackLang, path, false
+	}
+	return fallbackLang, path, true
+}
+
+// availableLanguages returns the languages of the translation files in fsys, with the fallback
+// language first so the matcher prefers it when nothing matches well.
+func availableLanguages(fsys fs.FS) []language.Tag {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil
+	}
+	tags := []language.Tag{language.Make(fallbackLang)}
+	for _, entry := range entries {
+		name, isYAML := strings.CutSuffix(entry.Name(), ".yaml")
+		if !isYAML || entry.IsDir() || name == fallbackLang {
+			continue
+		}
+		if tag, err := language.Parse(name); err == nil {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// languageFromEnv returns the language of the system locale from LC_ALL, LC_MESSAGES or
+// LANG, in that order, e.g. "de-DE" for "de_DE.UTF-8". It returns "" when no locale is set or
+// the locale is "C" or "POSIX".
+func languageFromEnv() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		locale := os.Getenv(name)
+		if locale == "" {
+			continue
+		}
+		// Strip the encoding and modifier: de_DE.UTF-8@euro -> de_DE
+		locale, _, _ = strings.Cut(locale, ".")
+		locale, _, _ = strings.Cut(locale, "@")
+		if locale == "C" || locale == "POSIX" {
+			return ""
+		}
+		tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+		if err != nil {
+			return ""
+		}
+		return tag.String()
+	}
+	return ""
+}
+
+// T is a helper function for localization. It takes a message ID and returns the localized string.
+// If the localization fails, it returns the message ID as a fallback.
+func T(id string) string {
+	return LocalizeFunc(GetDefaultLocalizer(), id)
+}
+
+// TN is a helper function for pluralized localization. It selects the plural form of the
+// message ID that matches count under the language's plural rules and makes count available
+// to the message as {{.Count}}. If the localization fails, it returns the message ID.
+func TN(id string, count int) string {
+	return LocalizePluralFunc(GetDefaultLocalizer(), id, count)
 }
 
 // GetLocalizer returns a new localizer for the specified language.
 // This is useful for per-request localization in HTTP handlers.
 func GetLocalizer(lang string) *i18n.Localizer {
-	if bundle == nil {
+	b := GetBundle()
+	if b == nil {
 		return nil
 	}
-	return i18n.NewLocalizer(bundle, lang)
+	return i18n.NewLocalizer(b, lang)
 }
 
 // GetBundle returns the global i18n bundle.
 // This can be used for advanced localization scenarios.
 func GetBundle() *i18n.Bundle {
+	mu.RLock()
+	defer mu.RUnlock()
 	return bundle
 }
 
 // GetDefaultLocalizer returns the default localizer initialized during Init().
 func GetDefaultLocalizer() *i18n.Localizer {
+	mu.RLock()
+	defer mu.RUnlock()
 	return localizer
 }
 
@@ -126,3 +251,20 @@ func LocalizeFunc(loc *i18n.Localizer, id string) string {
 	}
 	return msg
 }
+
+// LocalizePluralFunc is a template function for pluralized messages.
+// It takes a localizer, message ID and count, returning the localized plural form.
+func LocalizePluralFunc(loc *i18n.Localizer, id string, count int) string {
+	if loc == nil {
+		return id
+	}
+	msg, err := loc.Localize(&i18n.LocalizeConfig{
+		MessageID:    id,
+		PluralCount:  count,
+		TemplateData: map[string]interface{}{"Count": count},
+	})
+	if err != nil {
+		return id
+	}
+	return msg
+}
@@ -0,0 +1,186 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v4"
+	"golang.org/x/text/language"
+
+	"server/internal/config"
+)
+
+// repoTranslationDir points at the translation files shipped with the repository.
+var repoTranslationDir = filepath.Join("..", "..", "translations")
+
+// useTranslations initializes the package with English translations from dir, or the built-in
+// ones when dir does not exist, and restores the previous state when the test ends.
+func useTranslations(t *testing.T, dir string) {
+	t.Helper()
+	previousConf, previousBundle, previousLocalizer := conf, GetBundle(), GetDefaultLocalizer()
+	t.Cleanup(func() {
+		conf = previousConf
+		setBundle(previousBundle, previousLocalizer)
+	})
+	c := &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{Language: "en", TranslationsDir: dir}}
+	Init(c)
+}
+
+// loadRepoLocalizer builds a localizer for lang from the repository's translation file.
+func loadRepoLocalizer(t *testing.T, lang string) *i18n.Localizer {
+	t.Helper()
+	b := i18n.NewBundle(language.Make(lang))
+	b.RegisterUnmarshalFunc("yaml", yaml.Unmarshal)
+	_, err := b.LoadMessageFile(filepath.Join(repoTranslationDir, lang+".yaml"))
+	require.NoError(t, err)
+	return i18n.NewLocalizer(b, lang)
+}
+
+func TestLocalizePluralFunc(t *testing.T) {
+	cases := []struct {
+		lang  string
+		count int
+		want  string
+	}{
+		{"en", 1, "1 service"},
+		{"en", 0, "0 services"},
+		{"en", 5, "5 services"},
+		{"nl", 1, "1 dienst"},
+		{"nl", 2, "2 diensten"},
+		{"de", 3, "3 Dienste"},
+		{"fr", 0, "0 service"}, // French treats zero as singular
+		{"fr", 2, "2 services"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lang, func(t *testing.T) {
+			loc := loadRepoLocalizer(t, tc.lang)
+			assert.Equal(t, tc.want, LocalizePluralFunc(loc, "services_count", tc.count))
+		})
+	}
+}
+
+func TestLocalizePluralFunc_Fallbacks(t *testing.T) {
+	assert.Equal(t, "services_count", LocalizePluralFunc(nil, "services_count", 2), "nil localizer returns the message ID")
+
+	loc := loadRepoLocalizer(t, "en")
+	assert.Equal(t, "does_not_exist", LocalizePluralFunc(loc, "does_not_exist", 2), "unknown IDs return the message ID")
+}
+
+func TestLanguageFromEnv(t *testing.T) {
+	cases := []struct {
+		name       string
+		lcAll      string
+		lcMessages string
+		lang       string
+		want       string
+	}{
+		{name: "unset", want: ""},
+		{name: "LANG with region and encoding", lang: "de_DE.UTF-8", want: "de-DE"},
+		{name: "LANG with modifier", lang: "nl_NL@euro", want: "nl-NL"},
+		{name: "LC_MESSAGES before LANG", lcMessages: "fr_FR.UTF-8", lang: "de_DE.UTF-8", want: "fr-FR"},
+		{name: "LC_ALL before everything", lcAll: "nl_BE.UTF-8", lcMessages: "fr_FR", lang: "de_DE", want: "nl-BE"},
+		{name: "C locale", lang: "C.UTF-8", want: ""},
+		{name: "POSIX locale", lcAll: "POSIX", lang: "de_DE", want: ""},
+		{name: "invalid locale", lang: "not a locale", want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LC_ALL", tc.lcAll)
+			t.Setenv("LC_MESSAGES", tc.lcMessages)
+			t.Setenv("LANG", tc.lang)
+			assert.Equal(t, tc.want, languageFromEnv())
+		})
+	}
+}
+
+func TestResolveTranslationFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"en", "de", "pt", "no", "zh-Hant"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name+".yaml"), []byte("hello: Hello\n"), 0o600))
+	}
+
+	cases := []struct {
+		lang string
+		want string
+	}{
+		{"pt", "pt"},
+		{"pt-BR", "pt"},
+		{"de-AT", "de"},
+		{"en-US", "en"},
+		{"zh-TW", "zh-Hant"},
+		{"nb", "no"},
+		{"ja", "en"},
+		{"../../etc/passwd", "en"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.lang, func(t *testing.T) {
+			lang, path, ok := resolveTranslationFile(os.DirFS(dir), tc.lang)
+			require.True(t, ok)
+			assert.Equal(t, tc.want, lang)
+			assert.Equal(t, tc.want+".yaml", path)
+		})
+	}
+}
+
+func TestResolveTranslationFile_MissingFallback(t *testing.T) {
+	_, path, ok := resolveTranslationFile(os.DirFS(t.TempDir()), "pt-BR")
+	assert.False(t, ok)
+	assert.Equal(t, "en.yaml", path)
+}
+
+func TestReload_PicksUpEditedTranslations(t *testing.T) {
+	dir := t.TempDir()
+	enFile := filepath.Join(dir, "en.yaml")
+	require.NoError(t, os.WriteFile(enFile, []byte("greeting: Hello\n"), 0o600))
+	useTranslations(t, dir)
+	assert.Equal(t, "Hello", T("greeting"))
+
+	require.NoError(t, os.WriteFile(enFile, []byte("greeting: Hi there\n"), 0o600))
+	require.NoError(t, Reload())
+	assert.Equal(t, "Hi there", T("greeting"))
+	assert.Equal(t, "Hi there", LocalizeFunc(GetLocalizer("en"), "greeting"))
+
+	require.NoError(t, os.WriteFile(enFile, []byte("greeting: [unclosed\n"), 0o600))
+	assert.Error(t, Reload(), "an invalid file is reported")
+	assert.Equal(t, "Hi there", T("greeting"), "the previous translations are kept on error")
+}
+
+func TestConcurrentAccessDuringReload(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "en.yaml"), []byte("greeting: Hello\n"), 0o600))
+	useTranslations(t, dir)
+
+	// Run with -race to detect unsynchronized access to the bundle and localizer.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, Reload())
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				assert.Equal(t, "Hello", T("greeting"))
+				assert.Equal(t, "Hello", LocalizeFunc(GetLocalizer("en"), "greeting"))
+				assert.Equal(t, "Hello", TN("greeting", 2))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLoad_UsesEmbeddedTranslationsWithoutDirectory(t *testing.T) {
+	useTranslations(t, filepath.Join(t.TempDir(), "missing"))
+
+	_, loc, err := load("de-AT")
+	require.NoError(t, err)
+	assert.Equal(t, "3 Dienste", LocalizePluralFunc(loc, "services_count", 3))
+}
@@ -0,0 +1,96 @@
+package icons
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"server/internal/models"
+)
+
+// benchWords are combined into the synthetic icon references and service names, so fuzzy
+// matching sees realistic overlaps between names.
+var benchWords = []string{
+	"home", "media", "cloud", "photo", "music", "video", "mail", "note", "book", "code",
+	"git", "wiki", "dash", "board", "stat", "log", "monitor", "backup", "sync", "vault",
+	"proxy", "dns", "auth", "chat", "task", "feed", "news", "file", "share", "play",
+}
+
+// syntheticSelfHstIcons returns n selfh.st index entries. The fixed seed keeps the fixture
+// identical between runs, so benchmark results can be compared.
+func syntheticSelfHstIcons(n int) []models.SelfHstIcon {
+	rng := rand.New(rand.NewPCG(1, 1))
+	icons := make([]models.SelfHstIcon, n)
+	for i := range icons {
+		reference := fmt.Sprintf("%s-%s-%d", benchWords[rng.IntN(len(benchWords))], benchWords[rng.IntN(len(benchWords))], i)
+		svg := "No"
+		if i%2 == 0 {
+			svg = "Yes"
+		}
+		icons[i] = models.SelfHstIcon{Reference: reference, SVG: svg}
+	}
+	return icons
+}
+
+// syntheticServiceNames returns n service names built from the same words as
+// syntheticSelfHstIcons, with a fixed seed of their own.
+func syntheticServiceNames(n int) []string {
+	rng := rand.New(rand.NewPCG(2, 2))
+	names := make([]string, n)
+	for i := range names {
+		names[i] = benchWords[rng.IntN(len(benchWords))] + benchWords[rng.IntN(len(benchWords))]
+	}
+	return names
+}
+
+// useSyntheticSelfHstIndex installs n synthetic icons as the cached selfh.st index.
+func useSyntheticSelfHstIndex(b *testing.B, n int) {
+	b.Helper()
+	selfhstCacheMux.Lock()
+	previousIcons, previousTime := selfhstIcons, selfhstCacheTime
+	selfhstIcons, selfhstCacheTime = syntheticSelfHstIcons(n), time.Now()
+	selfhstCacheMux.Unlock()
+
+	b.Cleanup(func() {
+		selfhstCacheMux.Lock()
+		selfhstIcons, selfhstCacheTime = previousIcons, previousTime
+		selfhstCacheMux.Unlock()
+	})
+}
+
+func BenchmarkResolveSelfHstReference(b *testing.B) {
+	useConfig(b, newTestConfig())
+	useSyntheticSelfHstIndex(b, 5000)
+	names := syntheticServiceNames(500)
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		ResolveSelfHstReference(names[i%len(names)])
+	}
+}
+
+func BenchmarkFindIcon(b *testing.B) {
+	useConfig(b, newTestConfig())
+	useSyntheticSelfHstIndex(b, 5000)
+	names := syntheticServiceNames(500)
+
+	// Half of the services have a custom icon, the others resolve to a selfh.st reference.
+	files := make([]string, 0, len(names)/2)
+	for _, name := range names[:len(names)/2] {
+		files = append(files, name+".png")
+	}
+	if err := scanUserIconsDir(writeIconFiles(b, files...)); err != nil {
+		b.Fatal(err)
+	}
+	references := make([]string, len(names))
+	for i, name := range names {
+		references[i] = ResolveSelfHstReference(name)
+	}
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		n := i % len(names)
+		FindIcon(names[n], "https://"+names[n]+".lan", "", names[n], references[n])
+	}
+}
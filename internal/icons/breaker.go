@@ -0,0 +1,83 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains a circuit breaker that stops fetching from an unavailable upstream.
+package icons
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Circuit breaker settings for the selfh.st fetches
+const (
+	breakerFailureThreshold = 3
+	breakerCooldown         = 5 * time.Minute
+)
+
+// Circuit breakers for the selfh.st index and apps fetches
+var (
+	selfhstIconsBreaker = newCircuitBreaker("selfh.st icons", breakerFailureThreshold, breakerCooldown)
+	selfhstAppsBreaker  = newCircuitBreaker("selfh.st apps", breakerFailureThreshold, breakerCooldown)
+)
+
+// circuitBreaker opens after threshold consecutive failures, so callers skip the upstream and
+// use what they have instead of waiting for another failure. Once cooldown has passed a single
+// attempt is allowed again: success closes the breaker, failure opens it for another cooldown.
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+}
+
+// newCircuitBreaker creates a closed circuit breaker for the upstream called name.
+func newCircuitBreaker(name string, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether the upstream should be called. While the breaker is open it returns
+// false until the cooldown has passed, then it lets one attempt through.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.now().Before(b.openUntil) {
+		return false
+	}
+	// Let one attempt through; further calls wait for its result or the next cooldown
+	b.openUntil = b.now().Add(b.cooldown)
+	return true
+}
+
+// Record registers the result of a call to the upstream.
+func (b *circuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		if b.open {
+			log.Printf("Circuit breaker for %s closed, upstream is available again", b.name)
+		}
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if b.open {
+		b.openUntil = b.now().Add(b.cooldown)
+		log.Printf("Circuit breaker for %s stays open, retrying in %s: %v", b.name, b.cooldown, err)
+		return
+	}
+	if b.failures >= b.threshold {
+		b.open = true
+		b.openUntil = b.now().Add(b.cooldown)
+		log.Printf("Circuit breaker for %s opened after %d consecutive failures, retrying in %s: %v", b.name, b.failures, b.cooldown, err)
+	}
+}
@@ -7,14 +7,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"server/internal/config"
 	"server/internal/debug"
 	"server/internal/models"
 
@@ -25,29 +29,51 @@ import (
 const (
 	selfhstCacheTTL     = 1 * time.Hour
 	selfhstAppsCacheTTL = 24 * time.Hour
-	selfhstAPIURL       = "https://raw.githubusercontent.com/selfhst/icons/refs/heads/main/index.json"
-	selfhstAppsURL      = "https://raw.githubusercontent.com/selfhst/cdn/refs/heads/main/directory/integrations/trala.json"
-	userIconsDir        = "/icons"
+	// selfhstRetryInterval is how long stale cached data is served after a failed refresh
+	// before the next refresh is attempted.
+	selfhstRetryInterval = 1 * time.Minute
+	userIconsDir         = "/icons"
+)
+
+// selfh.st endpoints; variables so tests can point them at a local server
+var (
+	selfhstAPIURL  = "https://raw.githubusercontent.com/selfhst/icons/refs/heads/main/index.json"
+	selfhstAppsURL = "https://raw.githubusercontent.com/selfhst/cdn/refs/heads/main/directory/integrations/trala.json"
 )
 
 // Cache variables for SelfHst icons
 var (
-	selfhstIcons     []models.SelfHstIcon
-	selfhstCacheTime time.Time
-	selfhstCacheMux  sync.RWMutex
+	selfhstIcons       []models.SelfHstIcon
+	selfhstCacheTime   time.Time
+	selfhstLastAttempt time.Time // time of the last failed refresh
+	selfhstCacheMux    sync.RWMutex
 )
 
 // Cache variables for SelfHst apps
 var (
-	selfhstApps          []models.SelfHstApp
-	selfhstAppsCacheTime time.Time
-	selfhstAppsCacheMux  sync.RWMutex
+	selfhstApps            []models.SelfHstApp
+	selfhstAppsCacheTime   time.Time
+	selfhstAppsLastAttempt time.Time // time of the last failed refresh
+	selfhstAppsCacheMux    sync.RWMutex
 )
 
+// cacheGeneration is incremented whenever the selfh.st icon index or the user icon index is
+// replaced, so callers can tell when results derived from them are outdated.
+var cacheGeneration atomic.Uint64
+
+// CacheGeneration returns a counter that changes whenever the icon indexes are refreshed.
+func CacheGeneration() uint64 {
+	return cacheGeneration.Load()
+}
+
+// UserIconPath is the route prefix under which files from the user icon directory are served.
+const UserIconPath = "/icons/"
+
 // Cache variables for user icons
 var (
-	userIcons    map[string]string // Map of icon names to file paths
-	userIconsMux sync.RWMutex
+	userIcons     map[string]string // Map of icon names to file paths
+	userIconsRoot string            // Directory the file paths in userIcons are relative to
+	userIconsMux  sync.RWMutex
 	// Sorted user icon names for fuzzy matching
 	sortedUserIconNames    []string
 	sortedUserIconNamesMux sync.RWMutex
@@ -56,6 +82,19 @@ var (
 // externalHTTPClient is the HTTP client for external calls
 var externalHTTPClient *http.Client
 
+// NewExternalHTTPClient creates the HTTP client for external icon requests with the given
+// connection pool limits. SSL verification is always enabled, and connections to the
+// configured blocked networks are refused.
+func NewExternalHTTPClient(limits config.ExternalClientConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: guardIconDial}
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = limits.MaxIdleConns
+	transport.MaxIdleConnsPerHost = limits.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = limits.MaxConnsPerHost
+	return &http.Client{Timeout: 5 * time.Second, Transport: transport}
+}
+
 // InitHTTPClient initializes the HTTP client used for external icon requests.
 // This must be called before using any icon discovery functions.
 func InitHTTPClient(client *http.Client) {
@@ -63,10 +102,11 @@ func InitHTTPClient(client *http.Client) {
 }
 
 // GetSelfHstIconNames fetches the list of icons from the selfh.st index.json and caches it.
-// Returns cached data if still valid, otherwise fetches fresh data from the API.
+// Returns cached data if still valid, otherwise fetches fresh data from the API. When the
+// refresh fails, the previously cached icons are returned.
 func GetSelfHstIconNames() ([]models.SelfHstIcon, error) {
 	selfhstCacheMux.RLock()
-	if time.Since(selfhstCacheTime) < selfhstCacheTTL && len(selfhstIcons) > 0 {
+	if cacheUsable(len(selfhstIcons), selfhstCacheTime, selfhstLastAttempt, selfhstCacheTTL) {
 		selfhstCacheMux.RUnlock()
 		return selfhstIcons, nil
 	}
@@ -75,29 +115,26 @@ func GetSelfHstIconNames() ([]models.SelfHstIcon, error) {
 	selfhstCacheMux.Lock()
 	defer selfhstCacheMux.Unlock()
 	// Double-check after acquiring the lock
-	if time.Since(selfhstCacheTime) < selfhstCacheTTL && len(selfhstIcons) > 0 {
+	if cacheUsable(len(selfhstIcons), selfhstCacheTime, selfhstLastAttempt, selfhstCacheTTL) {
 		return selfhstIcons, nil
 	}
 
-	log.Println("Refreshing selfh.st icon cache from index.json...")
-	req, err := http.NewRequestWithContext(context.Background(), "GET", selfhstAPIURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", "TraLa-Dashboard-App")
-
-	resp, err := externalHTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("selfh.st icons API returned status %d", resp.StatusCode)
+	// While selfh.st is failing, skip the fetch and serve what is cached, possibly nothing
+	if !selfhstIconsBreaker.Allow() {
+		debugf("selfh.st icon index unavailable, serving %d cached icons", len(selfhstIcons))
+		return selfhstIcons, nil
 	}
 
+	log.Println("Refreshing selfh.st icon cache from index.json...")
 	var icons []models.SelfHstIcon
-	if err := json.NewDecoder(resp.Body).Decode(&icons); err != nil {
+	err := fetchSelfHstJSON(selfhstAPIURL, "icons", &icons)
+	selfhstIconsBreaker.Record(err)
+	if err != nil {
+		selfhstLastAttempt = time.Now()
+		if len(selfhstIcons) > 0 {
+			log.Printf("WARNING: Could not refresh selfh.st icon cache, serving %d cached icons: %v", len(selfhstIcons), err)
+			return selfhstIcons, nil
+		}
 		return nil, err
 	}
 
@@ -116,15 +153,17 @@ func GetSelfHstIconNames() ([]models.SelfHstIcon, error) {
 
 	selfhstIcons = icons
 	selfhstCacheTime = time.Now()
+	cacheGeneration.Add(1)
 	log.Printf("Successfully cached %d icons.", len(selfhstIcons))
 	return selfhstIcons, nil
 }
 
 // GetSelfHstAppTags fetches the integration data from the selfhst CDN and caches it.
-// Returns cached data if still valid, otherwise fetches fresh data from the API.
+// Returns cached data if still valid, otherwise fetches fresh data from the API. When the
+// refresh fails, the previously cached apps are returned.
 func GetSelfHstAppTags() ([]models.SelfHstApp, error) {
 	selfhstAppsCacheMux.RLock()
-	if time.Since(selfhstAppsCacheTime) < selfhstAppsCacheTTL && len(selfhstApps) > 0 {
+	if cacheUsable(len(selfhstApps), selfhstAppsCacheTime, selfhstAppsLastAttempt, selfhstAppsCacheTTL) {
 		selfhstAppsCacheMux.RUnlock()
 		return selfhstApps, nil
 	}
@@ -133,29 +172,26 @@ func GetSelfHstAppTags() ([]models.SelfHstApp, error) {
 	selfhstAppsCacheMux.Lock()
 	defer selfhstAppsCacheMux.Unlock()
 	// Double-check after acquiring the lock
-	if time.Since(selfhstAppsCacheTime) < selfhstAppsCacheTTL && len(selfhstApps) > 0 {
+	if cacheUsable(len(selfhstApps), selfhstAppsCacheTime, selfhstAppsLastAttempt, selfhstAppsCacheTTL) {
 		return selfhstApps, nil
 	}
 
-	log.Println("Refreshing Selfh.st apps cache from trala.json...")
-	req, err := http.NewRequestWithContext(context.Background(), "GET", selfhstAppsURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("User-Agent", "TraLa-Dashboard-App")
-
-	resp, err := externalHTTPClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("selfh.st apps API returned status %d", resp.StatusCode)
+	// While selfh.st is failing, skip the fetch and serve what is cached, possibly nothing
+	if !selfhstAppsBreaker.Allow() {
+		debugf("selfh.st apps unavailable, serving %d cached apps", len(selfhstApps))
+		return selfhstApps, nil
 	}
 
+	log.Println("Refreshing Selfh.st apps cache from trala.json...")
 	var data []models.SelfHstApp
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	err := fetchSelfHstJSON(selfhstAppsURL, "apps", &data)
+	selfhstAppsBreaker.Record(err)
+	if err != nil {
+		selfhstAppsLastAttempt = time.Now()
+		if len(selfhstApps) > 0 {
+			log.Printf("WARNING: Could not refresh selfh.st apps cache, serving %d cached apps: %v", len(selfhstApps), err)
+			return selfhstApps, nil
+		}
 		return nil, err
 	}
 
@@ -178,25 +214,63 @@ func GetSelfHstAppTags() ([]models.SelfHstApp, error) {
 	return selfhstApps, nil
 }
 
+// cacheUsable reports whether a cache of size entries can be served without a refresh: it is
+// younger than ttl, or a refresh failed less than selfhstRetryInterval ago.
+func cacheUsable(size int, cachedAt, lastAttempt time.Time, ttl time.Duration) bool {
+	if size == 0 {
+		return false
+	}
+	return time.Since(cachedAt) < ttl || time.Since(lastAttempt) < selfhstRetryInterval
+}
+
+// fetchSelfHstJSON fetches the selfh.st JSON document at url and decodes it into v. kind names
+// the document in errors.
+func fetchSelfHstJSON(url, kind string, v interface{}) error {
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "TraLa-Dashboard-App")
+
+	resp, err := externalHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("selfh.st %s API returned status %d", kind, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
 // ScanUserIcons scans the user icon directory and builds a map of icon names to file paths.
 // This function should be called at startup to populate the user icons cache.
 func ScanUserIcons() error {
-	userIconsMux.Lock()
-	defer userIconsMux.Unlock()
+	return scanUserIconsDir(userIconsDir)
+}
 
-	// Initialize the map
-	userIcons = make(map[string]string)
+// scanUserIconsDir implements ScanUserIcons for the given directory. The directory is walked
+// without holding the cache locks; the new index replaces the old one only when the walk
+// succeeds, so a rescan never leaves a partial index behind.
+func scanUserIconsDir(dir string) error {
+	icons := make(map[string]string)
 
 	// Check if the directory exists
-	if _, err := os.Stat(userIconsDir); os.IsNotExist(err) {
-		debugf("User icons directory does not exist: %s", userIconsDir)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		debugf("User icons directory does not exist: %s", dir)
+		storeUserIcons(dir, icons, nil)
 		return nil
 	}
 
 	log.Println("Scanning user icons directory...")
 
+	stripPrefixes := conf.GetUserIconStripPrefixes()
+	stripSuffixes := conf.GetUserIconStripSuffixes()
+
 	// Walk the directory to find all image files
-	err := filepath.Walk(userIconsDir, func(path string, info os.FileInfo, err error) error {
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -206,12 +280,17 @@ func ScanUserIcons() error {
 			return nil
 		}
 
-		// Check if it's an image file
+		// Check if it's an image file with an accepted extension
 		ext := strings.ToLower(filepath.Ext(path))
-		if ext == ".png" || ext == ".jpg" || ext == ".jpeg" || ext == ".svg" || ext == ".webp" || ext == ".gif" {
-			// Get the base name without extension as the icon name
+		if conf.IsUserIconExtension(ext) {
+			// Get the base name without extension as the icon name, applying the configured strip rules
 			iconName := strings.ToLower(strings.TrimSuffix(info.Name(), ext))
-			userIcons[iconName] = path
+			iconName = stripUserIconName(iconName, stripPrefixes, stripSuffixes)
+			// Files are walked in lexical order; of several files with the same name, the last one wins
+			if existing, ok := icons[iconName]; ok {
+				debugf("User icon %s replaces %s for name %s", path, existing, iconName)
+			}
+			icons[iconName] = path
 			debugf("Found user icon: %s -> %s", iconName, path)
 		}
 
@@ -226,8 +305,8 @@ func ScanUserIcons() error {
 	// 1. Primary sort: by length (shortest first). This prioritizes base names over variants
 	//    (e.g., "proxmox" over "proxmox-helper-scripts").
 	// 2. Secondary sort: alphabetically. This provides a stable order for names of the same length.
-	iconNames := make([]string, 0, len(userIcons))
-	for name := range userIcons {
+	iconNames := make([]string, 0, len(icons))
+	for name := range icons {
 		iconNames = append(iconNames, name)
 	}
 	sort.Slice(iconNames, func(i, j int) bool {
@@ -239,17 +318,70 @@ func ScanUserIcons() error {
 		return iconNames[i] < iconNames[j]
 	})
 
-	// Store the sorted icon names in our global variable for use in fuzzy matching
+	storeUserIcons(dir, icons, iconNames)
+
+	log.Printf("Successfully scanned user icons directory. Found %d icons.", len(icons))
+	return nil
+}
+
+// storeUserIcons replaces the user icon index of the directory root and the sorted icon names
+// used for fuzzy matching. Both are swapped under the user icons write lock, so lookups never
+// see a new index with old names.
+func storeUserIcons(root string, icons map[string]string, sortedNames []string) {
+	userIconsMux.Lock()
+	defer userIconsMux.Unlock()
+	userIcons = icons
+	userIconsRoot = root
+
 	sortedUserIconNamesMux.Lock()
-	sortedUserIconNames = iconNames
+	sortedUserIconNames = sortedNames
 	sortedUserIconNamesMux.Unlock()
+	cacheGeneration.Add(1)
+}
 
-	log.Printf("Successfully scanned user icons directory. Found %d icons.", len(userIcons))
-	return nil
+// UserIconURL maps the file path of a user icon to the URL it is served from: the base path,
+// the UserIconPath route and the path of the file within the user icon directory. It returns
+// "" for files outside the directory.
+func UserIconURL(filePath string) string {
+	userIconsMux.RLock()
+	root := userIconsRoot
+	userIconsMux.RUnlock()
+
+	rel, err := filepath.Rel(root, filePath)
+	if root == "" || err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return localIconURL(UserIconPath + strings.Join(segments, "/"))
 }
 
-// FindUserIcon performs a fuzzy search against user icons.
-// Returns the file path of the best matching icon, or empty string if no match found.
+// stripUserIconName removes the first matching prefix and suffix (case-insensitive) from a
+// lowercased icon name, so files like "app-logo.png" index as "app". A rule that would leave
+// an empty name is not applied.
+func stripUserIconName(name string, prefixes, suffixes []string) string {
+	for _, prefix := range prefixes {
+		prefix = strings.ToLower(prefix)
+		if prefix != "" && len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(suffix)
+		if suffix != "" && len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	return name
+}
+
+// FindUserIcon looks up a user icon by exact (case-insensitive) name and falls back to a fuzzy search.
+// Returns the file path of the best matching icon, or empty string if no match found. Use
+// UserIconURL to get the URL the file is served from.
 func FindUserIcon(routerName string) string {
 	userIconsMux.RLock()
 	defer userIconsMux.RUnlock()
@@ -259,6 +391,17 @@ func FindUserIcon(routerName string) string {
 		return ""
 	}
 
+	// An icon named exactly after the service always wins over fuzzy candidates
+	if path, ok := userIcons[strings.ToLower(routerName)]; ok {
+		debugf("[%s] Found user icon via exact match: %s", routerName, path)
+		return path
+	}
+
+	if !fuzzyMatchAllowed(routerName) {
+		debugf("[%s] Name shorter than %d characters, skipping fuzzy user icon match", routerName, conf.GetIconMinFuzzyLength())
+		return ""
+	}
+
 	// Use precomputed sorted icon names for fuzzy matching
 	sortedUserIconNamesMux.RLock()
 	iconNames := sortedUserIconNames
@@ -269,9 +412,6 @@ func FindUserIcon(routerName string) string {
 	if len(matches) > 0 {
 		// Return the path of the best match
 		if path, ok := userIcons[matches[0]]; ok {
-			// Convert file path to URL that can be served by the application
-			// The path will be something like "/icons/myicon.png"
-			// We want to serve it from "/icons/myicon.png"
 			debugf("[%s] Found user icon via fuzzy search: %s -> %s", routerName, matches[0], path)
 			return path
 		}
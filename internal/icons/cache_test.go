@@ -0,0 +1,307 @@
+package icons
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// --- Test helpers ---
+
+// useConfig installs c as the package configuration for the duration of the test.
+func useConfig(t testing.TB, c *config.TralaConfiguration) {
+	t.Helper()
+	previous := conf
+	conf = c
+	t.Cleanup(func() { conf = previous })
+}
+
+// newTestConfig returns a configuration with the default user icon extensions and icon link
+// rels and the selfh.st icon source enabled.
+func newTestConfig() *config.TralaConfiguration {
+	return &config.TralaConfiguration{
+		Environment: config.EnvironmentConfiguration{
+			SelfhstIconURL:     "https://icons.example/",
+			UseSelfhstIcons:    true,
+			IconLinkRels:       []string{"apple-touch-icon", "apple-touch-icon-precomposed", "icon", "fluid-icon", "mask-icon"},
+			UserIconExtensions: []string{".png", ".jpg", ".jpeg", ".svg", ".webp", ".gif"},
+		},
+	}
+}
+
+// writeIconFiles creates empty files with the given names in a temp dir and returns it.
+func writeIconFiles(t testing.TB, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o600))
+	}
+	return dir
+}
+
+// --- User icon tests ---
+
+func TestStripUserIconName(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		icon     string
+		prefixes []string
+		suffixes []string
+		want     string
+	}{
+		{"no rules", "app-logo", nil, nil, "app-logo"},
+		{"suffix stripped", "app-logo", nil, []string{"-logo"}, "app"},
+		{"suffix case-insensitive", "app-logo", nil, []string{"-LOGO"}, "app"},
+		{"prefix stripped", "icon-app", []string{"icon-"}, nil, "app"},
+		{"prefix and suffix", "icon-app-logo", []string{"icon-"}, []string{"-logo"}, "app"},
+		{"only first matching suffix", "app-logo-dark", nil, []string{"-dark", "-logo"}, "app-logo"},
+		{"never strips to empty", "-logo", nil, []string{"-logo"}, "-logo"},
+		{"non-matching rule", "grafana", []string{"icon-"}, []string{"-logo"}, "grafana"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, stripUserIconName(tc.icon, tc.prefixes, tc.suffixes))
+		})
+	}
+}
+
+func TestScanUserIcons_StripSuffixMatchesServiceName(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.UserIconStripSuffixes = []string{"-logo"}
+	useConfig(t, c)
+
+	dir := writeIconFiles(t, "app-logo.png", "readme.txt")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	assert.Equal(t, filepath.Join(dir, "app-logo.png"), FindUserIcon("app"))
+	assert.Empty(t, FindUserIcon("readme"), "files without an icon extension are not indexed")
+}
+
+func TestScanUserIcons_DuplicateNamesLastWins(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.UserIconStripSuffixes = []string{"-logo"}
+	useConfig(t, c)
+
+	// "app-logo.png" is walked before "app.png" and both are indexed as "app".
+	dir := writeIconFiles(t, "app-logo.png", "app.png")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	assert.Equal(t, filepath.Join(dir, "app.png"), FindUserIcon("app"), "the file walked last wins")
+}
+
+func TestScanUserIcons_WithoutStripRules(t *testing.T) {
+	useConfig(t, newTestConfig())
+
+	dir := writeIconFiles(t, "app-logo.png")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	userIconsMux.RLock()
+	_, indexed := userIcons["app-logo"]
+	userIconsMux.RUnlock()
+	assert.True(t, indexed, "icon names are unchanged when no strip rules are configured")
+}
+
+func TestScanUserIcons_RescanReplacesIndex(t *testing.T) {
+	useConfig(t, newTestConfig())
+
+	dir := writeIconFiles(t, "grafana.png", "plex.png")
+	require.NoError(t, scanUserIconsDir(dir))
+	require.NotEmpty(t, FindUserIcon("plex"))
+
+	require.NoError(t, os.Remove(filepath.Join(dir, "plex.png")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "jellyfin.svg"), nil, 0o600))
+	generation := CacheGeneration()
+	require.NoError(t, scanUserIconsDir(dir))
+	assert.NotEqual(t, generation, CacheGeneration(), "a rescan changes the cache generation")
+
+	assert.Equal(t, filepath.Join(dir, "jellyfin.svg"), FindUserIcon("jellyfin"), "new files are indexed")
+	assert.Empty(t, FindUserIcon("plex"), "removed files are dropped")
+	assert.Equal(t, filepath.Join(dir, "grafana.png"), FindUserIcon("grafana"))
+}
+
+func TestFindUserIcon_ExactMatchBeatsFuzzyCandidates(t *testing.T) {
+	useConfig(t, newTestConfig())
+
+	dir := writeIconFiles(t, "graf.png", "grafana-dashboard.svg", "my-grafana.png", "Grafana.png")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	assert.Equal(t, filepath.Join(dir, "Grafana.png"), FindUserIcon("grafana"))
+	assert.Equal(t, filepath.Join(dir, "Grafana.png"), FindUserIcon("GRAFANA"), "exact match is case-insensitive")
+	assert.Equal(t, filepath.Join(dir, "grafana-dashboard.svg"), FindUserIcon("grafana-d"), "fuzzy search still applies without an exact match")
+}
+
+func TestFindUserIcon_MinFuzzyLength(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.IconMinFuzzyLength = 3
+	useConfig(t, c)
+
+	dir := writeIconFiles(t, "it.png", "gitea.png")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	assert.Equal(t, filepath.Join(dir, "it.png"), FindUserIcon("it"), "exact matches ignore the minimum length")
+	assert.Empty(t, FindUserIcon("gt"), "short names skip fuzzy matching")
+	assert.Equal(t, filepath.Join(dir, "gitea.png"), FindUserIcon("gte"))
+}
+
+func TestNewExternalHTTPClient(t *testing.T) {
+	t.Parallel()
+	client := NewExternalHTTPClient(config.ExternalClientConfig{MaxIdleConns: 50, MaxIdleConnsPerHost: 5, MaxConnsPerHost: 10})
+	transport, ok := client.Transport.(*http.Transport)
+	require.True(t, ok)
+	assert.Equal(t, 50, transport.MaxIdleConns)
+	assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 10, transport.MaxConnsPerHost)
+	assert.NotSame(t, http.DefaultTransport, client.Transport, "the default transport must not be modified")
+}
+
+// --- Circuit breaker tests ---
+
+func TestCircuitBreaker_OpensAfterThresholdAndRetriesAfterCooldown(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(0, 0)
+	b := newCircuitBreaker("test", 2, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := errors.New("upstream down")
+
+	b.Record(failure)
+	assert.True(t, b.Allow(), "a single failure keeps the breaker closed")
+	b.Record(failure)
+	assert.False(t, b.Allow(), "the breaker opens after the threshold")
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow(), "one attempt is allowed after the cooldown")
+	assert.False(t, b.Allow(), "further calls wait for the result of that attempt")
+	b.Record(failure)
+	assert.False(t, b.Allow(), "a failed attempt opens the breaker again")
+
+	now = now.Add(time.Minute)
+	require.True(t, b.Allow())
+	b.Record(nil)
+	assert.True(t, b.Allow(), "a successful attempt closes the breaker")
+	b.Record(failure)
+	assert.True(t, b.Allow(), "failures are counted again from zero")
+}
+
+// --- selfh.st cache tests ---
+
+// useSelfHstServer points the selfh.st icon index at handler and resets the icon cache and its
+// circuit breaker for the duration of the test.
+func useSelfHstServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	previousURL, previousClient, previousBreaker := selfhstAPIURL, externalHTTPClient, selfhstIconsBreaker
+	selfhstAPIURL, externalHTTPClient = server.URL, server.Client()
+	selfhstIconsBreaker = newCircuitBreaker("test", breakerFailureThreshold, breakerCooldown)
+	selfhstIcons, selfhstCacheTime, selfhstLastAttempt = nil, time.Time{}, time.Time{}
+	t.Cleanup(func() {
+		selfhstAPIURL, externalHTTPClient, selfhstIconsBreaker = previousURL, previousClient, previousBreaker
+		selfhstIcons, selfhstCacheTime, selfhstLastAttempt = nil, time.Time{}, time.Time{}
+	})
+}
+
+func TestGetSelfHstIconNames_ServesStaleCacheOnFailedRefresh(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+	useSelfHstServer(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"Name":"Grafana","Reference":"grafana"}]`))
+	})
+
+	icons, err := GetSelfHstIconNames()
+	require.NoError(t, err)
+	require.Len(t, icons, 1)
+
+	// Expire the cache and let the refresh fail
+	failing.Store(true)
+	selfhstCacheMux.Lock()
+	selfhstCacheTime = time.Now().Add(-2 * selfhstCacheTTL)
+	selfhstCacheMux.Unlock()
+
+	icons, err = GetSelfHstIconNames()
+	require.NoError(t, err, "a failed refresh serves the cached icons")
+	assert.Equal(t, "grafana", icons[0].Reference)
+	assert.Equal(t, int32(2), requests.Load())
+	assert.False(t, selfhstLastAttempt.IsZero(), "the failed attempt is recorded")
+
+	icons, err = GetSelfHstIconNames()
+	require.NoError(t, err)
+	assert.Len(t, icons, 1)
+	assert.Equal(t, int32(2), requests.Load(), "no new attempt within the retry interval")
+}
+
+func TestGetSelfHstIconNames_FailsWithoutCache(t *testing.T) {
+	useSelfHstServer(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	})
+
+	icons, err := GetSelfHstIconNames()
+	assert.Error(t, err)
+	assert.Empty(t, icons)
+}
+
+func TestFindTags_SkipsAppsIndexWithoutGrouping(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`[{"reference":"grafana","name":"Grafana","tags":["monitoring"]}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	previousURL, previousClient, previousBreaker := selfhstAppsURL, externalHTTPClient, selfhstAppsBreaker
+	selfhstAppsURL, externalHTTPClient = server.URL, server.Client()
+	selfhstAppsBreaker = newCircuitBreaker("test", breakerFailureThreshold, breakerCooldown)
+	selfhstApps, selfhstAppsCacheTime, selfhstAppsLastAttempt = nil, time.Time{}, time.Time{}
+	t.Cleanup(func() {
+		selfhstAppsURL, externalHTTPClient, selfhstAppsBreaker = previousURL, previousClient, previousBreaker
+		selfhstApps, selfhstAppsCacheTime, selfhstAppsLastAttempt = nil, time.Time{}, time.Time{}
+	})
+
+	c := newTestConfig()
+	useConfig(t, c)
+	assert.Empty(t, FindTags("grafana", "grafana"))
+	assert.Equal(t, int32(0), requests.Load(), "the apps index is not fetched while grouping is disabled")
+
+	c.Environment.Grouping.Enabled = true
+	assert.Equal(t, []string{"monitoring"}, FindTags("grafana", "grafana"))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestGetServiceTags_ReturnsCopy(t *testing.T) {
+	tags := make([]string, 1, 8)
+	tags[0] = "monitoring"
+	selfhstAppsCacheMux.Lock()
+	previousApps, previousTime := selfhstApps, selfhstAppsCacheTime
+	selfhstApps, selfhstAppsCacheTime = []models.SelfHstApp{{Reference: "grafana", Tags: tags}}, time.Now()
+	selfhstAppsCacheMux.Unlock()
+	t.Cleanup(func() {
+		selfhstAppsCacheMux.Lock()
+		selfhstApps, selfhstAppsCacheTime = previousApps, previousTime
+		selfhstAppsCacheMux.Unlock()
+	})
+
+	first := append(GetServiceTags("grafana"), "auth")
+	second := append(GetServiceTags("grafana"), "compress")
+	assert.Equal(t, []string{"monitoring", "auth"}, first)
+	assert.Equal(t, []string{"monitoring", "compress"}, second, "appending to the tags does not change the cached apps")
+	assert.Equal(t, []string{"monitoring"}, GetServiceTags("grafana"))
+}
@@ -0,0 +1,161 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains the disk cache of discovered icons that survives restarts.
+package icons
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// iconCacheFile is the name of the disk cache file inside icon_cache_dir.
+const iconCacheFile = "icons.json"
+
+// discoveredIcon is an icon found by favicon or HTML discovery, as it is persisted in the disk
+// cache. URL is the icon before normalization and proxying.
+type discoveredIcon struct {
+	URL          string    `json:"url"`
+	Source       string    `json:"source"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+}
+
+// Cache variables for discovered icons, keyed by discoveredIconKey
+var (
+	discoveredIcons      = make(map[string]discoveredIcon)
+	discoveredIconsDirty bool
+	discoveredIconsMux   sync.Mutex
+)
+
+// discoveredIconKey returns the disk cache key of the icon of a router and its service URL.
+func discoveredIconKey(routerName, serviceURL string) string {
+	return routerName + " " + serviceURL
+}
+
+// iconCacheDir returns the configured disk cache directory, or "" when the cache is disabled.
+func iconCacheDir() string {
+	if conf == nil {
+		return ""
+	}
+	return conf.GetIconCacheDir()
+}
+
+// iconCacheTTL returns how long a discovered icon is reused; zero keeps it forever.
+func iconCacheTTL() time.Duration {
+	return time.Duration(conf.GetIconCacheTTLSeconds()) * time.Second
+}
+
+// expired reports whether a discovered icon is older than ttl.
+func (icon discoveredIcon) expired(ttl time.Duration) bool {
+	return ttl > 0 && time.Since(icon.DiscoveredAt) > ttl
+}
+
+// LoadCache reads the discovered icons persisted in icon_cache_dir, dropping expired ones.
+// It does nothing when no cache directory is configured or the file does not exist yet. A
+// cache file that cannot be read or parsed is ignored and reported as an error; discovery
+// then starts from scratch and the file is replaced by the next SaveCache.
+func LoadCache() error {
+	dir := iconCacheDir()
+	if dir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, iconCacheFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read icon cache: %w", err)
+	}
+	var loaded map[string]discoveredIcon
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("could not parse icon cache %s: %w", filepath.Join(dir, iconCacheFile), err)
+	}
+
+	ttl := iconCacheTTL()
+	discoveredIconsMux.Lock()
+	defer discoveredIconsMux.Unlock()
+	for key, icon := range loaded {
+		if icon.URL == "" || icon.expired(ttl) {
+			continue
+		}
+		discoveredIcons[key] = icon
+	}
+	debugf("Loaded %d discovered icons from the icon cache", len(discoveredIcons))
+	return nil
+}
+
+// SaveCache writes the discovered icons to icon_cache_dir when they changed since the last
+// save. The file is replaced atomically, so a crash cannot leave a truncated cache behind.
+// It does nothing when no cache directory is configured.
+func SaveCache() error {
+	dir := iconCacheDir()
+	if dir == "" {
+		return nil
+	}
+
+	ttl := iconCacheTTL()
+	discoveredIconsMux.Lock()
+	defer discoveredIconsMux.Unlock()
+	if !discoveredIconsDirty {
+		return nil
+	}
+	for key, icon := range discoveredIcons {
+		if icon.expired(ttl) {
+			delete(discoveredIcons, key)
+		}
+	}
+	data, err := json.Marshal(discoveredIcons)
+	if err != nil {
+		return fmt.Errorf("could not encode icon cache: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create icon cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, iconCacheFile+".*")
+	if err != nil {
+		return fmt.Errorf("could not write icon cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write icon cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write icon cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, iconCacheFile)); err != nil {
+		return fmt.Errorf("could not write icon cache: %w", err)
+	}
+	discoveredIconsDirty = false
+	return nil
+}
+
+// cachedDiscoveredIcon returns the discovered icon of a router from the disk cache, provided
+// it has not expired.
+func cachedDiscoveredIcon(routerName, serviceURL string) (discoveredIcon, bool) {
+	if iconCacheDir() == "" {
+		return discoveredIcon{}, false
+	}
+	discoveredIconsMux.Lock()
+	icon, ok := discoveredIcons[discoveredIconKey(routerName, serviceURL)]
+	discoveredIconsMux.Unlock()
+	if !ok || icon.expired(iconCacheTTL()) {
+		return discoveredIcon{}, false
+	}
+	return icon, true
+}
+
+// storeDiscoveredIcon records the discovered icon of a router in the disk cache; SaveCache
+// persists it.
+func storeDiscoveredIcon(routerName, serviceURL, iconURL, source string) {
+	if iconCacheDir() == "" {
+		return
+	}
+	discoveredIconsMux.Lock()
+	defer discoveredIconsMux.Unlock()
+	discoveredIcons[discoveredIconKey(routerName, serviceURL)] = discoveredIcon{URL: iconURL, Source: source, DiscoveredAt: time.Now()}
+	discoveredIconsDirty = true
+}
@@ -0,0 +1,149 @@
+package icons
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useIconCache enables the disk cache in a temp dir with the given TTL, starts with an empty
+// cache and restores it after the test. It returns the cache directory.
+func useIconCache(t *testing.T, ttlSeconds int) string {
+	t.Helper()
+	dir := t.TempDir()
+	c := newTestConfig()
+	c.Environment.UseSelfhstIcons = false
+	c.Environment.IconCacheDir = dir
+	c.Environment.IconCacheTTLSeconds = ttlSeconds
+	useConfig(t, c)
+	resetDiscoveredIcons()
+	t.Cleanup(resetDiscoveredIcons)
+	return dir
+}
+
+func resetDiscoveredIcons() {
+	discoveredIconsMux.Lock()
+	defer discoveredIconsMux.Unlock()
+	discoveredIcons = make(map[string]discoveredIcon)
+	discoveredIconsDirty = false
+}
+
+// writeIconCacheFile writes icons as the cache file in dir.
+func writeIconCacheFile(t *testing.T, dir string, icons map[string]discoveredIcon) {
+	t.Helper()
+	data, err := json.Marshal(icons)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, iconCacheFile), data, 0o600))
+}
+
+func TestIconCache_RoundTrip(t *testing.T) {
+	dir := useIconCache(t, 3600)
+
+	storeDiscoveredIcon("grafana", "https://grafana.lan", "https://grafana.lan/favicon.ico", IconSourceFavicon)
+	storeDiscoveredIcon("nas", "https://nas.lan", "https://nas.lan/logo.png", IconSourceHTML)
+	require.NoError(t, SaveCache())
+	assert.FileExists(t, filepath.Join(dir, iconCacheFile))
+
+	resetDiscoveredIcons()
+	require.NoError(t, LoadCache())
+
+	icon, ok := cachedDiscoveredIcon("grafana", "https://grafana.lan")
+	require.True(t, ok)
+	assert.Equal(t, "https://grafana.lan/favicon.ico", icon.URL)
+	assert.Equal(t, IconSourceFavicon, icon.Source)
+	icon, ok = cachedDiscoveredIcon("nas", "https://nas.lan")
+	require.True(t, ok)
+	assert.Equal(t, IconSourceHTML, icon.Source)
+
+	_, ok = cachedDiscoveredIcon("grafana", "https://grafana.example.com")
+	assert.False(t, ok, "the service URL is part of the key")
+}
+
+func TestIconCache_TTLExpiry(t *testing.T) {
+	dir := useIconCache(t, 3600)
+	writeIconCacheFile(t, dir, map[string]discoveredIcon{
+		discoveredIconKey("fresh", "https://fresh.lan"): {URL: "https://fresh.lan/favicon.ico", Source: IconSourceFavicon, DiscoveredAt: time.Now().Add(-time.Minute)},
+		discoveredIconKey("old", "https://old.lan"):     {URL: "https://old.lan/favicon.ico", Source: IconSourceFavicon, DiscoveredAt: time.Now().Add(-2 * time.Hour)},
+	})
+
+	require.NoError(t, LoadCache())
+	_, ok := cachedDiscoveredIcon("fresh", "https://fresh.lan")
+	assert.True(t, ok)
+	_, ok = cachedDiscoveredIcon("old", "https://old.lan")
+	assert.False(t, ok, "icons older than the TTL are discovered again")
+
+	conf.Environment.IconCacheTTLSeconds = 30
+	_, ok = cachedDiscoveredIcon("fresh", "https://fresh.lan")
+	assert.False(t, ok, "loaded icons expire as well")
+
+	conf.Environment.IconCacheTTLSeconds = 0
+	writeIconCacheFile(t, dir, map[string]discoveredIcon{
+		discoveredIconKey("old", "https://old.lan"): {URL: "https://old.lan/favicon.ico", Source: IconSourceFavicon, DiscoveredAt: time.Now().Add(-24 * time.Hour)},
+	})
+	require.NoError(t, LoadCache())
+	_, ok = cachedDiscoveredIcon("old", "https://old.lan")
+	assert.True(t, ok, "a TTL of zero keeps icons forever")
+}
+
+func TestLoadCache_IgnoresCorruptFile(t *testing.T) {
+	dir := useIconCache(t, 3600)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, iconCacheFile), []byte(`{"grafana": [`), 0o600))
+
+	assert.Error(t, LoadCache())
+	_, ok := cachedDiscoveredIcon("grafana", "https://grafana.lan")
+	assert.False(t, ok)
+
+	storeDiscoveredIcon("grafana", "https://grafana.lan", "https://grafana.lan/favicon.ico", IconSourceFavicon)
+	require.NoError(t, SaveCache(), "the corrupt file is replaced")
+	resetDiscoveredIcons()
+	require.NoError(t, LoadCache())
+	_, ok = cachedDiscoveredIcon("grafana", "https://grafana.lan")
+	assert.True(t, ok)
+}
+
+func TestIconCache_DisabledWithoutDir(t *testing.T) {
+	useIconCache(t, 3600)
+	conf.Environment.IconCacheDir = ""
+
+	storeDiscoveredIcon("grafana", "https://grafana.lan", "https://grafana.lan/favicon.ico", IconSourceFavicon)
+	_, ok := cachedDiscoveredIcon("grafana", "https://grafana.lan")
+	assert.False(t, ok)
+	assert.NoError(t, LoadCache())
+	assert.NoError(t, SaveCache())
+}
+
+func TestFindIcon_ReusesCachedDiscovery(t *testing.T) {
+	dir := useIconCache(t, 3600)
+	var faviconRequests atomic.Int32
+	useSelfHstServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			faviconRequests.Add(1)
+			w.Header().Set("Content-Type", "image/x-icon")
+			return
+		}
+		http.NotFound(w, r)
+	})
+	serverURL := selfhstAPIURL
+
+	iconURL, source := FindIcon("grafana", serverURL, "", "grafana", "")
+	assert.Equal(t, IconSourceFavicon, source)
+	assert.Equal(t, serverURL+"/favicon.ico", iconURL)
+	require.NoError(t, SaveCache())
+	requests := faviconRequests.Load()
+
+	// A restart starts with an empty in-memory cache and loads the file.
+	resetDiscoveredIcons()
+	require.NoError(t, LoadCache())
+	iconURL, source = FindIcon("grafana", serverURL, "", "grafana", "")
+	assert.Equal(t, IconSourceFavicon, source)
+	assert.Equal(t, serverURL+"/favicon.ico", iconURL)
+	assert.Equal(t, requests, faviconRequests.Load(), "the cached icon is not discovered again")
+	assert.FileExists(t, filepath.Join(dir, iconCacheFile))
+}
@@ -0,0 +1,245 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains a decoder for Windows icon (.ico) files, registered with image.Decode.
+package icons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/bmp"
+)
+
+// icoMagic is the header of an icon file: reserved 0, type 1 (icon).
+const icoMagic = "\x00\x00\x01\x00"
+
+// pngSignature starts an icon entry stored as PNG instead of a bitmap.
+const pngSignature = "\x89PNG\r\n\x1a\n"
+
+// Sizes of the structures in an icon file.
+const (
+	icoHeaderLen     = 6
+	icoEntryLen      = 16
+	bmpFileHeaderLen = 14
+)
+
+func init() {
+	image.RegisterFormat("ico", icoMagic, decodeICO, decodeICOConfig)
+}
+
+// icoEntry describes one image in an icon file.
+type icoEntry struct {
+	width, height int
+	bitCount      int
+	data          []byte
+}
+
+// decodeICO decodes the largest image in an icon file.
+func decodeICO(r io.Reader) (image.Image, error) {
+	entry, err := readLargestICOEntry(r)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(entry.data, []byte(pngSignature)) {
+		if _, err := decodeICOPNGConfig(entry); err != nil {
+			return nil, err
+		}
+		return png.Decode(bytes.NewReader(entry.data))
+	}
+	return decodeICOBitmap(entry.data)
+}
+
+// decodeICOConfig returns the dimensions of the largest image in an icon file.
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	entry, err := readLargestICOEntry(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	if bytes.HasPrefix(entry.data, []byte(pngSignature)) {
+		return decodeICOPNGConfig(entry)
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: entry.width, Height: entry.height}, nil
+}
+
+// decodeICOPNGConfig returns the dimensions of an icon entry stored as PNG. Like bitmap
+// entries, it may not exceed the size in the icon directory, which is at most 256 pixels, so
+// a small entry cannot declare a huge image that is only discovered while decoding it.
+func decodeICOPNGConfig(entry icoEntry) (image.Config, error) {
+	cfg, err := png.DecodeConfig(bytes.NewReader(entry.data))
+	if err != nil {
+		return image.Config{}, fmt.Errorf("ico: %w", err)
+	}
+	if cfg.Width > entry.width || cfg.Height > entry.height {
+		return image.Config{}, fmt.Errorf("ico: png entry of %dx%d exceeds its directory size %dx%d", cfg.Width, cfg.Height, entry.width, entry.height)
+	}
+	return cfg, nil
+}
+
+// readLargestICOEntry reads an icon file and returns the entry with the most pixels,
+// preferring the higher color depth between entries of the same size.
+func readLargestICOEntry(r io.Reader) (icoEntry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return icoEntry{}, err
+	}
+	if len(data) < icoHeaderLen || string(data[:4]) != icoMagic {
+		return icoEntry{}, errors.New("ico: invalid format")
+	}
+
+	count := int(binary.LittleEndian.Uint16(data[4:6]))
+	if count == 0 || len(data) < icoHeaderLen+count*icoEntryLen {
+		return icoEntry{}, errors.New("ico: invalid directory")
+	}
+
+	var best icoEntry
+	for i := 0; i < count; i++ {
+		dir := data[icoHeaderLen+i*icoEntryLen:]
+		entry := icoEntry{
+			width:    int(dir[0]),
+			height:   int(dir[1]),
+			bitCount: int(binary.LittleEndian.Uint16(dir[6:8])),
+		}
+		// A stored size of 0 means 256 pixels
+		if entry.width == 0 {
+			entry.width = 256
+		}
+		if entry.height == 0 {
+			entry.height = 256
+		}
+		size := int(binary.LittleEndian.Uint32(dir[8:12]))
+		offset := int(binary.LittleEndian.Uint32(dir[12:16]))
+		if offset < 0 || size <= 0 || offset > len(data) || size > len(data)-offset {
+			continue
+		}
+		entry.data = data[offset : offset+size]
+
+		pixels, bestPixels := entry.width*entry.height, best.width*best.height
+		if pixels > bestPixels || (pixels == bestPixels && entry.bitCount > best.bitCount) {
+			best = entry
+		}
+	}
+	if best.data == nil {
+		return icoEntry{}, errors.New("ico: no valid images")
+	}
+	return best, nil
+}
+
+// decodeICOBitmap decodes an icon entry stored as a bitmap. Such an entry is a BMP without
+// its file header, whose height covers both the color bitmap and the 1-bit transparency
+// mask that follows it.
+func decodeICOBitmap(data []byte) (image.Image, error) {
+	if len(data) < 40 {
+		return nil, errors.New("ico: bitmap header too short")
+	}
+	headerLen := int(binary.LittleEndian.Uint32(data[0:4]))
+	width := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+	height := int(int32(binary.LittleEndian.Uint32(data[8:12]))) / 2
+	bitCount := int(binary.LittleEndian.Uint16(data[14:16]))
+	if headerLen < 40 || headerLen > len(data) || width <= 0 || height <= 0 || width > 256 || height > 256 {
+		return nil, fmt.Errorf("ico: unsupported bitmap %dx%d", width, height)
+	}
+
+	paletteLen := 0
+	if bitCount <= 8 {
+		colors := int(binary.LittleEndian.Uint32(data[32:36]))
+		if colors == 0 {
+			colors = 1 << bitCount
+		}
+		paletteLen = colors * 4
+	}
+	pixelOffset := headerLen + paletteLen
+	if pixelOffset > len(data) {
+		return nil, errors.New("ico: truncated bitmap")
+	}
+	maskOffset := pixelOffset + bitmapStride(width, bitCount)*height
+
+	var img *image.NRGBA
+	if bitCount == 32 {
+		img = decodeICOBitmap32(data[pixelOffset:], width, height)
+		if img == nil {
+			return nil, errors.New("ico: truncated bitmap")
+		}
+	} else {
+		// Restore the file header and the real height so the BMP decoder can read the colors
+		bmpData := make([]byte, bmpFileHeaderLen+len(data))
+		copy(bmpData, "BM")
+		binary.LittleEndian.PutUint32(bmpData[2:6], uint32(len(bmpData)))
+		binary.LittleEndian.PutUint32(bmpData[10:14], uint32(bmpFileHeaderLen+pixelOffset))
+		copy(bmpData[bmpFileHeaderLen:], data)
+		binary.LittleEndian.PutUint32(bmpData[bmpFileHeaderLen+8:], uint32(int32(height)))
+
+		src, err := bmp.Decode(bytes.NewReader(bmpData))
+		if err != nil {
+			return nil, fmt.Errorf("ico: %w", err)
+		}
+		img = image.NewNRGBA(src.Bounds())
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				img.Set(x, y, src.At(x, y))
+			}
+		}
+	}
+
+	if bitCount != 32 || !hasAlpha(img) {
+		applyICOMask(img, data, maskOffset)
+	}
+	return img, nil
+}
+
+// decodeICOBitmap32 reads bottom-up BGRA rows. It returns nil when data is too short.
+func decodeICOBitmap32(data []byte, width, height int) *image.NRGBA {
+	stride := width * 4
+	if len(data) < stride*height {
+		return nil
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := data[(height-1-y)*stride:]
+		for x := 0; x < width; x++ {
+			b, g, r, a := row[x*4], row[x*4+1], row[x*4+2], row[x*4+3]
+			copy(img.Pix[img.PixOffset(x, y):], []byte{r, g, b, a})
+		}
+	}
+	return img
+}
+
+// hasAlpha reports whether any pixel of img is not fully transparent. Icons with a 32-bit
+// bitmap but an empty alpha channel rely on the transparency mask instead.
+func hasAlpha(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// applyICOMask makes the pixels set in the 1-bit transparency mask transparent and all others
+// opaque. A missing or truncated mask leaves the image opaque.
+func applyICOMask(img *image.NRGBA, data []byte, maskOffset int) {
+	width, height := img.Bounds().Dx(), img.Bounds().Dy()
+	stride := bitmapStride(width, 1)
+	hasMask := maskOffset >= 0 && maskOffset+stride*height <= len(data)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			alpha := byte(0xff)
+			if hasMask {
+				row := data[maskOffset+(height-1-y)*stride:]
+				if row[x/8]&(0x80>>(x%8)) != 0 {
+					alpha = 0
+				}
+			}
+			img.Pix[img.PixOffset(x, y)+3] = alpha
+		}
+	}
+}
+
+// bitmapStride returns the length of a bitmap row, which is padded to a multiple of 4 bytes.
+func bitmapStride(width, bitCount int) int {
+	return (width*bitCount + 31) / 32 * 4
+}
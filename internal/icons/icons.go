@@ -5,12 +5,20 @@ package icons
 import (
 	"fmt"
 	"log"
+	"math"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"server/internal/config"
+	"server/internal/models"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/lithammer/fuzzysearch/fuzzy"
@@ -20,6 +28,16 @@ import (
 // The frontend will use a fallback if icon is empty.
 const DefaultIcon = ""
 
+// Icon sources reported by FindIcon, describing which discovery method produced the icon.
+const (
+	IconSourceOverride = "override"
+	IconSourceUser     = "user"
+	IconSourceSelfHst  = "selfhst"
+	IconSourceFavicon  = "favicon"
+	IconSourceHTML     = "html"
+	IconSourceFallback = "fallback"
+)
+
 var conf *config.TralaConfiguration
 
 // Init stores the configuration instance for use by icon functions.
@@ -27,20 +45,50 @@ func Init(c *config.TralaConfiguration) {
 	conf = c
 }
 
-// FindIcon tries all icon-finding methods in order of priority and returns the icon URL.
+// FindIcon tries all icon-finding methods in order of priority and returns the icon URL
+// together with the IconSource* constant of the method that matched.
 // The priority order is:
 // 1. User-defined overrides (from configuration)
 // 2. User icons (fuzzy matched from /icons directory)
-// 3. SelfHst icons (fuzzy matched from selfh.st icon library)
+// 3. SelfHst icons (fuzzy matched from selfh.st icon library, unless use_selfhst_icons is off)
 // 4. /favicon.ico from the service URL
 // 5. HTML parsing for <link> tags
-func FindIcon(routerName, serviceURL string, displayNameReplaced string, reference string) string {
+// With icon_cache_dir set, the results of 4 and 5 are reused from the disk cache, see LoadCache.
+// When host is set, favicon and HTML discovery send it as the Host header, see FindFavicon.
+// When the icon proxy is enabled, allow-listed external icons are rewritten to the proxy route.
+func FindIcon(routerName, serviceURL, host string, displayNameReplaced string, reference string) (string, string) {
+	iconURL, source := findIcon(routerName, serviceURL, host, displayNameReplaced, reference)
+	return ProxiedIconURL(iconURL), source
+}
+
+// IsLocalIcon reports whether an icon URL returned by FindIcon is served by TraLa itself, as
+// custom, normalized and proxied icons are. Those are paths on the TraLa origin, while icons
+// from selfh.st, overrides and the services themselves are absolute URLs.
+func IsLocalIcon(iconURL string) bool {
+	if iconURL == "" {
+		return false
+	}
+	u, err := url.Parse(iconURL)
+	return err == nil && u.Scheme == "" && u.Host == ""
+}
+
+// localIconURL prefixes the path of an icon served by TraLa itself with the configured base
+// path, so the URL also works when a reverse proxy serves TraLa below a path.
+func localIconURL(path string) string {
+	if conf == nil {
+		return path
+	}
+	return conf.GetBasePath() + path
+}
+
+// findIcon implements the icon priority order documented on FindIcon.
+func findIcon(routerName, serviceURL, host string, displayNameReplaced string, reference string) (string, string) {
 	// Priority 1: Check user-defined overrides.
 	if iconValue := conf.GetIconOverride(routerName); iconValue != "" {
 		// Check if it's a full URL
 		if strings.HasPrefix(iconValue, "http://") || strings.HasPrefix(iconValue, "https://") {
 			debugf("[%s] Found icon via override (full URL): %s", routerName, iconValue)
-			return iconValue
+			return iconValue, IconSourceOverride
 		}
 
 		// Check if it's a filename with valid extension
@@ -48,48 +96,62 @@ func FindIcon(routerName, serviceURL string, displayNameReplaced string, referen
 		if ext == ".png" || ext == ".svg" || ext == ".webp" {
 			iconURL := conf.GetSelfhstIconURL() + strings.TrimPrefix(ext, ".") + "/" + strings.ToLower(iconValue)
 			debugf("[%s] Found icon via override (filename): %s", routerName, iconURL)
-			return iconURL
+			return iconURL, IconSourceOverride
 		}
 
 		// Fallback to default behavior if extension is not valid
 		iconURL := conf.GetSelfhstIconURL() + "png/" + strings.ToLower(iconValue) + ".png"
 		debugf("[%s] Found icon via override (fallback): %s", routerName, iconURL)
-		return iconURL
+		return iconURL, IconSourceOverride
 	}
 
 	// Priority 2: Check user icons
 	if iconPath := FindUserIcon(displayNameReplaced); iconPath != "" {
-		// For user icons, we return the URL that can be served by the application
-		debugf("[%s] Found icon via user icons (fuzzy search): %s", displayNameReplaced, iconPath)
-		return iconPath
+		if iconURL := UserIconURL(iconPath); iconURL != "" {
+			debugf("[%s] Found icon via user icons (fuzzy search): %s -> %s", displayNameReplaced, iconPath, iconURL)
+			return iconURL, IconSourceUser
+		}
 	}
 
 	// Priority 3: Fuzzy search against selfh.st icons
-	if reference != "" {
+	if reference != "" && conf.GetUseSelfhstIcons() {
 		iconURL := GetSelfHstIconURL(reference)
 		debugf("[%s] Found icon via fuzzy search: %s", displayNameReplaced, iconURL)
-		return iconURL
+		return iconURL, IconSourceSelfHst
+	}
+
+	// Favicon and HTML discovery contact the service, so their result is reused from the
+	// disk cache when one is configured.
+	if icon, ok := cachedDiscoveredIcon(routerName, serviceURL); ok {
+		debugf("[%s] Found icon via icon cache (%s): %s", routerName, icon.Source, icon.URL)
+		return NormalizeFavicon(icon.URL), icon.Source
 	}
 
 	// Priority 4: Check for /favicon.ico.
-	if iconURL := FindFavicon(serviceURL); iconURL != "" {
+	if iconURL := FindFavicon(serviceURL, host); iconURL != "" {
 		debugf("[%s] Found icon via /favicon.ico: %s", routerName, iconURL)
-		return iconURL
+		storeDiscoveredIcon(routerName, serviceURL, iconURL, IconSourceFavicon)
+		return NormalizeFavicon(iconURL), IconSourceFavicon
 	}
 
 	// Priority 5: Parse service's HTML for a <link> tag.
-	if iconURL := FindHTMLIcon(serviceURL); iconURL != "" {
+	if iconURL := FindHTMLIcon(serviceURL, host); iconURL != "" {
 		debugf("[%s] Found icon via HTML parsing: %s", routerName, iconURL)
-		return iconURL
+		storeDiscoveredIcon(routerName, serviceURL, iconURL, IconSourceHTML)
+		return NormalizeFavicon(iconURL), IconSourceHTML
 	}
 
 	debugf("[%s] No icon found, will use fallback.", routerName)
-	return DefaultIcon
+	return DefaultIcon, IconSourceFallback
 }
 
 // FindTags finds tags for a service using the provided selfh.st reference.
-// Returns an empty slice if no tags are found or if reference is empty.
+// Returns an empty slice if no tags are found, if reference is empty or if grouping is
+// disabled; in the latter case the selfh.st apps index is not fetched.
 func FindTags(routerName string, reference string) []string {
+	if !conf.GetGroupingEnabled() {
+		return []string{}
+	}
 	if reference != "" {
 		tags := GetServiceTags(reference)
 		debugf("[%s] Found tags via fuzzy search: %v", routerName, tags)
@@ -100,9 +162,20 @@ func FindTags(routerName string, reference string) []string {
 	return []string{}
 }
 
-// ResolveSelfHstReference performs fuzzy search to find the matching selfh.st reference for a service name.
-// Returns the best matching reference string, or empty string if no match found.
-func ResolveSelfHstReference(serviceName string) string {
+// categoryMatchSlack is how much further, in Levenshtein distance, a fuzzy match in a preferred
+// category may be from the service name than the match that would be picked without one.
+const categoryMatchSlack = 2
+
+// ResolveSelfHstReference finds the matching selfh.st reference for a service name.
+// An exact (case-insensitive) reference match is preferred before falling back to fuzzy search.
+// When icon category matching is enabled, categories (such as the group and tags of the
+// service) bias the fuzzy search towards close matches in one of those selfh.st categories.
+// Returns the best matching reference string, or empty string if no match found or the
+// selfh.st icon source is disabled, in which case the index is not fetched.
+func ResolveSelfHstReference(serviceName string, categories ...string) string {
+	if !conf.GetUseSelfhstIcons() {
+		return ""
+	}
 	icons, err := GetSelfHstIconNames()
 	if err != nil {
 		log.Printf("ERROR: Could not get selfh.st icon list for reference resolution: %v", err)
@@ -111,9 +184,22 @@ func ResolveSelfHstReference(serviceName string) string {
 
 	references := make([]string, len(icons))
 	for i, icon := range icons {
+		// A reference equal to the service name is always the best match
+		if strings.EqualFold(icon.Reference, serviceName) {
+			return icon.Reference
+		}
 		references[i] = icon.Reference
 	}
 
+	if !fuzzyMatchAllowed(serviceName) {
+		debugf("[%s] Name shorter than %d characters, skipping fuzzy selfh.st match", serviceName, conf.GetIconMinFuzzyLength())
+		return ""
+	}
+
+	if conf.GetIconCategoryMatching() && len(categories) > 0 {
+		return resolveInCategories(serviceName, icons, references, categories)
+	}
+
 	matches := fuzzy.FindFold(serviceName, references)
 	if len(matches) > 0 {
 		return matches[0]
@@ -121,6 +207,77 @@ func ResolveSelfHstReference(serviceName string) string {
 	return ""
 }
 
+// resolveInCategories fuzzy-matches serviceName like ResolveSelfHstReference, but prefers the
+// closest match whose selfh.st category is one of categories, as long as it is within
+// categoryMatchSlack of the match picked without a category. references holds the references
+// of icons in the same order.
+func resolveInCategories(serviceName string, icons []models.SelfHstIcon, references, categories []string) string {
+	ranks := fuzzy.RankFindFold(serviceName, references)
+	if len(ranks) == 0 {
+		return ""
+	}
+	// Ranks follow the index order, so the first one is the match picked without a category
+	fallback := ranks[0]
+	best := -1
+	for i, rank := range ranks {
+		if rank.Distance > fallback.Distance+categoryMatchSlack || !inCategory(icons[rank.OriginalIndex].Category, categories) {
+			continue
+		}
+		if best < 0 || rank.Distance < ranks[best].Distance {
+			best = i
+		}
+	}
+	if best < 0 {
+		return fallback.Target
+	}
+	if ranks[best].Target != fallback.Target {
+		debugf("[%s] Preferring %s in category %q over %s", serviceName, ranks[best].Target, icons[ranks[best].OriginalIndex].Category, fallback.Target)
+	}
+	return ranks[best].Target
+}
+
+// inCategory reports whether a selfh.st category equals one of categories, ignoring case and
+// treating dashes and underscores as spaces.
+func inCategory(category string, categories []string) bool {
+	if category == "" {
+		return false
+	}
+	normalize := strings.NewReplacer("-", " ", "_", " ")
+	category = normalize.Replace(strings.TrimSpace(category))
+	for _, c := range categories {
+		if strings.EqualFold(category, normalize.Replace(strings.TrimSpace(c))) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSelfHstReference reports whether name exactly (case-insensitively) matches a selfh.st
+// reference. It is always false when the selfh.st icon source is disabled.
+func IsSelfHstReference(name string) bool {
+	if !conf.GetUseSelfhstIcons() {
+		return false
+	}
+	icons, err := GetSelfHstIconNames()
+	if err != nil {
+		log.Printf("ERROR: Could not get selfh.st icon list for reference lookup: %v", err)
+		return false
+	}
+	for _, icon := range icons {
+		if strings.EqualFold(icon.Reference, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyMatchAllowed reports whether name is long enough for fuzzy icon matching.
+// Very short names match a large part of any index, so below the configured minimum
+// length only exact matches are used.
+func fuzzyMatchAllowed(name string) bool {
+	return len([]rune(name)) >= conf.GetIconMinFuzzyLength()
+}
+
 // GetSelfHstIconURL generates the icon URL for a given selfh.st reference.
 // Prefers SVG format if available, otherwise falls back to PNG.
 func GetSelfHstIconURL(reference string) string {
@@ -148,7 +305,8 @@ func GetSelfHstIconURL(reference string) string {
 }
 
 // GetServiceTags retrieves the tags for a given selfh.st reference.
-// Returns an empty slice if no tags are found or if reference is empty.
+// Returns an empty slice if no tags are found or if reference is empty. The tags are a copy,
+// so callers may append to them without changing the cached apps.
 func GetServiceTags(reference string) []string {
 	if reference == "" {
 		return []string{}
@@ -162,7 +320,7 @@ func GetServiceTags(reference string) []string {
 
 	for _, entry := range data {
 		if entry.Reference == reference {
-			return entry.Tags
+			return slices.Clone(entry.Tags)
 		}
 	}
 
@@ -171,26 +329,35 @@ func GetServiceTags(reference string) []string {
 
 // FindFavicon checks for the existence of /favicon.ico at the service URL.
 // Returns the favicon URL if it exists and is a valid image, otherwise empty string.
-func FindFavicon(serviceURL string) string {
+// host is the host name the service is routed by, such as the Host rule of its router. When it
+// differs from the host of serviceURL, because of a host rewrite or default domain, it is sent
+// as the Host header so virtual-hosted backends answer for the right site.
+func FindFavicon(serviceURL, host string) string {
 	u, err := url.Parse(serviceURL)
-	if err != nil {
+	if err != nil || !isScrapeAllowedURL(serviceURL) {
 		return ""
 	}
 	faviconURL := fmt.Sprintf("%s://%s/favicon.ico", u.Scheme, u.Host)
-	if IsValidImageURL(faviconURL) {
+	if isValidImageURL(faviconURL, host) {
 		return faviconURL
 	}
 	return ""
 }
 
 // FindHTMLIcon fetches and parses the service's HTML to find icon links.
-// It looks for apple-touch-icon and icon link rels in order.
-func FindHTMLIcon(serviceURL string) string {
-	if externalHTTPClient == nil {
+// It considers the links matching the rels of icon_link_rels and returns the largest valid
+// icon, see htmlIconCandidates. host is sent as the Host header like in FindFavicon, also when
+// validating icons on the same host as the page.
+func FindHTMLIcon(serviceURL, host string) string {
+	if externalHTTPClient == nil || !isScrapeAllowedURL(serviceURL) {
 		return ""
 	}
 
-	resp, err := externalHTTPClient.Get(serviceURL)
+	req, err := newIconRequest(http.MethodGet, serviceURL, host)
+	if err != nil {
+		return ""
+	}
+	resp, err := externalHTTPClient.Do(req)
 	if err != nil {
 		return ""
 	}
@@ -202,34 +369,179 @@ func FindHTMLIcon(serviceURL string) string {
 	if err != nil {
 		return ""
 	}
-	selectors := []string{"link[rel='apple-touch-icon']", "link[rel='icon']"}
-	for _, selector := range selectors {
-		if iconPath, exists := doc.Find(selector).Attr("href"); exists {
-			// Use the final URL after redirects as the base for resolving relative URLs
-			finalURL := resp.Request.URL.String()
-			absoluteIconURL, err := resolveURL(finalURL, iconPath)
-			if err == nil && IsValidImageURL(absoluteIconURL) {
-				return absoluteIconURL
-			}
+	// Use the final URL after redirects as the base for resolving relative URLs
+	finalURL := resp.Request.URL.String()
+	for _, iconPath := range htmlIconCandidates(doc, conf.GetIconLinkRels()) {
+		absoluteIconURL, err := resolveURL(finalURL, iconPath)
+		if err == nil && isScrapeAllowedURL(absoluteIconURL) && isValidImageURL(absoluteIconURL, sameHost(absoluteIconURL, resp.Request)) {
+			return absoluteIconURL
 		}
 	}
 	return ""
 }
 
+// htmlIconLink is an icon link found in a service's HTML.
+type htmlIconLink struct {
+	href     string
+	size     int // largest dimension from the sizes attribute, 0 if unknown
+	priority int // index of the first matching rel in icon_link_rels
+}
+
+// defaultAppleTouchIconSize is the size assumed for an apple-touch-icon without a sizes
+// attribute, the size iOS uses for home screen icons.
+const defaultAppleTouchIconSize = 180
+
+// htmlIconCandidates returns the hrefs of the links matching one of rels, largest icon first.
+// Links without a usable size keep the order of rels, and then the document order, relative to
+// links of the same size.
+func htmlIconCandidates(doc *goquery.Document, rels []string) []string {
+	var links []htmlIconLink
+	doc.Find("link[rel][href]").Each(func(_ int, link *goquery.Selection) {
+		linkRel, _ := link.Attr("rel")
+		priority := slices.IndexFunc(rels, func(rel string) bool { return relMatches(linkRel, rel) })
+		if priority < 0 {
+			return
+		}
+		href, _ := link.Attr("href")
+		sizes, _ := link.Attr("sizes")
+		size := largestIconSize(sizes)
+		if size == 0 && relMatches(linkRel, "apple-touch-icon") {
+			size = defaultAppleTouchIconSize
+		}
+		links = append(links, htmlIconLink{href: href, size: size, priority: priority})
+	})
+
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].size != links[j].size {
+			return links[i].size > links[j].size
+		}
+		return links[i].priority < links[j].priority
+	})
+	hrefs := make([]string, len(links))
+	for i, link := range links {
+		hrefs[i] = link.href
+	}
+	return hrefs
+}
+
+// largestIconSize returns the largest dimension listed in a sizes attribute such as
+// "16x16 32x32". "any", used for scalable icons, counts as larger than any listed size.
+// Returns 0 when no size can be parsed.
+func largestIconSize(sizes string) int {
+	largest := 0
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return math.MaxInt
+		}
+		width, height, ok := strings.Cut(size, "x")
+		if !ok {
+			continue
+		}
+		w, errW := strconv.Atoi(width)
+		h, errH := strconv.Atoi(height)
+		if errW != nil || errH != nil {
+			continue
+		}
+		largest = max(largest, w, h)
+	}
+	return largest
+}
+
+// relMatches reports whether the rel attribute of a link contains every keyword of rel, ignoring
+// case, so "icon" matches both "icon" and "shortcut icon" but not "mask-icon".
+func relMatches(linkRel, rel string) bool {
+	keywords := strings.Fields(strings.ToLower(linkRel))
+	for _, want := range strings.Fields(rel) {
+		if !slices.Contains(keywords, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// newIconRequest builds an icon discovery request for rawURL. When host is set and differs from
+// the host name of rawURL, it is sent as the Host header, keeping the port of rawURL.
+func newIconRequest(method, rawURL, host string) (*http.Request, error) {
+	req, err := http.NewRequest(method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" || strings.EqualFold(host, req.URL.Hostname()) {
+		return req, nil
+	}
+	if port := req.URL.Port(); port != "" {
+		req.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		req.Host = "[" + host + "]"
+	} else {
+		req.Host = host
+	}
+	return req, nil
+}
+
+// sameHost returns the host name of the Host header of page when iconURL is on the same host as
+// the page, so icons of a virtual-hosted page are requested like the page itself, and ""
+// otherwise.
+func sameHost(iconURL string, page *http.Request) string {
+	u, err := url.Parse(iconURL)
+	if err != nil || page.Host == "" || !strings.EqualFold(u.Host, page.URL.Host) {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(page.Host); err == nil {
+		return host
+	}
+	return strings.Trim(page.Host, "[]")
+}
+
+// imageExtensions are the extensions of icon URLs that are accepted as images even when the
+// server reports a non-image content type.
+var imageExtensions = map[string]bool{
+	".ico": true, ".png": true, ".svg": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true,
+}
+
 // IsValidImageURL performs a HEAD request to check if a URL points to a valid image.
-// Returns true if the URL returns a 200 OK status with an image content type.
+// Returns true if the URL returns a 200 OK status with an accepted content type, see
+// isImageContentType.
 func IsValidImageURL(iconURL string) bool {
+	return isValidImageURL(iconURL, "")
+}
+
+// isValidImageURL is IsValidImageURL, sending host as the Host header, see newIconRequest.
+func isValidImageURL(iconURL, host string) bool {
 	if externalHTTPClient == nil {
 		return false
 	}
 
-	resp, err := externalHTTPClient.Head(iconURL)
+	req, err := newIconRequest(http.MethodHead, iconURL, host)
+	if err != nil {
+		return false
+	}
+	resp, err := externalHTTPClient.Do(req)
 	if err != nil {
 		return false
 	}
 	defer resp.Body.Close()
-	contentType := resp.Header.Get("Content-Type")
-	return resp.StatusCode == http.StatusOK && strings.HasPrefix(contentType, "image/")
+	return resp.StatusCode == http.StatusOK && isImageContentType(iconURL, resp.Header.Get("Content-Type"))
+}
+
+// isImageContentType reports whether a response for iconURL with contentType is an image: an
+// image/* type, a type listed in icon_content_types, or, because servers often misreport
+// favicons as application/octet-stream or text/plain, any type but HTML when the URL path ends
+// in an image extension. HTML is rejected because single-page apps answer every path with
+// their index page.
+func isImageContentType(iconURL, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+	if strings.HasPrefix(mediaType, "image/") || (mediaType != "" && conf.IsIconContentType(mediaType)) {
+		return true
+	}
+	if mediaType == "text/html" {
+		return false
+	}
+	u, err := url.Parse(iconURL)
+	return err == nil && imageExtensions[strings.ToLower(path.Ext(u.Path))]
 }
 
 // resolveURL resolves a path against a base URL, returning the absolute URL.
@@ -0,0 +1,789 @@
+package icons
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// --- Test helpers ---
+
+// useSelfHstIcons primes the selfh.st icon cache with the given references and restores it
+// after the test. References should be passed shortest first, as GetSelfHstIconNames sorts them.
+func useSelfHstIcons(t testing.TB, references ...string) {
+	t.Helper()
+	icons := make([]models.SelfHstIcon, len(references))
+	for i, ref := range references {
+		icons[i] = models.SelfHstIcon{Reference: ref, SVG: "Yes"}
+	}
+	useSelfHstIndex(t, icons...)
+}
+
+// useSelfHstIndex primes the selfh.st icon cache with the given icons, in that order, and
+// restores it after the test.
+func useSelfHstIndex(t testing.TB, icons ...models.SelfHstIcon) {
+	t.Helper()
+	selfhstCacheMux.Lock()
+	previousIcons, previousTime := selfhstIcons, selfhstCacheTime
+	selfhstIcons, selfhstCacheTime = icons, time.Now()
+	selfhstCacheMux.Unlock()
+
+	t.Cleanup(func() {
+		selfhstCacheMux.Lock()
+		selfhstIcons, selfhstCacheTime = previousIcons, previousTime
+		selfhstCacheMux.Unlock()
+	})
+}
+
+// --- selfh.st reference tests ---
+
+func TestResolveSelfHstReference_ExactMatch(t *testing.T) {
+	useConfig(t, newTestConfig())
+	useSelfHstIcons(t, "plex", "plexamp", "home-assistant", "plex-meta-manager")
+
+	cases := []struct {
+		name    string
+		service string
+		want    string
+	}{
+		{"exact wins over longer variants", "plex", "plex"},
+		{"exact match for a variant", "plexamp", "plexamp"},
+		{"exact is case-insensitive", "Home-Assistant", "home-assistant"},
+		{"fuzzy fallback without exact match", "plx", "plex"},
+		{"no match", "grafana", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ResolveSelfHstReference(tc.service))
+		})
+	}
+}
+
+func TestResolveSelfHstReference_MinFuzzyLength(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.IconMinFuzzyLength = 4
+	useConfig(t, c)
+	useSelfHstIcons(t, "n8n", "nginx", "n8n-workflows", "nextcloud")
+
+	assert.Equal(t, "n8n", ResolveSelfHstReference("n8n"), "exact matches ignore the minimum length")
+	assert.Empty(t, ResolveSelfHstReference("nx"), "short names skip fuzzy matching")
+	assert.Equal(t, "nextcloud", ResolveSelfHstReference("nxtc"), "names at the minimum length still fuzzy match")
+}
+
+func TestResolveSelfHstReference_Categories(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.IconCategoryMatching = true
+	useConfig(t, c)
+	useSelfHstIndex(t,
+		models.SelfHstIcon{Reference: "photon", Category: "Networking"},
+		models.SelfHstIcon{Reference: "photos", Category: "Photo Management"},
+		models.SelfHstIcon{Reference: "photoview", Category: "Photo Management"},
+		models.SelfHstIcon{Reference: "photon-streaming", Category: "Media"},
+	)
+
+	cases := []struct {
+		name       string
+		service    string
+		categories []string
+		want       string
+	}{
+		{"no categories keeps the default match", "photo", nil, "photon"},
+		{"closest match in the category", "photo", []string{"Photo Management"}, "photos"},
+		{"category ignores case and dashes", "photo", []string{"photo-management"}, "photos"},
+		{"unknown category keeps the default match", "photo", []string{"gaming"}, "photon"},
+		{"distant category match is ignored", "photo", []string{"media"}, "photon"},
+		{"exact match wins over the category", "photon", []string{"photo management"}, "photon"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, ResolveSelfHstReference(tc.service, tc.categories...))
+		})
+	}
+
+	c.Environment.IconCategoryMatching = false
+	assert.Equal(t, "photon", ResolveSelfHstReference("photo", "photo management"), "categories are ignored unless enabled")
+}
+
+func TestIsSelfHstReference(t *testing.T) {
+	useConfig(t, newTestConfig())
+	useSelfHstIcons(t, "plex", "grafana")
+
+	assert.True(t, IsSelfHstReference("Grafana"))
+	assert.False(t, IsSelfHstReference("graf"), "fuzzy matches are not references")
+	assert.False(t, IsSelfHstReference("apps"))
+}
+
+// --- Image validation tests ---
+
+func TestIsValidImageURL_ContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		if r.URL.Query().Get("missing") != "" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = server.Client()
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+
+	cases := []struct {
+		name         string
+		path         string
+		contentType  string
+		extraTypes   []string
+		wantAccepted bool
+	}{
+		{"image type", "/icon", "image/png", nil, true},
+		{"image type with parameters", "/icon", "image/svg+xml; charset=utf-8", nil, true},
+		{"octet-stream png", "/favicon.png", "application/octet-stream", nil, true},
+		{"text/plain ico", "/favicon.ico", "text/plain", nil, true},
+		{"octet-stream without extension", "/icon", "application/octet-stream", nil, false},
+		{"configured content type", "/icon", "Application/Octet-Stream", []string{"application/octet-stream"}, true},
+		{"html page for an image path", "/favicon.ico", "text/html; charset=utf-8", nil, false},
+		{"not found", "/favicon.ico?missing=1", "image/x-icon", nil, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestConfig()
+			c.Environment.IconContentTypes = tc.extraTypes
+			useConfig(t, c)
+
+			u, err := url.Parse(server.URL + tc.path)
+			require.NoError(t, err)
+			q := u.Query()
+			q.Set("type", tc.contentType)
+			u.RawQuery = q.Encode()
+
+			assert.Equal(t, tc.wantAccepted, IsValidImageURL(u.String()))
+		})
+	}
+}
+
+// --- ICO tests ---
+
+// buildICO wraps the given entry images in an icon file directory.
+func buildICO(entries ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(icoMagic)
+	binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+	offset := icoHeaderLen + len(entries)*icoEntryLen
+	for _, entry := range entries {
+		var width, height int
+		if bytes.HasPrefix(entry, []byte(pngSignature)) {
+			cfg, _ := png.DecodeConfig(bytes.NewReader(entry))
+			width, height = cfg.Width, cfg.Height
+		} else {
+			width = int(binary.LittleEndian.Uint32(entry[4:8]))
+			height = int(binary.LittleEndian.Uint32(entry[8:12])) / 2
+		}
+		buf.Write([]byte{byte(width), byte(height), 0, 0, 1, 0, 32, 0})
+		binary.Write(&buf, binary.LittleEndian, uint32(len(entry)))
+		binary.Write(&buf, binary.LittleEndian, uint32(offset))
+		offset += len(entry)
+	}
+	for _, entry := range entries {
+		buf.Write(entry)
+	}
+	return buf.Bytes()
+}
+
+// buildICOBitmap returns a size x size icon bitmap with the given color depth (24 or 32). The
+// top-left pixel is transparent, through the alpha channel or the mask, and all others are red.
+func buildICOBitmap(size, bitCount int, useAlpha bool) []byte {
+	var buf bytes.Buffer
+	header := make([]byte, 40)
+	binary.LittleEndian.PutUint32(header[0:4], 40)
+	binary.LittleEndian.PutUint32(header[4:8], uint32(size))
+	binary.LittleEndian.PutUint32(header[8:12], uint32(size*2))
+	binary.LittleEndian.PutUint16(header[12:14], 1)
+	binary.LittleEndian.PutUint16(header[14:16], uint16(bitCount))
+	buf.Write(header)
+
+	// Rows are stored bottom-up in BGR(A) order
+	stride := bitmapStride(size, bitCount)
+	for y := size - 1; y >= 0; y-- {
+		row := make([]byte, stride)
+		for x := 0; x < size; x++ {
+			if bitCount == 32 {
+				// Without alpha the channel is left empty, as in older icons
+				alpha := byte(0)
+				if useAlpha && (x != 0 || y != 0) {
+					alpha = 0xff
+				}
+				copy(row[x*4:], []byte{0, 0, 0xff, alpha})
+			} else {
+				copy(row[x*3:], []byte{0, 0, 0xff})
+			}
+		}
+		buf.Write(row)
+	}
+
+	maskStride := bitmapStride(size, 1)
+	for y := size - 1; y >= 0; y-- {
+		row := make([]byte, maskStride)
+		if !useAlpha && y == 0 {
+			row[0] = 0x80
+		}
+		buf.Write(row)
+	}
+	return buf.Bytes()
+}
+
+// encodeTestPNG returns a size x size PNG filled with c.
+func encodeTestPNG(t *testing.T, size int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecodeICO(t *testing.T) {
+	t.Parallel()
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	cases := []struct {
+		name     string
+		data     []byte
+		wantSize int
+	}{
+		{"32-bit bitmap with alpha", buildICO(buildICOBitmap(16, 32, true)), 16},
+		{"32-bit bitmap without alpha uses the mask", buildICO(buildICOBitmap(16, 32, false)), 16},
+		{"24-bit bitmap with mask", buildICO(buildICOBitmap(16, 24, false)), 16},
+		{"largest entry wins", buildICO(buildICOBitmap(16, 24, false), buildICOBitmap(32, 32, true)), 32},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			img, format, err := image.Decode(bytes.NewReader(tc.data))
+			require.NoError(t, err)
+			assert.Equal(t, "ico", format)
+			assert.Equal(t, tc.wantSize, img.Bounds().Dx())
+			assert.Equal(t, tc.wantSize, img.Bounds().Dy())
+			assert.Equal(t, uint8(0), color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA).A, "top-left pixel is transparent")
+			assert.Equal(t, red, color.NRGBAModel.Convert(img.At(1, 1)))
+		})
+	}
+
+	t.Run("png entry", func(t *testing.T) {
+		t.Parallel()
+		img, format, err := image.Decode(bytes.NewReader(buildICO(encodeTestPNG(t, 48, red))))
+		require.NoError(t, err)
+		assert.Equal(t, "ico", format)
+		assert.Equal(t, 48, img.Bounds().Dx())
+		assert.Equal(t, red, color.NRGBAModel.Convert(img.At(10, 10)))
+	})
+
+	t.Run("png entry larger than its directory size", func(t *testing.T) {
+		t.Parallel()
+		data := buildICO(encodeTestPNG(t, 48, red))
+		data[icoHeaderLen], data[icoHeaderLen+1] = 16, 16
+		_, _, err := image.Decode(bytes.NewReader(data))
+		assert.Error(t, err)
+		_, _, err = image.DecodeConfig(bytes.NewReader(data))
+		assert.Error(t, err)
+
+		_, _, err = image.Decode(bytes.NewReader(buildICO(pngDeclaringSize(t, 50000, 50000))))
+		assert.Error(t, err, "a png entry declaring a huge size is rejected before decoding")
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		t.Parallel()
+		data := buildICO(buildICOBitmap(16, 32, true))
+		_, _, err := image.Decode(bytes.NewReader(data[:60]))
+		assert.Error(t, err)
+	})
+}
+
+func TestNormalizeFavicon_ConvertICO(t *testing.T) {
+	ico := buildICO(buildICOBitmap(16, 32, true))
+	pngData := encodeTestPNG(t, 16, color.White)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ico") {
+			w.Write(ico)
+			return
+		}
+		w.Write(pngData)
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = server.Client()
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+
+	c := newTestConfig()
+	c.Environment.ConvertICOFavicons = true
+	useConfig(t, c)
+
+	converted := NormalizeFavicon(server.URL + "/favicon.ico")
+	require.True(t, strings.HasPrefix(converted, NormalizedIconPath), "ico favicons are converted")
+	data, ok := GetNormalizedIcon(strings.TrimSuffix(strings.TrimPrefix(converted, NormalizedIconPath), ".png"))
+	require.True(t, ok)
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 16, img.Bounds().Dx(), "conversion keeps the original size")
+
+	assert.Equal(t, server.URL+"/icon.png", NormalizeFavicon(server.URL+"/icon.png"), "other formats are left alone")
+
+	c.Environment.ConvertICOFavicons = false
+	assert.Equal(t, server.URL+"/other.ico", NormalizeFavicon(server.URL+"/other.ico"), "conversion is off by default")
+}
+
+// pngDeclaringSize returns a 1x1 PNG whose header claims the given size, as a decompression
+// bomb would. Its header is valid, but decoding its pixels fails.
+func pngDeclaringSize(t *testing.T, width, height int) []byte {
+	t.Helper()
+	data := encodeTestPNG(t, 1, color.White)
+	// The IHDR chunk follows the 8-byte signature: length, type, width, height, ..., CRC.
+	binary.BigEndian.PutUint32(data[16:20], uint32(width))
+	binary.BigEndian.PutUint32(data[20:24], uint32(height))
+	binary.BigEndian.PutUint32(data[29:33], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func resetNormalizedIcons() {
+	normalizedIconsMux.Lock()
+	defer normalizedIconsMux.Unlock()
+	normalizedIcons = make(map[string][]byte)
+	normalizedIconOrder = nil
+}
+
+func TestEncodeNormalizedPNG(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name          string
+		width, height int
+		opaque        image.Point
+		transparent   []image.Point
+	}{
+		{"square", 16, 16, image.Pt(0, 0), nil},
+		{"wide is letterboxed", 64, 32, image.Pt(64, 64), []image.Point{image.Pt(64, 0), image.Pt(64, 127)}},
+		{"tall is pillarboxed", 32, 64, image.Pt(64, 64), []image.Point{image.Pt(0, 64), image.Pt(127, 64)}},
+		{"large is scaled down", 512, 512, image.Pt(127, 127), nil},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			src := image.NewNRGBA(image.Rect(0, 0, tc.width, tc.height))
+			draw.Draw(src, src.Bounds(), image.NewUniform(color.NRGBA{R: 0xff, A: 0xff}), image.Point{}, draw.Src)
+
+			data, err := encodeNormalizedPNG(src)
+			require.NoError(t, err)
+			out, err := png.Decode(bytes.NewReader(data))
+			require.NoError(t, err)
+			assert.Equal(t, image.Rect(0, 0, normalizedIconSize, normalizedIconSize), out.Bounds())
+			assert.Equal(t, uint8(0xff), color.NRGBAModel.Convert(out.At(tc.opaque.X, tc.opaque.Y)).(color.NRGBA).A, "the image covers %v", tc.opaque)
+			for _, p := range tc.transparent {
+				assert.Equal(t, uint8(0), color.NRGBAModel.Convert(out.At(p.X, p.Y)).(color.NRGBA).A, "the canvas around the image is transparent at %v", p)
+			}
+		})
+	}
+
+	t.Run("no pixels", func(t *testing.T) {
+		t.Parallel()
+		_, err := encodeNormalizedPNG(image.NewNRGBA(image.Rect(0, 0, 0, 0)))
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeFavicon_RejectsOversizedImages(t *testing.T) {
+	t.Parallel()
+	_, format, err := decodeFavicon(encodeTestPNG(t, 32, color.White))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+
+	_, _, err = decodeFavicon(pngDeclaringSize(t, 50000, 50000))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds", "the declared size is rejected before decoding")
+	_, _, err = decodeFavicon(pngDeclaringSize(t, maxFaviconDimension+1, 16))
+	assert.Error(t, err)
+	_, _, err = decodeFavicon([]byte("not an image"))
+	assert.Error(t, err)
+}
+
+func TestNormalizeFavicon_Resize(t *testing.T) {
+	pngData := encodeTestPNG(t, 32, color.White)
+	bomb := pngDeclaringSize(t, 50000, 50000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/icon.png":
+			w.Write(pngData)
+		case "/bomb.png":
+			w.Write(bomb)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = server.Client()
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+	resetNormalizedIcons()
+	t.Cleanup(resetNormalizedIcons)
+
+	c := newTestConfig()
+	c.Environment.NormalizeFavicons = true
+	useConfig(t, c)
+
+	normalized := NormalizeFavicon(server.URL + "/icon.png")
+	require.True(t, strings.HasPrefix(normalized, NormalizedIconPath), "favicons of any format are normalized")
+	data, ok := GetNormalizedIcon(strings.TrimSuffix(strings.TrimPrefix(normalized, NormalizedIconPath), ".png"))
+	require.True(t, ok)
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, normalizedIconSize, cfg.Width)
+	assert.Equal(t, normalizedIconSize, cfg.Height)
+
+	assert.Equal(t, server.URL+"/bomb.png", NormalizeFavicon(server.URL+"/bomb.png"), "an oversized favicon keeps its original URL")
+	assert.Equal(t, server.URL+"/missing.png", NormalizeFavicon(server.URL+"/missing.png"))
+}
+
+func TestStoreNormalizedIcon_DropsOldest(t *testing.T) {
+	resetNormalizedIcons()
+	t.Cleanup(resetNormalizedIcons)
+
+	for i := 0; i <= maxNormalizedIcons; i++ {
+		storeNormalizedIcon(normalizedIconKey(strings.Repeat("x", i)), []byte{byte(i)})
+	}
+	_, ok := GetNormalizedIcon(normalizedIconKey(""))
+	assert.False(t, ok, "the oldest icon is dropped")
+	_, ok = GetNormalizedIcon(normalizedIconKey("x"))
+	assert.True(t, ok)
+
+	storeNormalizedIcon(normalizedIconKey("x"), []byte{0})
+	normalizedIconsMux.RLock()
+	defer normalizedIconsMux.RUnlock()
+	assert.Len(t, normalizedIcons, maxNormalizedIcons)
+	assert.Len(t, normalizedIconOrder, maxNormalizedIcons, "replacing an icon does not count it twice")
+}
+
+// --- Icon scraping tests ---
+
+func TestIsScrapeAllowedHost(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		host    string
+		want    bool
+	}{
+		{"empty list allows all", nil, "anything.example", true},
+		{"exact host", []string{"home.lan"}, "home.lan", true},
+		{"subdomain", []string{"home.lan"}, "grafana.home.lan", true},
+		{"case and trailing dot", []string{"Home.LAN"}, "grafana.home.lan.", true},
+		{"suffix without dot boundary", []string{"home.lan"}, "evilhome.lan", false},
+		{"glob pattern", []string{"*.example.com"}, "app.example.com", true},
+		{"glob does not match apex", []string{"*.example.com"}, "example.com", false},
+		{"blocked host", []string{"home.lan"}, "169.254.169.254", false},
+		{"empty host", []string{"home.lan"}, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestConfig()
+			c.Environment.IconScrapeAllowedHosts = tc.allowed
+			useConfig(t, c)
+			assert.Equal(t, tc.want, IsScrapeAllowedHost(tc.host))
+		})
+	}
+}
+
+func TestFindFavicon_RespectsScrapeAllowList(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "image/x-icon")
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = server.Client()
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+
+	c := newTestConfig()
+	c.Environment.IconScrapeAllowedHosts = []string{"home.lan"}
+	useConfig(t, c)
+
+	assert.Empty(t, FindFavicon(server.URL, ""), "blocked hosts are not scraped")
+	assert.Empty(t, FindHTMLIcon(server.URL, ""))
+	assert.Equal(t, int32(0), requests.Load(), "no request is made to a blocked host")
+
+	c.Environment.IconScrapeAllowedHosts = []string{"127.0.0.1"}
+	assert.Equal(t, server.URL+"/favicon.ico", FindFavicon(server.URL, ""))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestFindFavicon_SendsRouterHost(t *testing.T) {
+	// The backend only serves the site for its virtual host, like a vhosted web server.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, _, _ := net.SplitHostPort(r.Host); host != "grafana" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Path {
+		case "/favicon.ico", "/img/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<link rel="icon" href="/img/logo.png">`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = server.Client()
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+	useConfig(t, newTestConfig())
+
+	assert.Empty(t, FindFavicon(server.URL, ""), "without the router host the backend does not know the site")
+	assert.Equal(t, server.URL+"/favicon.ico", FindFavicon(server.URL, "grafana"))
+	assert.Equal(t, server.URL+"/img/logo.png", FindHTMLIcon(server.URL, "grafana"))
+}
+
+func TestFindHTMLIcon_LinkRels(t *testing.T) {
+	cases := []struct {
+		name string
+		html string
+		rels []string
+		want string
+	}{
+		{"apple touch icon before icon", `<link rel="icon" href="/icon.png"><link rel="apple-touch-icon" href="/touch.png">`, nil, "/touch.png"},
+		{"shortcut icon", `<link rel="shortcut icon" href="/favicon.png">`, nil, "/favicon.png"},
+		{"rel is case-insensitive", `<link rel="Shortcut Icon" href="/favicon.png">`, nil, "/favicon.png"},
+		{"precomposed touch icon", `<link rel="apple-touch-icon-precomposed" href="/touch.png">`, nil, "/touch.png"},
+		{"fluid icon", `<link rel="fluid-icon" href="/fluid.png">`, nil, "/fluid.png"},
+		{"mask icon last", `<link rel="mask-icon" href="/mask.svg"><link rel="icon" href="/icon.png">`, nil, "/icon.png"},
+		{"missing icon falls through", `<link rel="apple-touch-icon" href="/missing.png"><link rel="icon" href="/icon.png">`, nil, "/icon.png"},
+		{"configured order", `<link rel="apple-touch-icon" href="/touch.png"><link rel="icon" href="/icon.png">`, []string{"icon"}, "/icon.png"},
+		{"rel not configured", `<link rel="fluid-icon" href="/fluid.png">`, []string{"icon"}, ""},
+		{"largest size wins", `<link rel="icon" sizes="16x16" href="/16.png"><link rel="icon" sizes="192x192" href="/192.png">`, nil, "/192.png"},
+		{"size beats rel order", `<link rel="apple-touch-icon" sizes="57x57" href="/touch.png"><link rel="icon" sizes="96x96" href="/96.png">`, nil, "/96.png"},
+		{"unsized apple touch icon beats tiny favicon", `<link rel="icon" sizes="16x16" href="/16.png"><link rel="apple-touch-icon" href="/touch.png">`, nil, "/touch.png"},
+		{"several sizes in one link", `<link rel="icon" sizes="64x64" href="/64.png"><link rel="icon" sizes="16x16 256x256" href="/multi.ico">`, nil, "/multi.ico"},
+		{"missing largest falls back to next", `<link rel="icon" sizes="32x32" href="/32.png"><link rel="icon" sizes="512x512" href="/missing.png">`, nil, "/32.png"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/":
+					w.Header().Set("Content-Type", "text/html")
+					_, _ = w.Write([]byte("<html><head>" + tc.html + "</head></html>"))
+				case "/missing.png":
+					http.NotFound(w, r)
+				default:
+					w.Header().Set("Content-Type", "image/png")
+				}
+			}))
+			t.Cleanup(server.Close)
+			previousClient := externalHTTPClient
+			externalHTTPClient = server.Client()
+			t.Cleanup(func() { externalHTTPClient = previousClient })
+			c := newTestConfig()
+			if tc.rels != nil {
+				c.Environment.IconLinkRels = tc.rels
+			}
+			useConfig(t, c)
+
+			want := ""
+			if tc.want != "" {
+				want = server.URL + tc.want
+			}
+			assert.Equal(t, want, FindHTMLIcon(server.URL, ""))
+		})
+	}
+}
+
+func TestLargestIconSize(t *testing.T) {
+	t.Parallel()
+	cases := map[string]int{
+		"":              0,
+		"16x16":         16,
+		"16x16 192x192": 192,
+		"120X60":        120,
+		"bogus 32x32":   32,
+		"axb":           0,
+		"any":           math.MaxInt,
+	}
+	for sizes, want := range cases {
+		assert.Equal(t, want, largestIconSize(sizes), sizes)
+	}
+}
+
+func TestIsAllowedIconAddress(t *testing.T) {
+	defaults := []string{"link-local", "100.100.100.200/32", "fd00:ec2::254/128"}
+	cases := []struct {
+		name    string
+		blocked []string
+		allowed []string
+		ip      string
+		want    bool
+	}{
+		{"metadata address blocked by default", defaults, nil, "169.254.169.254", false},
+		{"alibaba metadata blocked by default", defaults, nil, "100.100.100.200", false},
+		{"ipv6 metadata blocked by default", defaults, nil, "fd00:ec2::254", false},
+		{"private address allowed by default", defaults, nil, "192.168.1.10", true},
+		{"loopback allowed by default", defaults, nil, "127.0.0.1", true},
+		{"private class blocked", []string{"private"}, nil, "10.1.2.3", false},
+		{"loopback class blocked", []string{"loopback"}, nil, "::1", false},
+		{"single address blocked", []string{"192.168.1.10"}, nil, "192.168.1.10", false},
+		{"allowed network overrides block", []string{"private"}, []string{"192.168.1.0/24"}, "192.168.1.10", true},
+		{"allowed network does not cover others", []string{"private"}, []string{"192.168.1.0/24"}, "10.0.0.1", false},
+		{"empty block list allows all", nil, nil, "169.254.169.254", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestConfig()
+			c.Environment.IconBlockedNetworks = tc.blocked
+			c.Environment.IconAllowedNetworks = tc.allowed
+			useConfig(t, c)
+			assert.Equal(t, tc.want, IsAllowedIconAddress(net.ParseIP(tc.ip)))
+		})
+	}
+}
+
+func TestNewExternalHTTPClient_RefusesBlockedNetworks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+	}))
+	t.Cleanup(server.Close)
+	previousClient := externalHTTPClient
+	externalHTTPClient = NewExternalHTTPClient(config.ExternalClientConfig{})
+	t.Cleanup(func() { externalHTTPClient = previousClient })
+
+	c := newTestConfig()
+	c.Environment.IconBlockedNetworks = []string{"loopback"}
+	useConfig(t, c)
+	assert.Empty(t, FindFavicon(server.URL, ""), "the blocked address is refused after resolving")
+
+	c.Environment.IconAllowedNetworks = []string{"127.0.0.1"}
+	assert.Equal(t, server.URL+"/favicon.ico", FindFavicon(server.URL, ""))
+}
+
+func TestIsLocalIcon(t *testing.T) {
+	t.Parallel()
+	cases := map[string]bool{
+		"/icons/plex.png":                      true,
+		NormalizedIconPath + "abc.png":         true,
+		IconProxyPath + "?url=https%3A%2F%2Fx": true,
+		"https://cdn.example/icons/plex.png":   false,
+		"http://nas.lan/favicon.ico":           false,
+		"//cdn.example/icons/plex.png":         false,
+		"":                                     false,
+	}
+	for iconURL, want := range cases {
+		assert.Equal(t, want, IsLocalIcon(iconURL), iconURL)
+	}
+}
+
+func TestFindIcon_LocalAndExternalIcons(t *testing.T) {
+	useConfig(t, newTestConfig())
+	useSelfHstIcons(t, "grafana")
+	dir := writeIconFiles(t, "plex.png")
+	require.NoError(t, scanUserIconsDir(dir))
+
+	iconURL, source := FindIcon("plex", "https://plex.lan", "", "plex", "")
+	assert.Equal(t, IconSourceUser, source)
+	assert.True(t, IsLocalIcon(iconURL), "user icons are served by TraLa")
+
+	iconURL, source = FindIcon("grafana", "https://grafana.lan", "", "grafana", "grafana")
+	assert.Equal(t, IconSourceSelfHst, source)
+	assert.True(t, strings.HasPrefix(iconURL, "https://icons.example/"), iconURL)
+	assert.False(t, IsLocalIcon(iconURL), "selfh.st icons are external")
+}
+
+func TestFindIcon_SelfHstDisabled(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.UseSelfhstIcons = false
+	useConfig(t, c)
+	var indexRequests atomic.Int32
+	useSelfHstServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/favicon.ico" {
+			w.Header().Set("Content-Type", "image/x-icon")
+			return
+		}
+		indexRequests.Add(1)
+		_, _ = w.Write([]byte(`[{"Reference":"grafana","SVG":"Yes"}]`))
+	})
+	dir := writeIconFiles(t, "plex.png")
+	require.NoError(t, scanUserIconsDir(dir))
+	serverURL := selfhstAPIURL
+
+	assert.Empty(t, ResolveSelfHstReference("grafana"))
+
+	iconURL, source := FindIcon("grafana", serverURL, "", "grafana", "grafana")
+	assert.Equal(t, IconSourceFavicon, source, "selfh.st is skipped, favicon discovery still runs")
+	assert.Equal(t, serverURL+"/favicon.ico", iconURL)
+
+	_, source = FindIcon("plex", serverURL, "", "plex", "")
+	assert.Equal(t, IconSourceUser, source, "user icons still apply")
+	assert.Zero(t, indexRequests.Load(), "the selfh.st index must not be fetched")
+}
+
+func TestUserIconURL_BasePath(t *testing.T) {
+	cases := []struct {
+		name     string
+		basePath string
+		file     string
+		want     string
+	}{
+		{"root", "", "plex.png", "/icons/plex.png"},
+		{"base path", "/trala", "plex.png", "/trala/icons/plex.png"},
+		{"nested file with space", "/apps/trala", "media/my plex.png", "/apps/trala/icons/media/my%20plex.png"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestConfig()
+			c.Environment.BasePath = tc.basePath
+			useConfig(t, c)
+
+			dir := t.TempDir()
+			require.NoError(t, os.MkdirAll(filepath.Join(dir, "media"), 0o700))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(tc.file)), nil, 0o600))
+			require.NoError(t, scanUserIconsDir(dir))
+
+			assert.Equal(t, tc.want, UserIconURL(filepath.Join(dir, filepath.FromSlash(tc.file))))
+			iconURL, source := FindIcon("plex", "https://plex.lan", "", "plex", "")
+			assert.Equal(t, IconSourceUser, source)
+			assert.Equal(t, tc.want, iconURL, "FindIcon returns the served URL, not the file path")
+		})
+	}
+
+	t.Run("file outside the directory", func(t *testing.T) {
+		useConfig(t, newTestConfig())
+		require.NoError(t, scanUserIconsDir(writeIconFiles(t, "plex.png")))
+		assert.Empty(t, UserIconURL(filepath.Join(t.TempDir(), "plex.png")))
+	})
+}
+
+func TestProxiedIconURL_BasePath(t *testing.T) {
+	c := newTestConfig()
+	c.Environment.BasePath = "/trala"
+	c.Environment.IconProxy = config.IconProxyConfig{Enabled: true, AllowedHosts: []string{"cdn.example"}}
+	useConfig(t, c)
+
+	assert.Equal(t, "/trala"+IconProxyPath+"?url=https%3A%2F%2Fcdn.example%2Fplex.png", ProxiedIconURL("https://cdn.example/plex.png"))
+}
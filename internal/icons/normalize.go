@@ -0,0 +1,204 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains the optional normalization of discovered favicons to a uniform PNG.
+package icons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	// Decoders registered with image.Decode for the formats favicons commonly use.
+	_ "image/gif"
+	_ "image/jpeg"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// NormalizedIconPath is the route prefix under which normalized favicons are served.
+const NormalizedIconPath = "/api/icons/normalized/"
+
+// normalizedIconSize is the width and height in pixels of a normalized favicon.
+const normalizedIconSize = 128
+
+// maxFaviconBytes caps the size of a favicon downloaded for normalization.
+const maxFaviconBytes = 2 << 20 // 2MB
+
+// maxFaviconDimension caps the width and height in pixels of a favicon that is decoded for
+// normalization. A small compressed image can declare a huge size, so the declared size is
+// checked before the pixels are decoded.
+const maxFaviconDimension = 4096
+
+// maxNormalizedIcons caps the number of normalized favicons kept in memory. When it is
+// reached, the oldest one is dropped; it is normalized again the next time it is discovered.
+const maxNormalizedIcons = 1024
+
+// Cache variables for normalized favicons, keyed by a hash of the source URL
+var (
+	normalizedIcons     = make(map[string][]byte)
+	normalizedIconOrder []string // keys in insertion order, oldest first
+	normalizedIconsMux  sync.RWMutex
+)
+
+// NormalizeFavicon re-encodes a discovered favicon to a square PNG of a fixed size and
+// returns the local URL it is served from. With only ICO conversion enabled, .ico favicons
+// are converted to PNG at their original size and other favicons are left alone. It returns
+// iconURL unchanged when both are disabled or the image cannot be fetched or decoded (e.g. SVG).
+func NormalizeFavicon(iconURL string) string {
+	if iconURL == "" {
+		return iconURL
+	}
+	resize := conf.GetNormalizeFavicons()
+	if !resize && !(conf.GetConvertICOFavicons() && isICOURL(iconURL)) {
+		return iconURL
+	}
+
+	key := normalizedIconKey(iconURL)
+	normalizedIconsMux.RLock()
+	_, ok := normalizedIcons[key]
+	normalizedIconsMux.RUnlock()
+	if ok {
+		return localIconURL(NormalizedIconPath + key + ".png")
+	}
+
+	data, err := fetchAndNormalize(iconURL, resize)
+	if err != nil {
+		debugf("Could not normalize favicon %s, using original: %v", iconURL, err)
+		return iconURL
+	}
+
+	storeNormalizedIcon(key, data)
+
+	debugf("Normalized favicon %s -> %s", iconURL, key)
+	return localIconURL(NormalizedIconPath + key + ".png")
+}
+
+// GetNormalizedIcon returns the PNG bytes of a normalized favicon by its key.
+func GetNormalizedIcon(key string) ([]byte, bool) {
+	normalizedIconsMux.RLock()
+	defer normalizedIconsMux.RUnlock()
+	data, ok := normalizedIcons[key]
+	return data, ok
+}
+
+// storeNormalizedIcon caches a normalized favicon, dropping the oldest ones beyond
+// maxNormalizedIcons.
+func storeNormalizedIcon(key string, data []byte) {
+	normalizedIconsMux.Lock()
+	defer normalizedIconsMux.Unlock()
+	if _, ok := normalizedIcons[key]; !ok {
+		normalizedIconOrder = append(normalizedIconOrder, key)
+	}
+	normalizedIcons[key] = data
+	for len(normalizedIconOrder) > maxNormalizedIcons {
+		delete(normalizedIcons, normalizedIconOrder[0])
+		normalizedIconOrder = normalizedIconOrder[1:]
+	}
+}
+
+// normalizedIconKey derives a stable cache key from the source URL.
+func normalizedIconKey(iconURL string) string {
+	sum := sha256.Sum256([]byte(iconURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// fetchAndNormalize downloads an image and returns it as a normalized PNG. Without resize
+// only ICO images are accepted, and they are converted to PNG at their original size.
+func fetchAndNormalize(iconURL string, resize bool) ([]byte, error) {
+	if externalHTTPClient == nil {
+		return nil, fmt.Errorf("external HTTP client not initialized")
+	}
+
+	resp, err := externalHTTPClient.Get(iconURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("favicon returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconBytes))
+	if err != nil {
+		return nil, fmt.Errorf("could not read favicon: %w", err)
+	}
+	src, format, err := decodeFavicon(data)
+	if err != nil {
+		return nil, err
+	}
+	if resize {
+		return encodeNormalizedPNG(src)
+	}
+	if format != "ico" {
+		return nil, fmt.Errorf("expected an ICO image, got %s", format)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeFavicon decodes an image, rejecting it before its pixels are decoded when it declares
+// a width or height beyond maxFaviconDimension.
+func decodeFavicon(data []byte) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not decode image: %w", err)
+	}
+	if cfg.Width > maxFaviconDimension || cfg.Height > maxFaviconDimension {
+		return nil, "", fmt.Errorf("image of %dx%d pixels exceeds %dx%d", cfg.Width, cfg.Height, maxFaviconDimension, maxFaviconDimension)
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("could not decode image: %w", err)
+	}
+	return src, format, nil
+}
+
+// isICOURL reports whether the path of iconURL names an .ico file.
+func isICOURL(iconURL string) bool {
+	u, err := url.Parse(iconURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(path.Ext(u.Path), ".ico")
+}
+
+// encodeNormalizedPNG scales src to fit a transparent square canvas, preserving its aspect
+// ratio, and encodes the result as PNG.
+func encodeNormalizedPNG(src image.Image) ([]byte, error) {
+	bounds := src.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, fmt.Errorf("image has no pixels")
+	}
+
+	width, height := normalizedIconSize, normalizedIconSize
+	if bounds.Dx() > bounds.Dy() {
+		height = bounds.Dy() * normalizedIconSize / bounds.Dx()
+	} else if bounds.Dy() > bounds.Dx() {
+		width = bounds.Dx() * normalizedIconSize / bounds.Dy()
+	}
+	offsetX := (normalizedIconSize - width) / 2
+	offsetY := (normalizedIconSize - height) / 2
+
+	dst := image.NewRGBA(image.Rect(0, 0, normalizedIconSize, normalizedIconSize))
+	draw.CatmullRom.Scale(dst, image.Rect(offsetX, offsetY, offsetX+width, offsetY+height), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
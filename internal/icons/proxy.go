@@ -0,0 +1,117 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains the server-side icon proxy for external icons blocked by the browser.
+package icons
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// IconProxyPath is the route under which proxied icons are served.
+const IconProxyPath = "/api/icon-proxy"
+
+// maxProxiedIconBytes caps the size of a proxied icon to keep the proxy cheap.
+const maxProxiedIconBytes = 2 << 20 // 2MB
+
+// maxProxyRedirects caps the number of redirects the icon proxy follows.
+const maxProxyRedirects = 5
+
+// IsProxyAllowedHost reports whether host is on the icon proxy allow-list.
+// An allow-list entry matches the host itself and any of its subdomains.
+func IsProxyAllowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	for _, allowed := range conf.GetIconProxyAllowedHosts() {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxiedIconURL rewrites an external icon URL to go through the icon proxy when the proxy
+// is enabled and the icon host is allow-listed. Other URLs are returned unchanged.
+func ProxiedIconURL(iconURL string) string {
+	if !conf.GetIconProxyEnabled() || iconURL == "" {
+		return iconURL
+	}
+	u, err := url.Parse(iconURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return iconURL
+	}
+	if !IsProxyAllowedHost(u.Hostname()) {
+		return iconURL
+	}
+	return localIconURL(IconProxyPath + "?url=" + url.QueryEscape(iconURL))
+}
+
+// FetchProxiedIcon fetches an allow-listed external icon for the icon proxy.
+// It returns the response body (limited in size) and its content type. The caller must
+// close the returned body. Non-image responses and hosts outside the allow-list are rejected,
+// including hosts reached through a redirect.
+func FetchProxiedIcon(ctx context.Context, rawURL string) (io.ReadCloser, string, error) {
+	if externalHTTPClient == nil {
+		return nil, "", fmt.Errorf("external HTTP client not initialized")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid icon URL")
+	}
+	if !IsProxyAllowedHost(u.Hostname()) {
+		return nil, "", fmt.Errorf("host %s is not allowed for icon proxying", u.Hostname())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "TraLa-Dashboard-App")
+
+	client := *externalHTTPClient
+	client.CheckRedirect = checkProxyRedirect
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("icon host returned status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "image/") {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("icon host returned non-image content type %q", contentType)
+	}
+
+	body := struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(resp.Body, maxProxiedIconBytes), resp.Body}
+	return body, contentType, nil
+}
+
+// checkProxyRedirect validates every redirect of the icon proxy against the allow-list, so an
+// allow-listed host cannot make the proxy fetch arbitrary URLs.
+func checkProxyRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxProxyRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxProxyRedirects)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+	}
+	if !IsProxyAllowedHost(req.URL.Hostname()) {
+		return fmt.Errorf("redirect to host %s is not allowed for icon proxying", req.URL.Hostname())
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Package icons provides icon discovery and caching functionality for the Trala dashboard.
+// This file contains the checks that limit which hosts and addresses TraLa fetches icons from.
+package icons
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+)
+
+// IsScrapeAllowedHost reports whether TraLa may fetch a favicon or HTML page from host.
+// Every host is allowed when no allow-list is configured. An entry matches the host itself
+// and any of its subdomains; entries containing wildcards are matched as glob patterns.
+func IsScrapeAllowedHost(host string) bool {
+	allowedHosts := conf.GetIconScrapeAllowedHosts()
+	if len(allowedHosts) == 0 {
+		return true
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	for _, allowed := range allowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if strings.ContainsAny(allowed, "*?[") {
+			if matched, err := path.Match(allowed, host); err == nil && matched {
+				return true
+			}
+			continue
+		}
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScrapeAllowedURL reports whether the host of rawURL may be scraped for icons.
+func isScrapeAllowedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if !IsScrapeAllowedHost(u.Hostname()) {
+		debugf("Skipping icon scraping for %s: host is not in icon_scrape_allowed_hosts", u.Hostname())
+		return false
+	}
+	return true
+}
+
+// networkClasses maps the address classes accepted in the network lists to their checks.
+var networkClasses = map[string]func(net.IP) bool{
+	"loopback": net.IP.IsLoopback,
+	"private":  net.IP.IsPrivate,
+	"link-local": func(ip net.IP) bool {
+		return ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()
+	},
+}
+
+// IsAllowedIconAddress reports whether external icon requests may connect to ip. An address
+// is refused when it is in one of the blocked networks and in none of the allowed networks.
+func IsAllowedIconAddress(ip net.IP) bool {
+	if conf == nil {
+		return true
+	}
+	return !networksContain(conf.GetIconBlockedNetworks(), ip) || networksContain(conf.GetIconAllowedNetworks(), ip)
+}
+
+// networksContain reports whether ip matches one of the entries, which are IP addresses, CIDR
+// ranges or network classes. Invalid entries are ignored; they are reported by validation.
+func networksContain(entries []string, ip net.IP) bool {
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if check, ok := networkClasses[entry]; ok {
+			if check(ip) {
+				return true
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if entryIP := net.ParseIP(entry); entryIP != nil && entryIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardIconDial is the dialer control function of the external HTTP client. It runs after the
+// host name has been resolved, so it also covers redirects and DNS names pointing at blocked
+// addresses.
+func guardIconDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("could not parse address %s", address)
+	}
+	if !IsAllowedIconAddress(ip) {
+		debugf("Refusing to connect to %s: address is in icon_blocked_networks", ip)
+		return fmt.Errorf("connecting to %s is blocked by icon_blocked_networks", ip)
+	}
+	return nil
+}
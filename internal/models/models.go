@@ -20,6 +20,8 @@ type TraefikRouter struct {
 	Priority    int              `json:"priority"`
 	EntryPoints []string         `json:"entryPoints"`   // Added to determine the entrypoint
 	TLS         *json.RawMessage `json:"tls,omitempty"` // Added to capture TLS configuration
+	Middlewares []string         `json:"middlewares,omitempty"`
+	Status      string           `json:"status"` // "enabled", "disabled" or "warning"; empty for Traefik versions without it
 }
 
 // TraefikEntryPoint represents the essential fields from the Traefik Entrypoints API.
@@ -32,18 +34,57 @@ type TraefikEntryPoint struct {
 	} `json:"http"`
 }
 
+// TraefikService represents the essential fields from the Traefik HTTP services API.
+// ServerStatus maps each load balancer server URL to its health check status ("UP" or "DOWN")
+// and is only present when Traefik health checks are configured for the service.
+type TraefikService struct {
+	Name         string            `json:"name"`
+	Status       string            `json:"status"`
+	ServerStatus map[string]string `json:"serverStatus,omitempty"`
+}
+
 // --- Service Types ---
 
 // Service represents the final, processed data sent to the frontend.
 // It contains all the information needed to display a service in the dashboard.
 type Service struct {
-	Name     string   `json:"Name"`
-	URL      string   `json:"url"`
-	Priority int      `json:"priority"`
-	Icon     string   `json:"icon"`
-	Tags     []string `json:"tags"`
-	Group    string   `json:"group"`
-	Host     string   `json:"host"`
+	ID         string   `json:"id"` // Identifier for /api/services/{id}, unchanged while host, name and URL are unchanged
+	Name       string   `json:"Name"`
+	URL        string   `json:"url"`
+	Priority   int      `json:"priority"`
+	Icon       string   `json:"icon"`
+	IconSource string   `json:"iconSource"`     // Discovery method that produced Icon (override, user, selfhst, favicon, html, fallback)
+	IconLocal  bool     `json:"iconLocal"`      // Icon is served by TraLa itself rather than by an external host
+	Tags       []string `json:"tags,omitempty"` // Omitted when grouping is disabled
+	Group      string   `json:"group"`
+	Host       string   `json:"host"`
+	External   bool     `json:"external"` // URL is outside the configured internal domains, or flagged by an override
+}
+
+// ServiceListResponse is the /api/services response when ?envelope=1 is set. It contains the
+// services together with the number found and whether max_services truncated them.
+type ServiceListResponse struct {
+	Services  []Service `json:"services"`
+	Total     int       `json:"total"`
+	Truncated bool      `json:"truncated"`
+}
+
+// ServicesTrace is the /api/services response when ?debug=1 is set. It contains the services
+// together with the debug messages recorded while discovering them.
+type ServicesTrace struct {
+	Services  []Service `json:"services"`
+	Total     int       `json:"total"`
+	Truncated bool      `json:"truncated"`
+	Debug     []string  `json:"_debug"`
+}
+
+// QuickLink represents a configured bookmark sent to the frontend. Quick links are shown apart
+// from the services and do not take part in grouping.
+type QuickLink struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	Icon       string `json:"icon"`
+	IconSource string `json:"iconSource"`
 }
 
 // IconAndTags represents the icon URL and associated tags for a service.
@@ -73,6 +114,10 @@ type FrontendConfig struct {
 	GroupingColumns        int    `json:"groupingColumns"`
 	MultiHost              bool   `json:"multiHost"`
 	MixServices            bool   `json:"mixServices"`
+	MaintenanceMessage     string `json:"maintenanceMessage,omitempty"`
+	SiteTitle              string `json:"siteTitle,omitempty"`
+	LogoURL                string `json:"logoURL,omitempty"`
+	ConfigRevision         string `json:"configRevision"`
 }
 
 // ApplicationStatus represents the combined status information for the application.
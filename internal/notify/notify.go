@@ -0,0 +1,165 @@
+// Package notify sends webhook notifications when the set of services on the dashboard changes.
+// Changes are debounced: a notification is only sent once the service set has been stable for
+// the configured debounce time, and contains the net difference since the last notification.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"server/internal/config"
+	"server/internal/debug"
+	"server/internal/models"
+)
+
+var conf *config.TralaConfiguration
+
+// Init stores the configuration instance for use by the notifier.
+func Init(c *config.TralaConfiguration) {
+	conf = c
+}
+
+var debugf = debug.Debugf
+
+// Diff is the JSON payload posted to the webhook.
+type Diff struct {
+	Added   []models.Service `json:"added"`
+	Removed []models.Service `json:"removed"`
+}
+
+// Empty reports whether the diff contains no changes.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+var (
+	mu       sync.Mutex
+	baseline map[string]models.Service // service set at the last notification; nil until first observation
+	current  map[string]models.Service // most recently observed service set
+	timer    *time.Timer
+	client   = &http.Client{Timeout: 10 * time.Second}
+)
+
+// ServiceID returns the stable identifier used to detect added and removed services.
+func ServiceID(svc models.Service) string {
+	return svc.Host + "|" + svc.Name + "|" + svc.URL
+}
+
+// Observe records the complete current service list. The first observation becomes the
+// baseline; later changes are posted to the webhook after the debounce time. Callers must
+// only pass complete lists, not lists missing services of an unreachable instance.
+func Observe(svcs []models.Service) {
+	if conf == nil || conf.GetNotifyWebhookURL() == "" {
+		return
+	}
+
+	set := make(map[string]models.Service, len(svcs))
+	for _, svc := range svcs {
+		set[ServiceID(svc)] = svc
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if baseline == nil {
+		baseline = set
+		current = set
+		return
+	}
+	if sameIDs(current, set) {
+		return
+	}
+	current = set
+
+	// Restart the debounce timer on every change so bursts produce a single notification.
+	debounce := time.Duration(conf.GetNotifyDebounceSeconds()) * time.Second
+	if timer != nil {
+		timer.Stop()
+	}
+	timer = time.AfterFunc(debounce, flush)
+}
+
+// flush posts the difference between the baseline and the current service set.
+func flush() {
+	mu.Lock()
+	diff := ComputeDiff(baseline, current)
+	baseline = current
+	timer = nil
+	mu.Unlock()
+
+	if diff.Empty() {
+		debugf("Service set changed back before notification, nothing to send")
+		return
+	}
+	if err := post(conf.GetNotifyWebhookURL(), diff); err != nil {
+		log.Printf("WARNING: Could not send service change notification: %v", err)
+		return
+	}
+	debugf("Sent service change notification: %d added, %d removed", len(diff.Added), len(diff.Removed))
+}
+
+// ComputeDiff returns the services in after but not in before (added) and the services
+// in before but not in after (removed), each sorted by name.
+func ComputeDiff(before, after map[string]models.Service) Diff {
+	diff := Diff{Added: []models.Service{}, Removed: []models.Service{}}
+	for id, svc := range after {
+		if _, ok := before[id]; !ok {
+			diff.Added = append(diff.Added, svc)
+		}
+	}
+	for id, svc := range before {
+		if _, ok := after[id]; !ok {
+			diff.Removed = append(diff.Removed, svc)
+		}
+	}
+	byName := func(list []models.Service) func(i, j int) bool {
+		return func(i, j int) bool { return list[i].Name < list[j].Name }
+	}
+	sort.Slice(diff.Added, byName(diff.Added))
+	sort.Slice(diff.Removed, byName(diff.Removed))
+	return diff
+}
+
+// sameIDs reports whether both sets contain the same service IDs.
+func sameIDs(a, b map[string]models.Service) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for id := range a {
+		if _, ok := b[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// post sends diff as JSON to url.
+func post(url string, diff Diff) error {
+	body, err := json.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// useWebhook points the notifier at url with the given debounce and resets its state.
+func useWebhook(t *testing.T, url string, debounceSeconds int) {
+	t.Helper()
+	previous := conf
+	conf = &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{
+		NotifyWebhookURL:      url,
+		NotifyDebounceSeconds: debounceSeconds,
+	}}
+	mu.Lock()
+	baseline, current, timer = nil, nil, nil
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		baseline, current, timer = nil, nil, nil
+		mu.Unlock()
+		conf = previous
+	})
+}
+
+func set(svcs ...models.Service) map[string]models.Service {
+	m := make(map[string]models.Service, len(svcs))
+	for _, svc := range svcs {
+		m[ServiceID(svc)] = svc
+	}
+	return m
+}
+
+func TestComputeDiff(t *testing.T) {
+	t.Parallel()
+	a := models.Service{Name: "a", URL: "https://a.example", Host: "h"}
+	b := models.Service{Name: "b", URL: "https://b.example", Host: "h"}
+	c := models.Service{Name: "c", URL: "https://c.example", Host: "h"}
+
+	diff := ComputeDiff(set(a, b), set(b, c))
+	assert.Equal(t, []models.Service{c}, diff.Added)
+	assert.Equal(t, []models.Service{a}, diff.Removed)
+
+	assert.True(t, ComputeDiff(set(a, b), set(b, a)).Empty())
+}
+
+func TestObserve_PostsDiffAfterChange(t *testing.T) {
+	received := make(chan Diff, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var diff Diff
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&diff))
+		received <- diff
+	}))
+	defer srv.Close()
+	useWebhook(t, srv.URL, 0)
+
+	a := models.Service{Name: "a", URL: "https://a.example"}
+	b := models.Service{Name: "b", URL: "https://b.example"}
+
+	Observe([]models.Service{a}) // baseline, no notification
+	Observe([]models.Service{a, b})
+
+	select {
+	case diff := <-received:
+		assert.Equal(t, []string{"b"}, names(diff.Added))
+		assert.Empty(t, diff.Removed)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+	}
+}
+
+func TestObserve_DebounceCancelsFlapping(t *testing.T) {
+	calls := make(chan struct{}, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls <- struct{}{}
+	}))
+	defer srv.Close()
+	useWebhook(t, srv.URL, 3600)
+
+	a := models.Service{Name: "a", URL: "https://a.example"}
+	b := models.Service{Name: "b", URL: "https://b.example"}
+
+	Observe([]models.Service{a})
+	Observe([]models.Service{a, b}) // b appears...
+	Observe([]models.Service{a})    // ...and disappears again before the debounce expires
+	flush()
+
+	select {
+	case <-calls:
+		t.Fatal("flapping service should not be notified")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func names(svcs []models.Service) []string {
+	var result []string
+	for _, svc := range svcs {
+		result = append(result, svc.Name)
+	}
+	return result
+}
@@ -4,15 +4,20 @@ import "context"
 
 // Service represents a discovered service from a Traefik provider.
 type Service struct {
-	Name     string
-	URL      string
-	Priority int
-	Icon     string
-	Tags     []string
-	Group    string
+	Name       string
+	URL        string
+	Priority   int
+	Icon       string
+	IconSource string
+	IconLocal  bool
+	Tags       []string
+	Group      string
+	External   bool
 }
 
 // Provider defines the interface for fetching services from a Traefik instance.
+// When ctx ends while the routers are being processed, FetchServices returns the services
+// processed so far together with the context error.
 type Provider interface {
 	FetchServices(ctx context.Context) ([]Service, error)
 }
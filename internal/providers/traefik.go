@@ -2,7 +2,11 @@ package providers
 
 import (
 	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"runtime/debug"
+	"sync"
 
 	"server/internal/config"
 	"server/internal/models"
@@ -10,6 +14,13 @@ import (
 	"server/internal/traefik"
 )
 
+var conf *config.TralaConfiguration
+
+// Init stores the configuration instance for use by providers.
+func Init(c *config.TralaConfiguration) {
+	conf = c
+}
+
 // TraefikProvider fetches services from a single Traefik instance.
 type TraefikProvider struct {
 	Instance   config.TraefikInstanceConfig
@@ -28,33 +39,172 @@ func NewTraefikProvider(instance config.TraefikInstanceConfig) *TraefikProvider
 func (p *TraefikProvider) FetchServices(ctx context.Context) ([]Service, error) {
 	entryPoints, err := traefik.FetchAllPagesWithInstanceAuth[models.TraefikEntryPoint](ctx, p.HTTPClient, p.Instance.APIHost+"/api/entrypoints", p.Instance)
 	if err != nil {
+		traefik.RedetectAPIVersion(p.Instance)
 		return nil, err
 	}
 
 	routers, err := traefik.FetchAllPagesWithInstanceAuth[models.TraefikRouter](ctx, p.HTTPClient, p.Instance.APIHost+"/api/http/routers", p.Instance)
 	if err != nil {
+		traefik.RedetectAPIVersion(p.Instance)
 		return nil, err
 	}
+	routers = append(routers, p.fetchTCPRouters(ctx)...)
 
 	entryPointsMap := make(map[string]models.TraefikEntryPoint, len(entryPoints))
 	for _, ep := range entryPoints {
 		entryPointsMap[ep.Name] = ep
 	}
+	if len(entryPointsMap) == 0 && len(routers) > 0 {
+		// Without entrypoints the default can only come from its port, not from its name.
+		if conf != nil && conf.GetDefaultEntryPoint().Port != 0 {
+			log.Printf("WARNING: Traefik instance %s returned %d routers but no entrypoints, using default_entrypoint for all of them", p.Instance.Name, len(routers))
+		} else {
+			log.Printf("WARNING: Traefik instance %s returned %d routers but no entrypoints; no service URLs can be built. Check the Traefik entrypoint configuration or set default_entrypoint.", p.Instance.Name, len(routers))
+		}
+	}
+
+	result := processRouters(ctx, routers, entryPointsMap, p.fetchServiceHealth(ctx), p.Instance.Name)
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("processed %d of %d routers: %w", len(result), len(routers), err)
+	}
+	warnIfNothingDiscovered(p.Instance.Name, len(routers), len(result))
+	return result, nil
+}
+
+// FetchInstanceServices fetches the services of a single Traefik instance, with the instance
+// as their host. It implements services.InstanceFetcher. When ctx ends while the routers are
+// processed, the services processed so far are returned with the error.
+func FetchInstanceServices(ctx context.Context, instance config.TraefikInstanceConfig) ([]models.Service, error) {
+	fetched, err := NewTraefikProvider(instance).FetchServices(ctx)
+	if err != nil && len(fetched) == 0 {
+		return nil, err
+	}
+	result := make([]models.Service, 0, len(fetched))
+	for _, svc := range fetched {
+		result = append(result, models.Service{
+			Name:       svc.Name,
+			URL:        svc.URL,
+			Priority:   svc.Priority,
+			Icon:       svc.Icon,
+			IconSource: svc.IconSource,
+			IconLocal:  svc.IconLocal,
+			Tags:       svc.Tags,
+			Group:      svc.Group,
+			Host:       instance.Name,
+			External:   svc.External,
+		})
+	}
+	return result, err
+}
+
+// firstFetchDone records the instances whose routers were fetched and processed completely.
+var firstFetchDone sync.Map
+
+// warnIfNothingDiscovered logs a warning after the first complete fetch from an instance when
+// it returned routers but none of them became a service, which otherwise shows up as an empty
+// dashboard without an obvious cause. Later fetches are not checked, so the warning is logged
+// at most once per instance.
+func warnIfNothingDiscovered(instanceName string, routers, discovered int) {
+	if _, done := firstFetchDone.LoadOrStore(instanceName, true); done {
+		return
+	}
+	if routers == 0 || discovered > 0 {
+		return
+	}
+	log.Printf("WARNING: Traefik instance %s returned %d routers but none of them is shown. Check the exclude patterns, or set log_level to debug to see which routers are excluded or have a rule no URL can be built from.", instanceName, routers)
+}
+
+// fetchTCPRouters fetches the TCP routers of the instance when include_tcp is enabled. They
+// are processed like HTTP routers, with their HostSNI rule naming the host. It returns nil
+// when the option is off or the TCP routers cannot be fetched.
+func (p *TraefikProvider) fetchTCPRouters(ctx context.Context) []models.TraefikRouter {
+	if conf == nil || !conf.GetIncludeTCP() {
+		return nil
+	}
+	routers, err := traefik.FetchAllPagesWithInstanceAuth[models.TraefikRouter](ctx, p.HTTPClient, p.Instance.APIHost+"/api/tcp/routers", p.Instance)
+	if err != nil {
+		log.Printf("WARNING: Could not fetch TCP routers from instance %s, showing HTTP routers only: %v", p.Instance.Name, err)
+		return nil
+	}
+	return routers
+}
+
+// fetchServiceHealth fetches backend health from the Traefik services API when unhealthy
+// services should be hidden. It returns nil, treating every service as healthy, when the
+// option is off or the health data cannot be fetched.
+func (p *TraefikProvider) fetchServiceHealth(ctx context.Context) services.ServiceHealth {
+	if conf == nil || !conf.GetHideUnhealthy() {
+		return nil
+	}
+	traefikServices, err := traefik.FetchAllPagesWithInstanceAuth[models.TraefikService](ctx, p.HTTPClient, p.Instance.APIHost+"/api/http/services", p.Instance)
+	if err != nil {
+		log.Printf("WARNING: Could not fetch service health from instance %s, showing all services: %v", p.Instance.Name, err)
+		return nil
+	}
+	return services.BuildServiceHealth(traefikServices)
+}
+
+// processRouters converts routers into services, skipping excluded routers and routers
+// whose processing panicked. At most icon_discovery_concurrency routers are processed at the
+// same time, so icon discovery does not open a connection for every router at once; without
+// a configuration, or with a limit below 1, they are processed one at a time. The services
+// keep the order of their routers. No router is started after ctx has ended.
+func processRouters(ctx context.Context, routers []models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint, health services.ServiceHealth, instanceName string) []Service {
+	limit := 1
+	if conf != nil {
+		limit = conf.GetIconDiscoveryConcurrency()
+	}
+	limit = max(min(limit, len(routers)), 1)
+
+	processed := make([]models.Service, len(routers))
+	found := make([]bool, len(routers))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, router := range routers {
+		slots <- struct{}{}
+		if ctx.Err() != nil {
+			<-slots
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			processed[i], found[i] = processRouter(ctx, router, entryPoints, health, instanceName)
+		}()
+	}
+	wg.Wait()
 
 	var result []Service
-	for _, router := range routers {
-		svc, ok := services.ProcessRouter(router, entryPointsMap, p.Instance.Name)
-		if ok {
+	for i, svc := range processed {
+		if found[i] {
 			result = append(result, Service{
-				Name:     svc.Name,
-				URL:      svc.URL,
-				Priority: svc.Priority,
-				Icon:     svc.Icon,
-				Tags:     svc.Tags,
-				Group:    svc.Group,
+				Name:       svc.Name,
+				URL:        svc.URL,
+				Priority:   svc.Priority,
+				Icon:       svc.Icon,
+				IconSource: svc.IconSource,
+				IconLocal:  svc.IconLocal,
+				Tags:       svc.Tags,
+				Group:      svc.Group,
+				External:   svc.External,
 			})
 		}
 	}
+	return result
+}
 
-	return result, nil
+// processRouterFunc processes a single router; it is a variable so tests can replace it.
+var processRouterFunc = services.ProcessRouter
+
+// processRouter calls services.ProcessRouter, recovering from a panic so a single bad
+// router is skipped instead of aborting the whole refresh.
+func processRouter(ctx context.Context, router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint, health services.ServiceHealth, instanceName string) (svc models.Service, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("ERROR: Panic while processing router %s, skipping it: %v\n%s", router.Name, r, debug.Stack())
+			svc, ok = models.Service{}, false
+		}
+	}()
+	return processRouterFunc(ctx, router, entryPoints, health, instanceName)
 }
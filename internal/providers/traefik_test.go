@@ -0,0 +1,136 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+	"server/internal/services"
+)
+
+func TestProcessRouters_SkipsRouterThatPanics(t *testing.T) {
+	previous := processRouterFunc
+	t.Cleanup(func() { processRouterFunc = previous })
+	processRouterFunc = func(_ context.Context, router models.TraefikRouter, _ map[string]models.TraefikEntryPoint, _ services.ServiceHealth, instanceName string) (models.Service, bool) {
+		if router.Name == "broken@docker" {
+			panic("malformed router")
+		}
+		return models.Service{Name: router.Name, Host: instanceName}, true
+	}
+
+	routers := []models.TraefikRouter{
+		{Name: "first@docker"},
+		{Name: "broken@docker"},
+		{Name: "last@docker"},
+	}
+
+	var result []Service
+	require.NotPanics(t, func() {
+		result = processRouters(context.Background(), routers, nil, nil, "traefik")
+	})
+	require.Len(t, result, 2)
+	assert.Equal(t, "first@docker", result[0].Name)
+	assert.Equal(t, "last@docker", result[1].Name)
+}
+
+func TestProcessRouters_StopsWhenContextEnds(t *testing.T) {
+	previous := processRouterFunc
+	t.Cleanup(func() { processRouterFunc = previous })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	processRouterFunc = func(_ context.Context, router models.TraefikRouter, _ map[string]models.TraefikEntryPoint, _ services.ServiceHealth, instanceName string) (models.Service, bool) {
+		if router.Name == "second@docker" {
+			cancel()
+		}
+		return models.Service{Name: router.Name}, true
+	}
+
+	routers := []models.TraefikRouter{
+		{Name: "first@docker"},
+		{Name: "second@docker"},
+		{Name: "third@docker"},
+	}
+
+	result := processRouters(ctx, routers, nil, nil, "traefik")
+	require.Len(t, result, 2, "the router being processed is finished, the rest is skipped")
+	assert.Equal(t, "first@docker", result[0].Name)
+	assert.Equal(t, "second@docker", result[1].Name)
+}
+
+func TestWarnIfNothingDiscovered(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	t.Cleanup(func() {
+		for _, name := range []string{"empty", "ok", "idle"} {
+			firstFetchDone.Delete(name)
+		}
+	})
+
+	warnIfNothingDiscovered("ok", 3, 2)
+	warnIfNothingDiscovered("idle", 0, 0)
+	assert.Empty(t, buf.String(), "no warning when services were found or Traefik has no routers")
+
+	warnIfNothingDiscovered("ok", 3, 0)
+	assert.Empty(t, buf.String(), "only the first fetch is checked")
+
+	warnIfNothingDiscovered("empty", 4, 0)
+	assert.Contains(t, buf.String(), "Traefik instance empty returned 4 routers but none of them is shown")
+
+	buf.Reset()
+	warnIfNothingDiscovered("empty", 4, 0)
+	assert.Empty(t, buf.String(), "the warning is logged once")
+}
+
+func TestProcessRouters_BoundsConcurrency(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.IconDiscoveryConcurrency = 3
+	Init(c)
+	t.Cleanup(func() { Init(nil) })
+
+	previous := processRouterFunc
+	t.Cleanup(func() { processRouterFunc = previous })
+	var active, peak atomic.Int32
+	processRouterFunc = func(_ context.Context, router models.TraefikRouter, _ map[string]models.TraefikEntryPoint, _ services.ServiceHealth, _ string) (models.Service, bool) {
+		n := active.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		active.Add(-1)
+		return models.Service{Name: router.Name}, router.Name != "skipped@docker"
+	}
+
+	var routers []models.TraefikRouter
+	for i := 0; i < 20; i++ {
+		routers = append(routers, models.TraefikRouter{Name: fmt.Sprintf("app%02d@docker", i)})
+	}
+	routers[7].Name = "skipped@docker"
+
+	result := processRouters(context.Background(), routers, nil, nil, "traefik")
+	assert.LessOrEqual(t, peak.Load(), int32(3), "no more routers than icon_discovery_concurrency are processed at once")
+	assert.Greater(t, peak.Load(), int32(1), "routers are processed concurrently")
+	require.Len(t, result, 19)
+	assert.Equal(t, "app00@docker", result[0].Name)
+	assert.Equal(t, "app08@docker", result[7].Name, "services keep the order of their routers")
+	assert.Equal(t, "app19@docker", result[18].Name)
+
+	peak.Store(0)
+	c.Environment.IconDiscoveryConcurrency = 0
+	require.Len(t, processRouters(context.Background(), routers, nil, nil, "traefik"), 19)
+	assert.Equal(t, int32(1), peak.Load(), "a limit below 1 processes routers one at a time")
+}
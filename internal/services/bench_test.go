@@ -0,0 +1,56 @@
+package services
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"testing"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// benchTags are the tags assigned to the synthetic services.
+var benchTags = []string{
+	"Media", "Monitoring", "Networking", "Storage", "Productivity", "Development", "Security",
+	"Communication", "Home Automation", "Backup", "Photos", "Documents", "Finance", "Gaming",
+}
+
+// syntheticServices returns n services with one to four tags each; every tenth service has a
+// group override. The fixed seed keeps the fixture identical between runs, so benchmark
+// results can be compared.
+func syntheticServices(n int) []models.Service {
+	rng := rand.New(rand.NewPCG(3, 3))
+	svcs := make([]models.Service, n)
+	for i := range svcs {
+		tags := make([]string, 1+rng.IntN(4))
+		for j := range tags {
+			tags[j] = benchTags[rng.IntN(len(benchTags))]
+		}
+		svcs[i] = models.Service{
+			Name: fmt.Sprintf("service %d", i),
+			URL:  fmt.Sprintf("https://service-%d.lan", i),
+			Tags: tags,
+		}
+		if i%10 == 0 {
+			svcs[i].Group = "Pinned"
+		}
+	}
+	return svcs
+}
+
+func BenchmarkCalculateGroups(b *testing.B) {
+	useConfig(b, &config.TralaConfiguration{
+		Environment: config.EnvironmentConfiguration{
+			Grouping: config.GroupingConfig{Enabled: true, TagFrequencyThreshold: 0.9, MinServicesPerGroup: 2},
+		},
+	})
+	fixture := syntheticServices(500)
+	svcs := make([]models.Service, len(fixture))
+
+	b.ReportAllocs()
+	for b.Loop() {
+		// CalculateGroups assigns groups in place, so every iteration starts from the fixture.
+		copy(svcs, fixture)
+		CalculateGroups(svcs)
+	}
+}
@@ -0,0 +1,53 @@
+// Package services provides service processing and grouping functionality for the Trala dashboard.
+// This file contains the backend health lookup used to hide unhealthy services.
+package services
+
+import (
+	"strings"
+
+	"server/internal/models"
+)
+
+// ServiceHealth maps a qualified Traefik service name (name@provider) to whether at least
+// one of its servers is up. Services without health data are absent from the map, and a
+// nil map means health data is unavailable; both are treated as healthy.
+type ServiceHealth map[string]bool
+
+// BuildServiceHealth builds a ServiceHealth from the Traefik HTTP services API response.
+// Only services reporting server status (i.e. with health checks configured) are included.
+func BuildServiceHealth(traefikServices []models.TraefikService) ServiceHealth {
+	health := make(ServiceHealth, len(traefikServices))
+	for _, svc := range traefikServices {
+		if len(svc.ServerStatus) == 0 {
+			continue
+		}
+		up := false
+		for _, status := range svc.ServerStatus {
+			if strings.EqualFold(status, "UP") {
+				up = true
+				break
+			}
+		}
+		health[svc.Name] = up
+	}
+	return health
+}
+
+// IsHealthy reports whether the service behind router has at least one healthy server.
+// Routers without health data are considered healthy.
+func (h ServiceHealth) IsHealthy(router models.TraefikRouter) bool {
+	up, ok := h[qualifiedServiceName(router)]
+	return !ok || up
+}
+
+// qualifiedServiceName returns the router's service name including the provider suffix.
+// Traefik omits the provider when the service is defined by the same provider as the router.
+func qualifiedServiceName(router models.TraefikRouter) string {
+	if strings.Contains(router.Service, "@") {
+		return router.Service
+	}
+	if _, provider, ok := strings.Cut(router.Name, "@"); ok {
+		return router.Service + "@" + provider
+	}
+	return router.Service
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"server/internal/models"
+)
+
+func TestServiceHealth_IsHealthy(t *testing.T) {
+	t.Parallel()
+	health := BuildServiceHealth([]models.TraefikService{
+		{Name: "up@docker", ServerStatus: map[string]string{"http://10.0.0.1:80": "DOWN", "http://10.0.0.2:80": "UP"}},
+		{Name: "down@docker", ServerStatus: map[string]string{"http://10.0.0.3:80": "DOWN"}},
+		{Name: "unchecked@file"},
+	})
+
+	cases := []struct {
+		name   string
+		router models.TraefikRouter
+		want   bool
+	}{
+		{"one server up", models.TraefikRouter{Name: "app@docker", Service: "up"}, true},
+		{"all servers down", models.TraefikRouter{Name: "app@docker", Service: "down"}, false},
+		{"qualified service name", models.TraefikRouter{Name: "app@file", Service: "down@docker"}, false},
+		{"no health check configured", models.TraefikRouter{Name: "app@file", Service: "unchecked"}, true},
+		{"unknown service", models.TraefikRouter{Name: "app@docker", Service: "missing"}, true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, health.IsHealthy(tc.router))
+		})
+	}
+
+	var unavailable ServiceHealth
+	assert.True(t, unavailable.IsHealthy(models.TraefikRouter{Name: "app@docker", Service: "down"}), "nil health treats all services as healthy")
+}
@@ -3,10 +3,17 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net/url"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"server/internal/config"
 	"server/internal/debug"
@@ -24,34 +31,56 @@ func Init(c *config.TralaConfiguration) {
 
 // ProcessRouter takes a raw Traefik router, finds its best icon, and returns the final Service object.
 // It handles router name extraction, URL reconstruction, exclusion checks, and icon/tag discovery.
+// When hide_unhealthy is enabled, routers whose service has no healthy server in health are skipped.
+// Routers whose status is not "enabled" are skipped unless include_disabled is set.
+// Its decisions are recorded in the debug trace carried by ctx, if any.
 // Returns the processed Service and a boolean indicating if the router should be included.
-func ProcessRouter(router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint, instanceName string) (models.Service, bool) {
-	routerName := strings.Split(router.Name, "@")[0]
+func ProcessRouter(ctx context.Context, router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint, health ServiceHealth, instanceName string) (models.Service, bool) {
+	if !IsProviderAllowed(router.Name) {
+		tracef(ctx, "Skipping router %s, its provider is not in traefik.providers", router.Name)
+		return models.Service{}, false
+	}
 
-	// Remove entrypoint name from the beginning of router name (case-insensitive)
-	if len(router.EntryPoints) > 0 {
-		entryPointName := router.EntryPoints[0]
-		prefix := entryPointName + "-"
-		if len(routerName) > len(prefix) && strings.HasPrefix(strings.ToLower(routerName), strings.ToLower(prefix)) {
-			routerName = routerName[len(prefix):]
-			debugf("Removed entrypoint prefix '%s' from router name, new name: '%s'", prefix, routerName)
-		}
+	if router.Status != "" && router.Status != "enabled" && !conf.GetIncludeDisabled() {
+		tracef(ctx, "Skipping router %s, its status is %s", router.Name, router.Status)
+		return models.Service{}, false
 	}
 
-	serviceURL := traefik.ReconstructURL(router, entryPoints)
+	routerName := strings.Split(router.Name, "@")[0]
+	if conf.GetStripEntrypointPrefix() {
+		routerName = stripEntrypointPrefix(routerName, router)
+	}
+
+	serviceURL := traefik.ReconstructURLWithHost(router, entryPoints, conf.GetHostOverride(routerName))
 
 	if serviceURL == "" {
-		debugf("Could not reconstruct URL for router %s from rule: %s", routerName, router.Rule)
+		tracef(ctx, "Could not reconstruct URL for router %s from rule: %s", routerName, router.Rule)
 		return models.Service{}, false
 	}
 
 	if IsExcluded(routerName) {
-		debugf("Excluding router: %s", routerName)
+		tracef(ctx, "Excluding router: %s", routerName)
 		return models.Service{}, false
 	}
 
 	if IsEntrypointExcluded(router.EntryPoints) {
-		debugf("Excluding router %s due to entrypoint exclusion", routerName)
+		tracef(ctx, "Excluding router %s due to entrypoint exclusion", routerName)
+		return models.Service{}, false
+	}
+
+	if IsMiddlewareExcluded(router.Middlewares) {
+		tracef(ctx, "Excluding router %s due to middleware exclusion", routerName)
+		return models.Service{}, false
+	}
+
+	if conf.GetHideUnhealthy() && !health.IsHealthy(router) {
+		tracef(ctx, "Excluding router %s because service %s has no healthy servers", routerName, router.Service)
+		return models.Service{}, false
+	}
+
+	if err := validateServiceURL(serviceURL); err != nil {
+		warnInvalidURL(routerName, serviceURL, err)
+		tracef(ctx, "Skipping router %s, reconstructed URL %q is invalid: %v", routerName, serviceURL, err)
 		return models.Service{}, false
 	}
 
@@ -64,7 +93,7 @@ func ProcessRouter(router models.TraefikRouter, entryPoints map[string]models.Tr
 			}
 			apiURL := traefikAPIHost + "/api"
 			if serviceURL == apiURL {
-				debugf("Excluding router %s because it's the Traefik API service for instance %s", routerName, inst.Name)
+				tracef(ctx, "Excluding router %s because it's the Traefik API service for instance %s", routerName, inst.Name)
 				return models.Service{}, false
 			}
 		}
@@ -76,25 +105,95 @@ func ProcessRouter(router models.TraefikRouter, entryPoints map[string]models.Tr
 		displayName = routerNameReplaced
 	}
 
-	debugf("Processing router: %s (display: %s), URL: %s", routerName, displayName, serviceURL)
-	displayNameReplaced := strings.ReplaceAll(displayName, " ", "-")
-	reference := icons.ResolveSelfHstReference(displayNameReplaced)
-	iconURL := icons.FindIcon(routerName, serviceURL, displayNameReplaced, reference)
-	tags := icons.FindTags(routerName, reference)
-
+	tracef(ctx, "Processing router: %s (display: %s), URL: %s", routerName, displayName, serviceURL)
+	matchName := iconMatchName(ctx, routerName, strings.ReplaceAll(displayName, " ", "-"), serviceURL)
 	group := conf.GetGroupOverride(routerName)
 
+	reference := icons.ResolveSelfHstReference(matchName, iconCategories(group, router.Middlewares)...)
+	iconURL, iconSource := icons.FindIcon(routerName, serviceURL, traefik.RuleHost(router, entryPoints), matchName, reference)
+	tracef(ctx, "Icon for router %s from %s: %s", routerName, iconSource, iconURL)
+	tags := findServiceTags(routerName, reference, router.Middlewares)
+
+	external := IsExternalURL(serviceURL)
+	if override := conf.GetExternalOverride(routerName); override != nil {
+		external = *override
+	}
+
 	return models.Service{
-		Name:     displayName,
-		URL:      serviceURL,
-		Priority: router.Priority,
-		Icon:     iconURL,
-		Tags:     tags,
-		Group:    group,
-		Host:     instanceName,
+		Name:       displayName,
+		URL:        serviceURL,
+		Priority:   servicePriority(routerName, router.Priority),
+		Icon:       iconURL,
+		IconSource: iconSource,
+		IconLocal:  icons.IsLocalIcon(iconURL),
+		Tags:       tags,
+		Group:      group,
+		Host:       instanceName,
+		External:   external,
 	}, true
 }
 
+// servicePriority returns the dashboard priority of a discovered service: the priority
+// override when one is configured, otherwise the router priority.
+func servicePriority(routerName string, routerPriority int) int {
+	if override := conf.GetPriorityOverride(routerName); override != nil {
+		return *override
+	}
+	return routerPriority
+}
+
+// invalidURLWarned records the routers whose invalid URL was logged.
+var invalidURLWarned sync.Map
+
+// warnInvalidURL logs that a router is skipped because of its invalid URL. The warning is
+// logged once per router name, as the router is processed again on every refresh.
+func warnInvalidURL(routerName, serviceURL string, err error) {
+	if _, warned := invalidURLWarned.LoadOrStore(routerName, true); warned {
+		return
+	}
+	log.Printf("WARNING: Skipping router %s, reconstructed URL %q is invalid: %v", routerName, serviceURL, err)
+}
+
+// validateServiceURL checks that a reconstructed service URL parses and has an http(s)
+// scheme, a host and, if present, a port in the valid range.
+func validateServiceURL(serviceURL string) error {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("missing host")
+	}
+	if p := u.Port(); p != "" {
+		if port, err := strconv.Atoi(p); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid port %q", p)
+		}
+	}
+	return nil
+}
+
+// stripEntrypointPrefix removes the name of the router's first entrypoint followed by "-"
+// from the beginning of routerName (case-insensitive). The prefix is kept when the router's
+// service carries the full router name, because the prefix is then part of the real name.
+func stripEntrypointPrefix(routerName string, router models.TraefikRouter) string {
+	if len(router.EntryPoints) == 0 {
+		return routerName
+	}
+	prefix := router.EntryPoints[0] + "-"
+	if len(routerName) <= len(prefix) || !strings.HasPrefix(strings.ToLower(routerName), strings.ToLower(prefix)) {
+		return routerName
+	}
+	if serviceName := strings.Split(router.Service, "@")[0]; strings.EqualFold(serviceName, routerName) {
+		debugf("Keeping entrypoint prefix '%s' in router name '%s', it matches the service name", prefix, routerName)
+		return routerName
+	}
+	debugf("Removed entrypoint prefix '%s' from router name, new name: '%s'", prefix, routerName[len(prefix):])
+	return routerName[len(prefix):]
+}
+
 // GetManualServices processes manually configured services and returns them as Service objects.
 // It validates URLs, resolves icons, and applies default values where needed.
 func GetManualServices() []models.Service {
@@ -113,26 +212,14 @@ func GetManualServices() []models.Service {
 			continue
 		}
 
-		displayNameReplaced := strings.ReplaceAll(manualService.Name, " ", "-")
-		reference := icons.ResolveSelfHstReference(displayNameReplaced)
+		reference := icons.ResolveSelfHstReference(strings.ReplaceAll(manualService.Name, " ", "-"), iconCategories(manualService.Group, nil)...)
+		iconURL, iconSource := resolveConfiguredIcon(manualService.Name, manualService.URL, manualService.Icon, reference)
 
-		iconURL := manualService.Icon
-		if iconURL == "" {
-			iconURL = icons.FindIcon(manualService.Name, manualService.URL, displayNameReplaced, reference)
-		} else if !strings.HasPrefix(iconURL, "http://") && !strings.HasPrefix(iconURL, "https://") {
-			ext := filepath.Ext(iconURL)
-			if ext == ".png" || ext == ".svg" || ext == ".webp" {
-				iconURL = conf.GetSelfhstIconURL() + strings.TrimPrefix(ext, ".") + "/" + strings.ToLower(iconURL)
-			} else {
-				iconURL = conf.GetSelfhstIconURL() + "png/" + strings.ToLower(iconURL) + ".png"
-			}
-		}
-
-		tags := icons.FindTags(manualService.Name, reference)
+		tags := findServiceTags(manualService.Name, reference, nil)
 
 		priority := manualService.Priority
 		if priority == 0 {
-			priority = 50
+			priority = conf.GetManualServiceDefaultPriority()
 		}
 
 		host := manualService.Host
@@ -140,31 +227,178 @@ func GetManualServices() []models.Service {
 			host = defaultHost
 		}
 
+		external := IsExternalURL(manualService.URL)
+		if manualService.External != nil {
+			external = *manualService.External
+		}
+
+		group := manualService.Group
+		if group == "" {
+			group = conf.GetManualGroup()
+		}
+
 		service := models.Service{
-			Name:     manualService.Name,
-			URL:      manualService.URL,
-			Priority: priority,
-			Icon:     iconURL,
-			Tags:     tags,
-			Group:    manualService.Group,
-			Host:     host,
+			Name:       manualService.Name,
+			URL:        manualService.URL,
+			Priority:   priority,
+			Icon:       iconURL,
+			IconSource: iconSource,
+			IconLocal:  icons.IsLocalIcon(iconURL),
+			Tags:       tags,
+			Group:      group,
+			Host:       host,
+			External:   external,
 		}
 
 		result = append(result, service)
 		debugf("Added manual service: %s (URL: %s, Icon: %s, Priority: %d, Group: %s, Host: %s)",
-			manualService.Name, manualService.URL, iconURL, priority, manualService.Group, host)
+			manualService.Name, manualService.URL, iconURL, priority, group, host)
+	}
+
+	return result
+}
+
+// GetQuickLinks processes the configured quick links and returns them with resolved icons.
+// Quick links use the same icon resolution as manual services but are not grouped.
+func GetQuickLinks() []models.QuickLink {
+	links := conf.GetQuickLinks()
+	result := make([]models.QuickLink, 0, len(links))
+
+	for _, link := range links {
+		if !config.IsValidUrl(link.URL) {
+			log.Printf("Warning: Invalid URL for quick link '%s': %s", link.Name, link.URL)
+			continue
+		}
+
+		reference := icons.ResolveSelfHstReference(strings.ReplaceAll(link.Name, " ", "-"))
+		iconURL, iconSource := resolveConfiguredIcon(link.Name, link.URL, link.Icon, reference)
+
+		result = append(result, models.QuickLink{
+			Name:       link.Name,
+			URL:        link.URL,
+			Icon:       iconURL,
+			IconSource: iconSource,
+		})
+		debugf("Added quick link: %s (URL: %s, Icon: %s)", link.Name, link.URL, iconURL)
 	}
 
 	return result
 }
 
+// resolveConfiguredIcon returns the icon URL and its source for a configured entry. An empty
+// icon is detected from the name and URL; a file name refers to the selfh.st icons and a full
+// URL is used as is.
+func resolveConfiguredIcon(name, serviceURL, icon, reference string) (string, string) {
+	if icon == "" {
+		return icons.FindIcon(name, serviceURL, "", strings.ReplaceAll(name, " ", "-"), reference)
+	}
+
+	// FindIcon already applies the proxy rewrite; explicit icons need it here.
+	return icons.ProxiedIconURL(selfHstIconURL(icon)), icons.IconSourceOverride
+}
+
+// ResolveIcon resolves a configured icon such as search_engine_icon like a service icon: a
+// full URL is used as is, a matching custom icon is served by TraLa and any other value names
+// a selfh.st icon. It returns empty string for an empty icon.
+func ResolveIcon(icon string) string {
+	if icon == "" {
+		return ""
+	}
+	if !strings.HasPrefix(icon, "http://") && !strings.HasPrefix(icon, "https://") {
+		name := strings.TrimSuffix(icon, filepath.Ext(icon))
+		if iconPath := icons.FindUserIcon(name); iconPath != "" {
+			if iconURL := icons.UserIconURL(iconPath); iconURL != "" {
+				return iconURL
+			}
+		}
+	}
+	return icons.ProxiedIconURL(selfHstIconURL(icon))
+}
+
+// selfHstIconURL returns icon unchanged when it is a full URL and the selfh.st URL of the icon
+// name otherwise. The extension selects the format; names without one use PNG.
+func selfHstIconURL(icon string) string {
+	if strings.HasPrefix(icon, "http://") || strings.HasPrefix(icon, "https://") {
+		return icon
+	}
+	ext := filepath.Ext(icon)
+	if ext == ".png" || ext == ".svg" || ext == ".webp" {
+		return conf.GetSelfhstIconURL() + strings.TrimPrefix(ext, ".") + "/" + strings.ToLower(icon)
+	}
+	return conf.GetSelfhstIconURL() + "png/" + strings.ToLower(icon) + ".png"
+}
+
+// SortServices sorts services by priority, highest first. Services with equal priority are
+// ordered by name (case-insensitive), then URL and host, so the order never changes between
+// refreshes of the same services.
+func SortServices(svcs []models.Service) {
+	sort.SliceStable(svcs, func(i, j int) bool {
+		a, b := svcs[i], svcs[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if nameA, nameB := strings.ToLower(a.Name), strings.ToLower(b.Name); nameA != nameB {
+			return nameA < nameB
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.URL != b.URL {
+			return a.URL < b.URL
+		}
+		return a.Host < b.Host
+	})
+}
+
+// IsExternalURL reports whether the host of serviceURL lies outside the internal_domains
+// suffixes. Suffixes only match whole labels, so "lan" matches "nas.lan" but not "nas.plan".
+// Without internal domains every service is internal.
+func IsExternalURL(serviceURL string) bool {
+	domains := conf.GetInternalDomains()
+	if len(domains) == 0 {
+		return false
+	}
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsProviderAllowed reports whether the provider of a router, the part of its full name after
+// the last "@", is in the traefik.providers allow-list. An empty list allows every provider.
+func IsProviderAllowed(fullRouterName string) bool {
+	providers := conf.GetTraefikProviders()
+	if len(providers) == 0 {
+		return true
+	}
+	at := strings.LastIndex(fullRouterName, "@")
+	if at < 0 {
+		return false
+	}
+	provider := strings.ToLower(fullRouterName[at+1:])
+	for _, allowed := range providers {
+		if provider == allowed {
+			return true
+		}
+	}
+	return false
+}
+
 // IsExcluded checks if a router name is in the exclude list.
 // Supports wildcard patterns (*, ?) and logs invalid patterns.
 func IsExcluded(routerName string) bool {
 	excludePatterns := conf.GetExcludeRouters()
+	caseInsensitive := conf.GetCaseInsensitiveNames()
 
 	for _, exclude := range excludePatterns {
-		match, err := filepath.Match(exclude, routerName)
+		match, err := matchName(exclude, routerName, caseInsensitive)
 		if err != nil {
 			// Log invalid pattern so it is visible in docker logs
 			log.Printf("WARNING: invalid exclude pattern %q: %v", exclude, err)
@@ -181,10 +415,11 @@ func IsExcluded(routerName string) bool {
 // Supports wildcard patterns (*, ?) and logs invalid patterns.
 func IsEntrypointExcluded(entryPoints []string) bool {
 	excludePatterns := conf.GetExcludeEntrypoints()
+	caseInsensitive := conf.GetCaseInsensitiveNames()
 
 	for _, ep := range entryPoints {
 		for _, exclude := range excludePatterns {
-			match, err := filepath.Match(exclude, ep)
+			match, err := matchName(exclude, ep, caseInsensitive)
 			if err != nil {
 				log.Printf("WARNING: invalid exclude.entrypoints pattern %q: %v", exclude, err)
 				continue
@@ -198,6 +433,111 @@ func IsEntrypointExcluded(entryPoints []string) bool {
 	return false
 }
 
+// IsMiddlewareExcluded checks if any of a router's middlewares matches the middleware exclude
+// list. Patterns are matched against the middleware name without its "@provider" suffix.
+func IsMiddlewareExcluded(middlewares []string) bool {
+	excludePatterns := conf.GetExcludeMiddlewares()
+	caseInsensitive := conf.GetCaseInsensitiveNames()
+
+	for _, mw := range middlewares {
+		name := strings.Split(mw, "@")[0]
+		for _, exclude := range excludePatterns {
+			match, err := matchName(exclude, name, caseInsensitive)
+			if err != nil {
+				log.Printf("WARNING: invalid exclude.middlewares pattern %q: %v", exclude, err)
+				continue
+			}
+			if match {
+				debugf("Excluding middleware: %s matched pattern %s", mw, exclude)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findTagsFunc looks up the selfh.st tags of a service; it is a variable so tests can replace it.
+var findTagsFunc = icons.FindTags
+
+// findServiceTags returns the tags of a service: its selfh.st tags plus, when middleware_tags
+// is enabled, the names of its middlewares. Tags only serve to build groups, so tag discovery
+// is skipped and nil is returned while grouping is disabled.
+func findServiceTags(name, reference string, middlewares []string) []string {
+	if !conf.GetGroupingEnabled() {
+		return nil
+	}
+	tags := findTagsFunc(name, reference)
+	if conf.GetMiddlewareTags() {
+		tags = appendMiddlewareTags(tags, middlewares)
+	}
+	return tags
+}
+
+// iconMatchName returns the name used to match icons and tags of a discovered service. That is
+// its display name, unless icon_match_path is enabled, the display name is generic (it has no
+// exact selfh.st match) and the service URL has a path: then the last path segment is used, so
+// "apps/grafana" on a shared host matches on "grafana".
+func iconMatchName(ctx context.Context, routerName, displayNameReplaced, serviceURL string) string {
+	if !conf.GetIconMatchPath() {
+		return displayNameReplaced
+	}
+	segment := lastPathSegment(serviceURL)
+	if segment == "" || strings.EqualFold(segment, displayNameReplaced) || icons.IsSelfHstReference(displayNameReplaced) {
+		return displayNameReplaced
+	}
+	tracef(ctx, "Matching icon of router %s on path segment %q instead of %q", routerName, segment, displayNameReplaced)
+	return segment
+}
+
+// lastPathSegment returns the last non-empty segment of the path of rawURL, or an empty
+// string when the URL has no path.
+func lastPathSegment(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	p := strings.Trim(u.Path, "/")
+	if p == "" {
+		return ""
+	}
+	return path.Base(p)
+}
+
+// iconCategories returns the selfh.st categories a service suggests before its icon is known:
+// its configured group and, when middleware tags are enabled, the tags of its middlewares.
+func iconCategories(group string, middlewares []string) []string {
+	var categories []string
+	if group != "" {
+		categories = append(categories, group)
+	}
+	if conf.GetMiddlewareTags() {
+		categories = appendMiddlewareTags(categories, middlewares)
+	}
+	return categories
+}
+
+// appendMiddlewareTags adds the names of middlewares, without their "@provider" suffix, to
+// tags, skipping names that are already present. The result never shares its backing array
+// with tags, which may be shared between services.
+func appendMiddlewareTags(tags []string, middlewares []string) []string {
+	tags = slices.Clip(tags)
+	for _, mw := range middlewares {
+		name := strings.Split(mw, "@")[0]
+		if name != "" && !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// matchName matches name against a wildcard pattern, ignoring case when caseInsensitive is set.
+func matchName(pattern, name string, caseInsensitive bool) (bool, error) {
+	if caseInsensitive {
+		return filepath.Match(strings.ToLower(pattern), strings.ToLower(name))
+	}
+	return filepath.Match(pattern, name)
+}
+
 // ExtractServiceNameFromURL extracts the service name from a search engine URL.
 // It parses the hostname and extracts the second-level domain name.
 func ExtractServiceNameFromURL(searchURL string) string {
@@ -227,3 +567,6 @@ func ExtractServiceNameFromURL(searchURL string) string {
 
 // debugf is a wrapper for the shared debug utility
 var debugf = debug.Debugf
+
+// tracef is a wrapper for the shared request trace utility
+var tracef = debug.Tracef
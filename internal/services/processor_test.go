@@ -0,0 +1,547 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/debug"
+	"server/internal/icons"
+	"server/internal/models"
+)
+
+// useConfig installs c as the package configuration for the duration of the test.
+func useConfig(t testing.TB, c *config.TralaConfiguration) {
+	t.Helper()
+	previous := conf
+	conf = c
+	t.Cleanup(func() { conf = previous })
+}
+
+func TestIsExcluded_CaseHandling(t *testing.T) {
+	c := &config.TralaConfiguration{
+		Services: config.ServiceConfiguration{
+			Exclude: config.ExcludeConfig{
+				Routers:     []string{"Grafana*"},
+				Entrypoints: []string{"Internal"},
+			},
+		},
+	}
+	useConfig(t, c)
+
+	assert.True(t, IsExcluded("Grafana-admin"))
+	assert.False(t, IsExcluded("grafana-admin"), "matching is case-sensitive by default")
+	assert.False(t, IsEntrypointExcluded([]string{"internal"}))
+
+	c.Environment.CaseInsensitiveNames = true
+	assert.True(t, IsExcluded("grafana-admin"))
+	assert.True(t, IsExcluded("GRAFANA"))
+	assert.True(t, IsEntrypointExcluded([]string{"internal"}))
+	assert.False(t, IsExcluded("prometheus"))
+}
+
+func TestIsProviderAllowed(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	useConfig(t, c)
+
+	for _, name := range []string{"app@docker", "dashboard@file", "web@kubernetes", "plain"} {
+		assert.True(t, IsProviderAllowed(name), "an empty list allows %s", name)
+	}
+
+	c.Environment.Traefik.Providers = []string{"docker", "kubernetes"}
+	assert.True(t, IsProviderAllowed("app@docker"))
+	assert.True(t, IsProviderAllowed("App@Docker"), "provider names are compared in lower case")
+	assert.True(t, IsProviderAllowed("web@kubernetes"))
+	assert.False(t, IsProviderAllowed("dashboard@file"))
+	assert.False(t, IsProviderAllowed("web@kubernetescrd"), "providers must match exactly")
+	assert.False(t, IsProviderAllowed("plain"), "routers without a provider are not allowed")
+}
+
+func TestIsExternalURL(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	useConfig(t, c)
+
+	assert.False(t, IsExternalURL("https://github.com"), "without internal domains every service is internal")
+
+	c.Environment.InternalDomains = []string{"lan", "home.example.com"}
+	cases := map[string]bool{
+		"https://nas.lan":                  false,
+		"https://NAS.LAN:8443/admin":       false,
+		"http://lan":                       false,
+		"https://grafana.home.example.com": false,
+		"https://nas.plan":                 true,
+		"https://example.com":              true,
+		"https://github.com":               true,
+	}
+	for serviceURL, want := range cases {
+		assert.Equal(t, want, IsExternalURL(serviceURL), serviceURL)
+	}
+}
+
+func TestIsMiddlewareExcluded(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	useConfig(t, c)
+	middlewares := []string{"auth@docker", "maintenance@file"}
+
+	assert.False(t, IsMiddlewareExcluded(middlewares), "nothing is excluded by default")
+
+	c.Services.Exclude.Middlewares = []string{"maint*"}
+	assert.True(t, IsMiddlewareExcluded(middlewares), "the provider suffix is ignored")
+	assert.False(t, IsMiddlewareExcluded([]string{"auth@docker"}))
+	assert.False(t, IsMiddlewareExcluded(nil))
+}
+
+func TestAppendMiddlewareTags(t *testing.T) {
+	t.Parallel()
+	got := appendMiddlewareTags([]string{"monitoring", "auth"}, []string{"auth@docker", "compress@file", "compress@docker"})
+	assert.Equal(t, []string{"monitoring", "auth", "compress"}, got)
+	assert.Nil(t, appendMiddlewareTags(nil, nil))
+
+	shared := make([]string, 1, 4)
+	shared[0] = "monitoring"
+	first := appendMiddlewareTags(shared, []string{"auth@docker"})
+	second := appendMiddlewareTags(shared, []string{"compress@docker"})
+	assert.Equal(t, []string{"monitoring", "auth"}, first, "appending does not write into a shared array")
+	assert.Equal(t, []string{"monitoring", "compress"}, second)
+}
+
+func TestStripEntrypointPrefix(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name   string
+		router models.TraefikRouter
+		want   string
+	}{
+		{"genuine prefix", models.TraefikRouter{Name: "websecure-app@docker", Service: "app", EntryPoints: []string{"websecure"}}, "app"},
+		{"prefix is case-insensitive", models.TraefikRouter{Name: "WebSecure-app@docker", Service: "app", EntryPoints: []string{"websecure"}}, "app"},
+		{"prefix belongs to the service name", models.TraefikRouter{Name: "websecure-app@docker", Service: "websecure-app", EntryPoints: []string{"websecure"}}, "websecure-app"},
+		{"name equal to prefix", models.TraefikRouter{Name: "websecure-@docker", Service: "x", EntryPoints: []string{"websecure"}}, "websecure-"},
+		{"other entrypoint", models.TraefikRouter{Name: "web-app@docker", Service: "app", EntryPoints: []string{"websecure"}}, "web-app"},
+		{"no entrypoints", models.TraefikRouter{Name: "websecure-app@docker", Service: "app"}, "websecure-app"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			routerName := strings.Split(tc.router.Name, "@")[0]
+			assert.Equal(t, tc.want, stripEntrypointPrefix(routerName, tc.router))
+		})
+	}
+}
+
+func TestValidateServiceURL(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://app.example.com", false},
+		{"http://app.example.com:8080/admin", false},
+		{"http://[fd00::10]:8080", false},
+		{"http://app.example.com:0.0.0.0:80", true}, // entrypoint address with an IP
+		{"http://app.example.com:70000", true},
+		{"http://:8080", true},
+		{"ftp://app.example.com", true},
+		{"http://app example.com", true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.url, func(t *testing.T) {
+			t.Parallel()
+			err := validateServiceURL(tc.url)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResolveConfiguredIcon_ExplicitIcons(t *testing.T) {
+	c := &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{
+		SelfhstIconURL: "https://icons.example/",
+	}}
+	useConfig(t, c)
+	icons.Init(c)
+
+	cases := []struct {
+		icon string
+		want string
+	}{
+		{"GitHub.svg", "https://icons.example/svg/github.svg"},
+		{"github", "https://icons.example/png/github.png"},
+		{"https://cdn.example/logo.ico", "https://cdn.example/logo.ico"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.icon, func(t *testing.T) {
+			iconURL, source := resolveConfiguredIcon("GitHub", "https://github.com", tc.icon, "")
+			assert.Equal(t, tc.want, iconURL)
+			assert.Equal(t, icons.IconSourceOverride, source)
+		})
+	}
+}
+
+func TestResolveIcon(t *testing.T) {
+	c := &config.TralaConfiguration{Environment: config.EnvironmentConfiguration{
+		SelfhstIconURL: "https://icons.example/",
+	}}
+	useConfig(t, c)
+	icons.Init(c)
+
+	cases := map[string]string{
+		"":                             "",
+		"SearXNG.svg":                  "https://icons.example/svg/searxng.svg",
+		"searxng":                      "https://icons.example/png/searxng.png",
+		"https://cdn.example/logo.ico": "https://cdn.example/logo.ico",
+	}
+	for icon, want := range cases {
+		assert.Equal(t, want, ResolveIcon(icon), icon)
+	}
+}
+
+func TestGetManualServices_ManualGroup(t *testing.T) {
+	c := &config.TralaConfiguration{
+		Environment: config.EnvironmentConfiguration{
+			SelfhstIconURL: "https://icons.example/",
+			Grouping:       config.GroupingConfig{Enabled: true, TagFrequencyThreshold: 0.9, MinServicesPerGroup: 1, ManualGroup: "Bookmarks"},
+		},
+		Services: config.ServiceConfiguration{
+			Manual: []config.ManualService{
+				{Name: "GitHub", URL: "https://github.com", Icon: "github.svg"},
+				{Name: "Wiki", URL: "https://wiki.lan", Icon: "wiki.svg", Group: "Docs"},
+			},
+		},
+	}
+	useConfig(t, c)
+	icons.Init(c)
+
+	svcs := CalculateGroups(GetManualServices())
+	require.Len(t, svcs, 2)
+	assert.Equal(t, "Bookmarks", svcs[0].Group, "manual services without a group get manual_group")
+	assert.Equal(t, "Docs", svcs[1].Group, "an explicit group is kept")
+
+	c.Environment.Grouping.ManualGroup = ""
+	assert.Empty(t, GetManualServices()[0].Group, "without manual_group the tags decide")
+}
+
+func TestGetManualServices_DefaultPriority(t *testing.T) {
+	c := &config.TralaConfiguration{
+		Environment: config.EnvironmentConfiguration{SelfhstIconURL: "https://icons.example/"},
+		Services: config.ServiceConfiguration{
+			Manual: []config.ManualService{
+				{Name: "GitHub", URL: "https://github.com", Icon: "github.svg"},
+				{Name: "Wiki", URL: "https://wiki.lan", Icon: "wiki.svg", Priority: 80},
+			},
+			ManualPriority: 5,
+		},
+	}
+	useConfig(t, c)
+	icons.Init(c)
+
+	svcs := GetManualServices()
+	require.Len(t, svcs, 2)
+	assert.Equal(t, 5, svcs[0].Priority, "manual services without a priority get the default")
+	assert.Equal(t, 80, svcs[1].Priority, "an explicit priority is kept")
+}
+
+func TestSortServices_EqualPriorityIsDeterministic(t *testing.T) {
+	t.Parallel()
+	want := []models.Service{
+		{Name: "Zulu", Priority: 200, URL: "https://zulu.lan"},
+		{Name: "alpha", Priority: 100, URL: "https://alpha.lan"},
+		{Name: "Bravo", Priority: 100, URL: "https://bravo.lan"},
+		{Name: "bravo", Priority: 100, URL: "https://bravo.lan"},
+		{Name: "charlie", Priority: 100, URL: "https://a.charlie.lan"},
+		{Name: "charlie", Priority: 100, URL: "https://b.charlie.lan", Host: "a"},
+		{Name: "charlie", Priority: 100, URL: "https://b.charlie.lan", Host: "b"},
+		{Name: "delta", Priority: 10, URL: "https://delta.lan"},
+	}
+
+	// Every rotation of the input must produce the same order
+	for shift := range want {
+		input := append(append([]models.Service{}, want[shift:]...), want[:shift]...)
+		SortServices(input)
+		assert.Equal(t, want, input, "rotation %d", shift)
+	}
+}
+
+func TestServicePriority_OverrideBeatsRouterPriority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "grafana"
+      priority: 500
+    - service: "nas"
+      priority: 0
+    - service: "wiki"
+      group: "Docs"
+`), 0o600))
+	c, err := config.LoadConfiguration(path)
+	require.NoError(t, err)
+	useConfig(t, c)
+
+	assert.Equal(t, 500, servicePriority("grafana", 10), "a higher override wins")
+	assert.Equal(t, 0, servicePriority("nas", 50), "a zero override wins as well")
+	assert.Equal(t, 30, servicePriority("wiki", 30), "overrides without priority keep the router priority")
+	assert.Equal(t, 20, servicePriority("unknown", 20))
+}
+
+func TestFindServiceTags_SkippedWithoutGrouping(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.MiddlewareTags = true
+	useConfig(t, c)
+
+	assert.Nil(t, findServiceTags("grafana", "", []string{"auth@docker"}), "no tags are discovered while grouping is disabled")
+
+	c.Environment.Grouping.Enabled = true
+	assert.Equal(t, []string{"auth"}, findServiceTags("grafana", "", []string{"auth@docker"}), "re-enabling grouping restores tags")
+}
+
+func TestProcessRouter_TracesDecisions(t *testing.T) {
+	c := &config.TralaConfiguration{
+		Environment: config.EnvironmentConfiguration{LogLevel: "info"},
+		Services: config.ServiceConfiguration{
+			Exclude: config.ExcludeConfig{Routers: []string{"grafana"}},
+		},
+	}
+	useConfig(t, c)
+	ctx, trace := debug.WithTrace(context.Background())
+	entryPoints := map[string]models.TraefikEntryPoint{"websecure": {Name: "websecure", Address: ":443"}}
+
+	_, ok := ProcessRouter(ctx, models.TraefikRouter{Name: "grafana@docker", Rule: "Host(`grafana.lan`)", EntryPoints: []string{"websecure"}}, entryPoints, nil, "traefik")
+	assert.False(t, ok)
+	_, ok = ProcessRouter(ctx, models.TraefikRouter{Name: "broken@docker", Rule: "PathPrefix(`/`)", EntryPoints: []string{"websecure"}}, entryPoints, nil, "traefik")
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{
+		"Excluding router: grafana",
+		"Could not reconstruct URL for router broken from rule: PathPrefix(`/`)",
+	}, trace.Messages(), "decisions are traced while the log level is info")
+
+	_, ok = ProcessRouter(context.Background(), models.TraefikRouter{Name: "grafana@docker", Rule: "Host(`grafana.lan`)", EntryPoints: []string{"websecure"}}, entryPoints, nil, "traefik")
+	assert.False(t, ok)
+	assert.Len(t, trace.Messages(), 2, "requests without a trace are not recorded")
+}
+
+func TestProcessRouter_InvalidURLWarning(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	c := &config.TralaConfiguration{
+		Services: config.ServiceConfiguration{
+			Exclude: config.ExcludeConfig{Routers: []string{"excluded-broken"}},
+		},
+	}
+	useConfig(t, c)
+	entryPoints := map[string]models.TraefikEntryPoint{"web": {Name: "web", Address: ":80"}}
+	router := func(name string) models.TraefikRouter {
+		return models.TraefikRouter{Name: name + "@docker", Rule: "Host(`app example.com`)", EntryPoints: []string{"web"}}
+	}
+
+	for range 2 {
+		_, ok := ProcessRouter(context.Background(), router("excluded-broken"), entryPoints, nil, "traefik")
+		assert.False(t, ok)
+	}
+	assert.Empty(t, logs.String(), "an excluded router is not reported for its invalid URL")
+
+	for range 2 {
+		_, ok := ProcessRouter(context.Background(), router("warned-broken"), entryPoints, nil, "traefik")
+		assert.False(t, ok)
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "Skipping router warned-broken"), "the warning is logged once per router")
+}
+
+func TestProcessRouter_HostOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "wildcard"
+      icon: "https://icons.example/wildcard.svg"
+      host: "app.example.com"
+`), 0o600))
+	c, err := config.LoadConfiguration(path)
+	require.NoError(t, err)
+	useConfig(t, c)
+	entryPoints := map[string]models.TraefikEntryPoint{"web": {Name: "web", Address: ":80"}}
+	rule := "HostRegexp(`^.+\\.example\\.com$`)"
+
+	svc, ok := ProcessRouter(context.Background(), models.TraefikRouter{Name: "wildcard@docker", Rule: rule, EntryPoints: []string{"web"}}, entryPoints, nil, "traefik")
+	require.True(t, ok)
+	assert.Equal(t, "http://app.example.com", svc.URL)
+
+	_, ok = ProcessRouter(context.Background(), models.TraefikRouter{Name: "other@docker", Rule: rule, EntryPoints: []string{"web"}}, entryPoints, nil, "traefik")
+	assert.False(t, ok, "a dynamic HostRegexp without an override host is skipped")
+}
+
+func TestProcessRouter_SkipsDisabledRouters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "3.0"
+environment:
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "app"
+      icon: "https://icons.example/app.svg"
+`), 0o600))
+	c, err := config.LoadConfiguration(path)
+	require.NoError(t, err)
+	useConfig(t, c)
+	entryPoints := map[string]models.TraefikEntryPoint{"web": {Name: "web", Address: ":80"}}
+
+	cases := []struct {
+		status          string
+		includeDisabled bool
+		want            bool
+	}{
+		{"enabled", false, true},
+		{"", false, true},
+		{"disabled", false, false},
+		{"warning", false, false},
+		{"disabled", true, true},
+	}
+	for _, tc := range cases {
+		c.Services.IncludeDisabled = tc.includeDisabled
+		router := models.TraefikRouter{Name: "app@docker", Rule: "Host(`app.lan`)", EntryPoints: []string{"web"}, Status: tc.status}
+		_, ok := ProcessRouter(context.Background(), router, entryPoints, nil, "traefik")
+		assert.Equal(t, tc.want, ok, "status %q, include_disabled %t", tc.status, tc.includeDisabled)
+	}
+}
+
+func TestIconMatchName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "3.0"
+environment:
+  use_selfhst_icons: false
+  icon_match_path: true
+  traefik:
+    api_host: "http://t.local"
+`), 0o600))
+	c, err := config.LoadConfiguration(path)
+	require.NoError(t, err)
+	useConfig(t, c)
+
+	cases := []struct {
+		name        string
+		displayName string
+		url         string
+		want        string
+	}{
+		{"last path segment", "apps", "https://something.example.com/apps/grafana", "grafana"},
+		{"trailing slash", "monitoring", "https://something.example.com/grafana/", "grafana"},
+		{"no path keeps the name", "grafana", "https://grafana.example.com", "grafana"},
+		{"root path keeps the name", "grafana", "https://grafana.example.com/", "grafana"},
+		{"segment equal to the name", "Grafana", "https://example.com/grafana", "Grafana"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, iconMatchName(context.Background(), tc.displayName, tc.displayName, tc.url))
+		})
+	}
+
+	c.Environment.IconMatchPath = false
+	assert.Equal(t, "apps", iconMatchName(context.Background(), "apps", "apps", "https://something.example.com/apps/grafana"), "the path is ignored unless enabled")
+}
+
+// stubFindTags makes every service share the selfh.st tags, backed by an array with spare
+// capacity as a decoded JSON slice is, for the duration of the test.
+func stubFindTags(t *testing.T) {
+	t.Helper()
+	shared := make([]string, 1, 8)
+	shared[0] = "monitoring"
+	previous := findTagsFunc
+	findTagsFunc = func(string, string) []string { return shared }
+	t.Cleanup(func() { findTagsFunc = previous })
+}
+
+// loadMiddlewareTagsConfig installs a configuration with grouping and middleware tags enabled
+// and icon overrides for the routers app-a and app-b, so no icon discovery happens.
+func loadMiddlewareTagsConfig(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "configuration.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: "3.0"
+environment:
+  use_selfhst_icons: false
+  middleware_tags: true
+  grouping:
+    enabled: true
+  traefik:
+    api_host: "http://t.local"
+services:
+  overrides:
+    - service: "app-a"
+      icon: "https://icons.example/app.svg"
+    - service: "app-b"
+      icon: "https://icons.example/app.svg"
+`), 0o600))
+	c, err := config.LoadConfiguration(path)
+	require.NoError(t, err)
+	useConfig(t, c)
+}
+
+func TestProcessRouter_MiddlewareTagsAreNotShared(t *testing.T) {
+	loadMiddlewareTagsConfig(t)
+	stubFindTags(t)
+	entryPoints := map[string]models.TraefikEntryPoint{"web": {Name: "web", Address: ":80"}}
+
+	a, ok := ProcessRouter(context.Background(), models.TraefikRouter{Name: "app-a@docker", Rule: "Host(`a.lan`)", EntryPoints: []string{"web"}, Middlewares: []string{"auth@docker"}}, entryPoints, nil, "traefik")
+	require.True(t, ok)
+	b, ok := ProcessRouter(context.Background(), models.TraefikRouter{Name: "app-b@docker", Rule: "Host(`b.lan`)", EntryPoints: []string{"web"}, Middlewares: []string{"compress@docker"}}, entryPoints, nil, "traefik")
+	require.True(t, ok)
+
+	assert.Equal(t, []string{"monitoring", "auth"}, a.Tags, "tags of a service are not changed by the next one")
+	assert.Equal(t, []string{"monitoring", "compress"}, b.Tags)
+}
+
+func TestProcessRouter_ConcurrentSharedReference(t *testing.T) {
+	loadMiddlewareTagsConfig(t)
+	stubFindTags(t)
+	entryPoints := map[string]models.TraefikEntryPoint{"web": {Name: "web", Address: ":80"}}
+	routers := []models.TraefikRouter{
+		{Name: "app-a@docker", Rule: "Host(`a.lan`)", EntryPoints: []string{"web"}, Middlewares: []string{"auth@docker"}},
+		{Name: "app-b@docker", Rule: "Host(`b.lan`)", EntryPoints: []string{"web"}, Middlewares: []string{"compress@docker"}},
+	}
+	want := [][]string{{"monitoring", "auth"}, {"monitoring", "compress"}}
+
+	// Routers on the same selfh.st reference are processed concurrently, as processRouters
+	// does; run with -race to check they share no mutable state.
+	tags := make([][]string, 32)
+	var wg sync.WaitGroup
+	for i := range tags {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svc, ok := ProcessRouter(context.Background(), routers[i%2], entryPoints, nil, "traefik")
+			if ok {
+				tags[i] = svc.Tags
+			}
+		}()
+	}
+	wg.Wait()
+	for i, got := range tags {
+		assert.Equal(t, want[i%2], got)
+	}
+}
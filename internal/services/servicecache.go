@@ -0,0 +1,132 @@
+// Package services provides service processing and grouping functionality for the Trala dashboard.
+// This file contains the cached service list and its background refresh.
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// serviceListCache holds the service list of the last discovery run for a configuration
+// revision. Once it is older than twice refresh_interval_seconds, the background refresh is
+// late, so the next request starts a refresh but is still served the old list.
+var serviceListCache struct {
+	mu       sync.RWMutex
+	list     ServiceList
+	revision string
+	builtAt  time.Time
+}
+
+// serviceListBuildMu serializes discovery runs, so requests arriving while the cache is empty
+// wait for the running build instead of starting their own.
+var serviceListBuildMu sync.Mutex
+
+// buildServiceListFunc runs the discovery pipeline; it is a variable so tests can replace it.
+var buildServiceListFunc = BuildServiceList
+
+// BuildAll runs the discovery pipeline, stores the result as the cached service list and
+// returns its services. Requests that find no cached list build it the same way.
+func BuildAll(ctx context.Context, c *config.TralaConfiguration) []models.Service {
+	serviceListBuildMu.Lock()
+	defer serviceListBuildMu.Unlock()
+	return buildAndStore(ctx, c).Services
+}
+
+// buildAndStore runs the discovery pipeline and caches its result. The caller holds
+// serviceListBuildMu.
+func buildAndStore(ctx context.Context, c *config.TralaConfiguration) ServiceList {
+	revision := c.GetRevision()
+	list := buildServiceListFunc(ctx, c, true)
+	storeServiceList(list, revision)
+	return list
+}
+
+// RefreshServiceList builds the service list without falling back to snapshots and caches it
+// when every instance could be fetched. It holds serviceListBuildMu, so an older build that
+// finishes later cannot replace its result.
+func RefreshServiceList(ctx context.Context, c *config.TralaConfiguration) ServiceList {
+	serviceListBuildMu.Lock()
+	defer serviceListBuildMu.Unlock()
+	revision := c.GetRevision()
+	list := buildServiceListFunc(ctx, c, false)
+	if len(list.Failed) == 0 {
+		storeServiceList(list, revision)
+	}
+	return list
+}
+
+// storeServiceList replaces the cached service list.
+func storeServiceList(list ServiceList, revision string) {
+	serviceListCache.mu.Lock()
+	defer serviceListCache.mu.Unlock()
+	serviceListCache.list = list
+	serviceListCache.revision = revision
+	serviceListCache.builtAt = time.Now()
+}
+
+// loadServiceList returns the cached service list if it was built for revision, and its age.
+func loadServiceList(revision string) (ServiceList, time.Duration, bool) {
+	serviceListCache.mu.RLock()
+	defer serviceListCache.mu.RUnlock()
+	if serviceListCache.builtAt.IsZero() || serviceListCache.revision != revision {
+		return ServiceList{}, 0, false
+	}
+	return serviceListCache.list, time.Since(serviceListCache.builtAt), true
+}
+
+// CachedServiceList returns the cached service list. Requests never wait for a refresh of a
+// list that is already cached: when it is older than twice refresh_interval_seconds, a refresh
+// is started in the background and the old list is served meanwhile. Only when the cache is
+// empty, such as right after startup or a configuration change, they wait for or trigger a
+// build. A refresh interval of zero disables the cache.
+func CachedServiceList(ctx context.Context, c *config.TralaConfiguration) ServiceList {
+	maxAge := 2 * time.Duration(c.GetRefreshIntervalSeconds()) * time.Second
+	if maxAge <= 0 {
+		return buildServiceListFunc(ctx, c, true)
+	}
+
+	revision := c.GetRevision()
+	if list, age, ok := loadServiceList(revision); ok {
+		if age >= maxAge {
+			refreshInBackground(c)
+		}
+		return list
+	}
+
+	serviceListBuildMu.Lock()
+	defer serviceListBuildMu.Unlock()
+	// A build that was running while this request waited may have filled the cache.
+	if list, _, ok := loadServiceList(revision); ok {
+		return list
+	}
+	// The list is shared, so it is not cut short when this request is cancelled.
+	return buildAndStore(context.WithoutCancel(ctx), c)
+}
+
+// refreshInBackground rebuilds the cached service list in a new goroutine unless a build is
+// already running, so requests finding an outdated list neither wait nor pile up builds.
+func refreshInBackground(c *config.TralaConfiguration) {
+	if !serviceListBuildMu.TryLock() {
+		return
+	}
+	go func() {
+		defer serviceListBuildMu.Unlock()
+		list := buildAndStore(context.Background(), c)
+		debugf("Refreshed the outdated service list: %d services", len(list.Services))
+	}()
+}
+
+// RefreshServicesPeriodically builds the service list right away and then every
+// refresh_interval_seconds, so requests are served from a fresh cached list. It never returns.
+func RefreshServicesPeriodically(c *config.TralaConfiguration) {
+	for {
+		start := time.Now()
+		svcs := BuildAll(context.Background(), c)
+		debugf("Refreshed the service list in %s: %d services", time.Since(start).Round(time.Millisecond), len(svcs))
+		time.Sleep(time.Duration(max(c.GetRefreshIntervalSeconds(), 1)) * time.Second)
+	}
+}
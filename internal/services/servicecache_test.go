@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// stubServiceListBuilds replaces the discovery pipeline with build, counting its calls, and
+// resets the service list cache for the duration of the test.
+func stubServiceListBuilds(t *testing.T, build func(c *config.TralaConfiguration) ServiceList) *atomic.Int32 {
+	t.Helper()
+	var builds atomic.Int32
+	previous := buildServiceListFunc
+	buildServiceListFunc = func(_ context.Context, c *config.TralaConfiguration, _ bool) ServiceList {
+		builds.Add(1)
+		return build(c)
+	}
+	resetServiceListCache()
+	t.Cleanup(func() {
+		buildServiceListFunc = previous
+		resetServiceListCache()
+	})
+	return &builds
+}
+
+func resetServiceListCache() {
+	serviceListCache.mu.Lock()
+	defer serviceListCache.mu.Unlock()
+	serviceListCache.builtAt = time.Time{}
+}
+
+// titledServiceList returns a service list with a single service named after the site title.
+func titledServiceList(c *config.TralaConfiguration) ServiceList {
+	return ServiceList{Services: []models.Service{{Name: c.GetSiteTitle()}}, Total: 1}
+}
+
+func TestCachedServiceList_ReusedUntilConfigChanges(t *testing.T) {
+	builds := stubServiceListBuilds(t, titledServiceList)
+	c := &config.TralaConfiguration{}
+	c.Environment.RefreshIntervalSeconds = 30
+	c.Environment.SiteTitle = "Home"
+
+	assert.Equal(t, "Home", CachedServiceList(context.Background(), c).Services[0].Name, "a miss builds the list")
+	assert.Equal(t, "Home", CachedServiceList(context.Background(), c).Services[0].Name)
+	assert.Equal(t, int32(1), builds.Load(), "a hit reuses the list")
+
+	c.Environment.SiteTitle = "Lab"
+	assert.Equal(t, "Lab", CachedServiceList(context.Background(), c).Services[0].Name)
+	assert.Equal(t, int32(2), builds.Load(), "a configuration change invalidates the list")
+
+	BuildAll(context.Background(), c)
+	CachedServiceList(context.Background(), c)
+	assert.Equal(t, int32(3), builds.Load(), "requests use the list of the last refresh")
+
+	c.Environment.RefreshIntervalSeconds = 0
+	CachedServiceList(context.Background(), c)
+	CachedServiceList(context.Background(), c)
+	assert.Equal(t, int32(5), builds.Load(), "without a refresh interval every request builds the list")
+}
+
+func TestCachedServiceList_ConcurrentMissesShareOneBuild(t *testing.T) {
+	release := make(chan struct{})
+	builds := stubServiceListBuilds(t, func(c *config.TralaConfiguration) ServiceList {
+		<-release
+		return titledServiceList(c)
+	})
+	c := &config.TralaConfiguration{}
+	c.Environment.RefreshIntervalSeconds = 30
+	c.Environment.SiteTitle = "Home"
+
+	var wg sync.WaitGroup
+	names := make([]string, 8)
+	for i := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			names[i] = CachedServiceList(context.Background(), c).Services[0].Name
+		}()
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), builds.Load(), "requests wait for the running build")
+	for _, name := range names {
+		assert.Equal(t, "Home", name)
+	}
+}
+
+func TestCachedServiceList_HitDoesNotWaitForRefresh(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	stubServiceListBuilds(t, func(c *config.TralaConfiguration) ServiceList {
+		close(started)
+		<-release
+		return titledServiceList(c)
+	})
+	c := &config.TralaConfiguration{}
+	c.Environment.RefreshIntervalSeconds = 30
+	c.Environment.SiteTitle = "Home"
+	storeServiceList(ServiceList{Services: []models.Service{{Name: "cached"}}}, c.GetRevision())
+
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		BuildAll(context.Background(), c)
+	}()
+	<-started
+
+	list := CachedServiceList(context.Background(), c)
+	require.Len(t, list.Services, 1)
+	assert.Equal(t, "cached", list.Services[0].Name, "the previous list is served while the refresh runs")
+
+	close(release)
+	<-refreshed
+	assert.Equal(t, "Home", CachedServiceList(context.Background(), c).Services[0].Name, "the refreshed list replaces it")
+}
+
+func TestCachedServiceList_OutdatedListRefreshesInBackground(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	builds := stubServiceListBuilds(t, func(c *config.TralaConfiguration) ServiceList {
+		close(started)
+		<-release
+		return titledServiceList(c)
+	})
+	c := &config.TralaConfiguration{}
+	c.Environment.RefreshIntervalSeconds = 30
+	c.Environment.SiteTitle = "Home"
+	storeServiceList(ServiceList{Services: []models.Service{{Name: "outdated"}}}, c.GetRevision())
+	serviceListCache.mu.Lock()
+	serviceListCache.builtAt = time.Now().Add(-time.Minute)
+	serviceListCache.mu.Unlock()
+
+	assert.Equal(t, "outdated", CachedServiceList(context.Background(), c).Services[0].Name, "the outdated list is served without waiting")
+	<-started
+	assert.Equal(t, "outdated", CachedServiceList(context.Background(), c).Services[0].Name)
+	assert.Equal(t, int32(1), builds.Load(), "requests do not start a second refresh while one runs")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return CachedServiceList(context.Background(), c).Services[0].Name == "Home"
+	}, time.Second, 10*time.Millisecond, "the refreshed list replaces it")
+	// Wait for the refresh to release the build lock before the cache is reset.
+	serviceListBuildMu.Lock()
+	serviceListBuildMu.Unlock()
+}
+
+func TestRefreshServiceList_WaitsForRunningBuild(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	stubServiceListBuilds(t, func(c *config.TralaConfiguration) ServiceList {
+		if calls.Add(1) == 1 {
+			// The first, slower build started before the refresh.
+			close(started)
+			<-release
+			return ServiceList{Services: []models.Service{{Name: "older"}}}
+		}
+		return titledServiceList(c)
+	})
+	c := &config.TralaConfiguration{}
+	c.Environment.RefreshIntervalSeconds = 30
+	c.Environment.SiteTitle = "Home"
+
+	built := make(chan struct{})
+	go func() {
+		defer close(built)
+		BuildAll(context.Background(), c)
+	}()
+	<-started
+
+	refreshed := make(chan ServiceList)
+	go func() { refreshed <- RefreshServiceList(context.Background(), c) }()
+	close(release)
+	<-built
+	assert.Equal(t, "Home", (<-refreshed).Services[0].Name)
+	assert.Equal(t, "Home", CachedServiceList(context.Background(), c).Services[0].Name, "the older build does not replace the refreshed list")
+}
@@ -0,0 +1,145 @@
+// Package services provides service processing and grouping functionality for the Trala dashboard.
+// This file contains the discovery pipeline that builds the service list from all instances.
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"server/internal/config"
+	"server/internal/icons"
+	"server/internal/models"
+	"server/internal/notify"
+)
+
+// ServiceList is the result of the discovery pipeline.
+type ServiceList struct {
+	Services []models.Service
+	Total    int      // number of services before truncation by max_services
+	Stale    bool     // at least one instance was unreachable and served from its last snapshot
+	Partial  bool     // the services_timeout_seconds budget ran out before all services were processed
+	Failed   []string // instances whose services could not be fetched nor served from a snapshot
+}
+
+// InstanceFetcher fetches the services of a single Traefik instance. When ctx ends while the
+// routers are processed, it returns the services processed so far together with the error.
+type InstanceFetcher func(ctx context.Context, instance config.TraefikInstanceConfig) ([]models.Service, error)
+
+// fetchInstanceServices fetches the services of an instance; it is set with SetInstanceFetcher.
+var fetchInstanceServices InstanceFetcher
+
+// SetInstanceFetcher sets the function the discovery pipeline fetches the services of each
+// Traefik instance with. The providers package implements it, which imports this package.
+func SetInstanceFetcher(fetch InstanceFetcher) {
+	fetchInstanceServices = fetch
+}
+
+// fetchInstance fetches the services of an instance with the fetcher set by SetInstanceFetcher.
+func fetchInstance(ctx context.Context, instance config.TraefikInstanceConfig) ([]models.Service, error) {
+	if fetchInstanceServices == nil {
+		return nil, errors.New("no instance fetcher set")
+	}
+	return fetchInstanceServices(ctx, instance)
+}
+
+// BuildServiceList runs the discovery pipeline: it fetches services from every Traefik
+// instance, adds manual services, calculates groups and sorts the result by priority.
+// When max_services is set, only the highest priority services are kept.
+// An unreachable instance is served from its last successful fetch if that is recent enough.
+// When the services_timeout_seconds budget runs out, the remaining routers are skipped and
+// the services processed so far are returned. Without useSnapshots, unreachable instances are
+// not served from their snapshot.
+func BuildServiceList(ctx context.Context, c *config.TralaConfiguration, useSnapshots bool) ServiceList {
+	if seconds := c.GetServicesTimeoutSeconds(); seconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+		defer cancel()
+	}
+
+	instances := c.GetTraefikInstances()
+	staleMaxAge := time.Duration(c.GetStaleMaxAgeSeconds()) * time.Second
+	var allServices []models.Service
+	var failed []string
+	stale := false
+	complete := true
+
+	for _, instance := range instances {
+		instanceServices, err := fetchInstance(ctx, instance)
+		if err != nil && len(instanceServices) > 0 {
+			log.Printf("WARNING: Ran out of time processing instance %s, showing %d of its services: %v", instance.Name, len(instanceServices), err)
+			tracef(ctx, "Ran out of time processing instance %s, showing %d of its services: %v", instance.Name, len(instanceServices), err)
+			complete = false
+		} else if err != nil {
+			complete = false
+			if staleMaxAge <= 0 || !useSnapshots {
+				log.Printf("WARNING: Failed to fetch services from instance %s: %v", instance.Name, err)
+				tracef(ctx, "Failed to fetch services from instance %s: %v", instance.Name, err)
+				failed = append(failed, instance.Name)
+				continue
+			}
+			snapshot, age, ok := loadInstanceSnapshot(instance.Name, staleMaxAge)
+			if !ok {
+				log.Printf("WARNING: Failed to fetch services from instance %s: %v", instance.Name, err)
+				tracef(ctx, "Failed to fetch services from instance %s: %v", instance.Name, err)
+				failed = append(failed, instance.Name)
+				continue
+			}
+			log.Printf("WARNING: Failed to fetch services from instance %s, serving last known services from %s ago: %v", instance.Name, age.Round(time.Second), err)
+			tracef(ctx, "Failed to fetch services from instance %s, serving last known services from %s ago: %v", instance.Name, age.Round(time.Second), err)
+			instanceServices = snapshot
+			stale = true
+		} else {
+			tracef(ctx, "Fetched %d services from instance %s", len(instanceServices), instance.Name)
+			if staleMaxAge > 0 {
+				storeInstanceSnapshot(instance.Name, instanceServices)
+			}
+		}
+		allServices = append(allServices, instanceServices...)
+	}
+
+	partial := ctx.Err() != nil
+	if partial {
+		complete = false
+	}
+
+	manualServices := GetManualServices()
+	finalServices := make([]models.Service, 0, len(allServices)+len(manualServices))
+	finalServices = append(finalServices, allServices...)
+	finalServices = append(finalServices, manualServices...)
+
+	finalServices = CalculateGroups(finalServices)
+
+	SortServices(finalServices)
+	for i := range finalServices {
+		finalServices[i].ID = ServiceID(finalServices[i])
+	}
+
+	// Only complete lists are compared, so an unreachable instance is not reported as removed services.
+	if complete {
+		notify.Observe(finalServices)
+	}
+
+	if err := icons.SaveCache(); err != nil {
+		log.Printf("WARNING: Could not save the icon cache: %v", err)
+	}
+
+	total := len(finalServices)
+	if limit := c.GetMaxServices(); limit > 0 && total > limit {
+		log.Printf("WARNING: Found %d services, only showing the %d with the highest priority (max_services)", total, limit)
+		tracef(ctx, "Found %d services, only showing the %d with the highest priority (max_services)", total, limit)
+		finalServices = finalServices[:limit]
+	}
+
+	return ServiceList{Services: finalServices, Total: total, Stale: stale, Partial: partial, Failed: failed}
+}
+
+// ServiceID returns the ID of svc: the first 16 hex digits of the SHA-256 of its notification
+// ID, so it survives restarts and stays the same while host, name and URL are unchanged.
+func ServiceID(svc models.Service) string {
+	sum := sha256.Sum256([]byte(notify.ServiceID(svc)))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,46 @@
+// Package services provides service processing and grouping functionality for the Trala dashboard.
+// This file contains the last known good service lists used while a Traefik instance is unreachable.
+package services
+
+import (
+	"sync"
+	"time"
+
+	"server/internal/models"
+)
+
+// instanceSnapshot is the service list of the last successful fetch from a Traefik instance.
+type instanceSnapshot struct {
+	services  []models.Service
+	fetchedAt time.Time
+}
+
+var (
+	instanceSnapshots   = make(map[string]instanceSnapshot)
+	instanceSnapshotsMu sync.RWMutex
+)
+
+// storeInstanceSnapshot records svcs as the last known good service list of an instance.
+func storeInstanceSnapshot(instanceName string, svcs []models.Service) {
+	instanceSnapshotsMu.Lock()
+	defer instanceSnapshotsMu.Unlock()
+	instanceSnapshots[instanceName] = instanceSnapshot{services: svcs, fetchedAt: time.Now()}
+}
+
+// loadInstanceSnapshot returns a copy of the last known good service list of an instance
+// and its age, provided it is not older than maxAge.
+func loadInstanceSnapshot(instanceName string, maxAge time.Duration) ([]models.Service, time.Duration, bool) {
+	instanceSnapshotsMu.RLock()
+	defer instanceSnapshotsMu.RUnlock()
+	snapshot, ok := instanceSnapshots[instanceName]
+	if !ok {
+		return nil, 0, false
+	}
+	age := time.Since(snapshot.fetchedAt)
+	if age > maxAge {
+		return nil, 0, false
+	}
+	result := make([]models.Service, len(snapshot.services))
+	copy(result, snapshot.services)
+	return result, age, true
+}
@@ -6,13 +6,18 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
+	"regexp/syntax"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"server/internal/config"
@@ -32,10 +37,13 @@ func Init(c *config.TralaConfiguration) {
 	conf = c
 }
 
-// Regex patterns to reliably find Host and PathPrefix in Traefik rules
+// Regex patterns to reliably find Host, HostRegexp, HostSNI, PathPrefix and Path in Traefik rules
 var (
-	hostRegex = regexp.MustCompile(`Host\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
-	pathRegex = regexp.MustCompile(`PathPrefix\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
+	hostRegex       = regexp.MustCompile(`Host\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
+	hostSNIRegex    = regexp.MustCompile(`HostSNI\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
+	hostRegexpRegex = regexp.MustCompile(`HostRegexp\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
+	pathRegex       = regexp.MustCompile(`PathPrefix\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
+	exactPathRegex  = regexp.MustCompile(`\bPath\(\s*` + "`" + `([^` + "`" + `]+)` + "`" + `\s*\)`)
 )
 
 // --- HTTP Client Initialization ---
@@ -113,12 +121,79 @@ func CreateHTTPRequestWithInstanceAuthAndContext(ctx context.Context, method, ur
 
 	if instance.EnableBasicAuth {
 		debugf("Setting basic auth for instance %s", instance.Name)
-		req.SetBasicAuth(instance.BasicAuth.Username, instance.BasicAuth.Password)
+		req.SetBasicAuth(instance.BasicAuth.Username, basicAuthPassword(instance))
 	}
 
 	return req, nil
 }
 
+// passwordFile is the last read of a basic auth password file.
+type passwordFile struct {
+	modTime  time.Time
+	size     int64
+	password string
+	failed   bool // the last read failed; the failure was logged
+}
+
+// passwordFiles holds the last read of every password file, by path.
+var (
+	passwordFiles   = make(map[string]passwordFile)
+	passwordFilesMu sync.Mutex
+)
+
+// basicAuthPassword returns the basic auth password of an instance. A password file is read
+// again whenever its modification time or size changes, so a rotated password is used without
+// a restart. If it cannot be read, the password loaded with the configuration is used; the
+// failure is logged once, until the file can be read again.
+func basicAuthPassword(instance config.TraefikInstanceConfig) string {
+	path := instance.BasicAuth.PasswordFile
+	if path == "" {
+		return instance.BasicAuth.Password
+	}
+
+	passwordFilesMu.Lock()
+	defer passwordFilesMu.Unlock()
+	cached, seen := passwordFiles[path]
+	info, err := os.Stat(path)
+	if err == nil && seen && !cached.failed && info.ModTime().Equal(cached.modTime) && info.Size() == cached.size {
+		return cached.password
+	}
+
+	var password string
+	if err == nil {
+		password, err = config.ReadPasswordFile(path)
+	}
+	if err != nil {
+		if !cached.failed {
+			log.Printf("WARNING: Could not read password file for instance %s, using the password loaded at startup: %v", instance.Name, err)
+		}
+		passwordFiles[path] = passwordFile{failed: true}
+		return instance.BasicAuth.Password
+	}
+	if cached.failed {
+		log.Printf("Password file for instance %s can be read again", instance.Name)
+	}
+	passwordFiles[path] = passwordFile{modTime: info.ModTime(), size: info.Size(), password: password}
+	return password
+}
+
+// StatusError is returned when the Traefik API answers with a status other than 200 OK.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return "non-200 status: " + e.Status
+}
+
+// IsAuthError reports whether err is a Traefik API response rejecting the configured
+// credentials (401 Unauthorized or 403 Forbidden).
+func IsAuthError(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden)
+}
+
 // CreateAndExecuteHTTPRequestWithInstance creates an authenticated HTTP request for a specific
 // instance and executes it using the provided client. The caller should pass a shared
 // *http.Client (e.g. from CreateHTTPClientForInstance) rather than creating a new one per call.
@@ -138,7 +213,7 @@ func CreateAndExecuteHTTPRequestWithInstance(ctx context.Context, client *http.C
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("ERROR: API returned non-200 status: %s", resp.Status)
 		resp.Body.Close()
-		return nil, fmt.Errorf("non-200 status: %s", resp.Status)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	return resp, nil
@@ -159,7 +234,7 @@ func FetchAllPagesWithInstanceAuth[T any](ctx context.Context, client *http.Clie
 			return nil, err
 		}
 
-		resp, err := client.Do(req)
+		resp, err := doWithRateLimitRetry(ctx, client, req)
 		if err != nil {
 			log.Printf("ERROR: Could not fetch from %s: %v", currentURL, err)
 			return nil, err
@@ -168,7 +243,7 @@ func FetchAllPagesWithInstanceAuth[T any](ctx context.Context, client *http.Clie
 		if resp.StatusCode != http.StatusOK {
 			log.Printf("ERROR: API returned non-200 status: %s", resp.Status)
 			resp.Body.Close()
-			return nil, fmt.Errorf("non-200 status: %s", resp.Status)
+			return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 		}
 
 		var items []T
@@ -201,6 +276,48 @@ func FetchAllPagesWithInstanceAuth[T any](ctx context.Context, client *http.Clie
 	return allItems, nil
 }
 
+// doWithRateLimitRetry executes req and retries it while Traefik answers 429 Too Many Requests,
+// up to rate_limit_retries times. Each retry waits for the Retry-After header of the response,
+// or doubles from one second when it is missing, capped at rate_limit_max_wait_seconds. The
+// last 429 response is returned once the retries are used up.
+func doWithRateLimitRetry(ctx context.Context, client *http.Client, req *http.Request) (*http.Response, error) {
+	retries, maxWait := 0, time.Duration(0)
+	if conf != nil {
+		retries = conf.GetRateLimitRetries()
+		maxWait = time.Duration(conf.GetRateLimitWaitSeconds()) * time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= retries {
+			return resp, err
+		}
+		resp.Body.Close()
+
+		wait := min(retryAfter(resp.Header.Get("Retry-After"), attempt), maxWait)
+		log.Printf("WARNING: Traefik API at %s is rate limiting requests, retrying in %s (%d/%d)", req.URL, wait, attempt+1, retries)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter returns the wait requested by a Retry-After header, given either in seconds or
+// as an HTTP date. Without a usable header it backs off exponentially from one second.
+func retryAfter(header string, attempt int) time.Duration {
+	if seconds, err := strconv.Atoi(strings.TrimSpace(header)); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return time.Second << min(attempt, 6)
+}
+
 // --- URL Reconstruction ---
 
 // DetermineProtocol determines the correct protocol (http/https) for a service
@@ -226,15 +343,46 @@ func DetermineProtocol(router models.TraefikRouter, entryPoint models.TraefikEnt
 // ReconstructURL extracts the base URL from a Traefik rule and determines the protocol and port
 // based on the router's entrypoint.
 func ReconstructURL(router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint) string {
-	hostMatches := hostRegex.FindStringSubmatch(router.Rule)
-	if len(hostMatches) < 2 {
+	return ReconstructURLWithHost(router, entryPoints, "")
+}
+
+// ReconstructURLWithHost works like ReconstructURL, but uses fallbackHost when the rule does
+// not name a single host, such as a HostRegexp rule matching any subdomain. The fallback host
+// is used as given, without the default domain and host rewrites.
+func ReconstructURLWithHost(router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint, fallbackHost string) string {
+	if len(router.EntryPoints) == 0 {
+		debugf("[%s] Router has no entrypoints defined. Cannot determine URL.", router.Name)
+		return ""
+	}
+	entryPoint, found, ok := routerEntryPoint(router, entryPoints)
+	if !ok {
+		debugf("[%s] Entrypoint '%s' not found in Traefik configuration.", router.Name, strings.Join(router.EntryPoints, "', '"))
+		return ""
+	}
+	if !found {
+		debugf("[%s] Entrypoint '%s' not found in Traefik configuration, using default entrypoint %s (%s)", router.Name, strings.Join(router.EntryPoints, "', '"), entryPoint.Name, entryPoint.Address)
+	}
+
+	hostname := ruleHost(router, entryPoint)
+	switch {
+	case hostname != "":
+		hostname = bracketIPv6(hostname)
+		if conf != nil {
+			hostname = appendDefaultDomain(hostname, conf.GetDefaultDomain())
+			hostname = rewriteHost(hostname, conf.GetHostRewrites())
+		}
+	case fallbackHost != "":
+		debugf("[%s] Rule does not name a single host, using override host %s", router.Name, fallbackHost)
+		hostname = bracketIPv6(fallbackHost)
+	default:
 		return ""
 	}
-	hostname := hostMatches[1]
 
+	// Path and PathPrefix are shown the same way; PathPrefix wins when a rule has both.
 	path := ""
-	pathMatches := pathRegex.FindStringSubmatch(router.Rule)
-	if len(pathMatches) >= 2 {
+	if pathMatches := pathRegex.FindStringSubmatch(router.Rule); len(pathMatches) >= 2 {
+		path = pathMatches[1]
+	} else if pathMatches := exactPathRegex.FindStringSubmatch(router.Rule); len(pathMatches) >= 2 {
 		path = pathMatches[1]
 	}
 
@@ -243,17 +391,6 @@ func ReconstructURL(router models.TraefikRouter, entryPoints map[string]models.T
 	}
 	path = strings.TrimSuffix(path, "/")
 
-	if len(router.EntryPoints) == 0 {
-		debugf("[%s] Router has no entrypoints defined. Cannot determine URL.", router.Name)
-		return ""
-	}
-	entryPointName := router.EntryPoints[0]
-	entryPoint, ok := entryPoints[entryPointName]
-	if !ok {
-		debugf("[%s] Entrypoint '%s' not found in Traefik configuration.", router.Name, entryPointName)
-		return ""
-	}
-
 	protocol := DetermineProtocol(router, entryPoint)
 	port := strings.TrimPrefix(entryPoint.Address, ":")
 
@@ -264,5 +401,269 @@ func ReconstructURL(router models.TraefikRouter, entryPoints map[string]models.T
 	return fmt.Sprintf("%s://%s:%s%s", protocol, hostname, port, path)
 }
 
+// routerEntryPoint returns the entrypoint of router that URLs are built for: the first of its
+// entrypoints that serves HTTPS, or else its first entrypoint reported by Traefik. Following
+// the order of the router keeps the choice the same between refreshes. When Traefik reports
+// none of them, the configured default entrypoint is returned with found set to false. ok is
+// false when neither is available.
+func routerEntryPoint(router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint) (entryPoint models.TraefikEntryPoint, found, ok bool) {
+	if len(router.EntryPoints) == 0 {
+		return models.TraefikEntryPoint{}, false, false
+	}
+	for _, name := range router.EntryPoints {
+		candidate, exists := entryPoints[name]
+		if !exists {
+			continue
+		}
+		if !found {
+			entryPoint, found = candidate, true
+		}
+		if DetermineProtocol(router, candidate) == "https" {
+			return candidate, true, true
+		}
+	}
+	if found {
+		return entryPoint, true, true
+	}
+	var fallback config.DefaultEntryPointConfig
+	if conf != nil {
+		fallback = conf.GetDefaultEntryPoint()
+	}
+	entryPoint, ok = DefaultEntryPoint(entryPoints, fallback)
+	return entryPoint, false, ok
+}
+
+// RuleHost returns the host name of a router's Host rule as Traefik matches it, before the
+// default domain and host rewrites are applied, or "" when the rule has no Host matcher. A
+// HostRegexp rule matching a single fixed host counts as a Host rule. Of several hosts, the
+// one ReconstructURL links to is returned. IPv6 literals are returned without brackets.
+func RuleHost(router models.TraefikRouter, entryPoints map[string]models.TraefikEntryPoint) string {
+	entryPoint, _, _ := routerEntryPoint(router, entryPoints)
+	return strings.Trim(ruleHost(router, entryPoint), "[]")
+}
+
+// ruleHost returns the host ReconstructURL links to: of the hosts named by the Host matchers
+// of the rule, or without them the fixed hosts of its HostRegexp matchers, the one chosen by
+// preferredHost. The HostSNI matchers of TCP routers count as Host matchers. It returns ""
+// when the rule names no host or every pattern matches more than a fixed host.
+func ruleHost(router models.TraefikRouter, entryPoint models.TraefikEntryPoint) string {
+	var hosts []string
+	for _, hostMatches := range hostRegex.FindAllStringSubmatch(router.Rule, -1) {
+		hosts = append(hosts, hostMatches[1])
+	}
+	hosts = append(hosts, sniHosts(router)...)
+	if len(hosts) == 0 {
+		for _, regexpMatches := range hostRegexpRegex.FindAllStringSubmatch(router.Rule, -1) {
+			host, ok := literalHost(regexpMatches[1])
+			if !ok {
+				debugf("[%s] Could not derive a host from HostRegexp pattern %s, it does not match a single fixed host", router.Name, regexpMatches[1])
+				continue
+			}
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) <= 1 {
+		return strings.Join(hosts, "")
+	}
+
+	suffix := ""
+	if conf != nil {
+		suffix = conf.GetTraefikPreferredHostSuffix()
+	}
+	host := preferredHost(hosts, suffix, tlsDomains(router, entryPoint))
+	debugf("[%s] Rule names %d hosts, using %s", router.Name, len(hosts), host)
+	return host
+}
+
+// sniHosts returns the hosts named by the HostSNI matchers of a TCP router rule. The catch-all
+// HostSNI(`*`) names no host and is skipped.
+func sniHosts(router models.TraefikRouter) []string {
+	var hosts []string
+	for _, sniMatches := range hostSNIRegex.FindAllStringSubmatch(router.Rule, -1) {
+		if host := strings.TrimSpace(sniMatches[1]); host != "*" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// preferredHost picks the host to link to from the hosts of a rule: the first one ending in
+// suffix, else the first one covered by the TLS domains, else the first one.
+func preferredHost(hosts []string, suffix string, domains []string) string {
+	if suffix != "" {
+		for _, host := range hosts {
+			lower := strings.ToLower(strings.Trim(host, "[]"))
+			if lower == suffix || strings.HasSuffix(lower, "."+suffix) {
+				return host
+			}
+		}
+	}
+	for _, host := range hosts {
+		for _, domain := range domains {
+			if matchesTLSDomain(strings.ToLower(host), domain) {
+				return host
+			}
+		}
+	}
+	return hosts[0]
+}
+
+// tlsDomains returns the lower-cased main and SAN domains of the TLS configuration of router
+// and entryPoint.
+func tlsDomains(router models.TraefikRouter, entryPoint models.TraefikEntryPoint) []string {
+	var domains []string
+	for _, raw := range []json.RawMessage{routerTLS(router), entryPoint.HTTP.TLS} {
+		var tls struct {
+			Domains []struct {
+				Main string   `json:"main"`
+				SANs []string `json:"sans"`
+			} `json:"domains"`
+		}
+		if len(raw) == 0 || json.Unmarshal(raw, &tls) != nil {
+			continue
+		}
+		for _, domain := range tls.Domains {
+			for _, name := range append([]string{domain.Main}, domain.SANs...) {
+				if name != "" {
+					domains = append(domains, strings.ToLower(name))
+				}
+			}
+		}
+	}
+	return domains
+}
+
+// routerTLS returns the raw TLS configuration of router, or nil when it has none.
+func routerTLS(router models.TraefikRouter) json.RawMessage {
+	if router.TLS == nil {
+		return nil
+	}
+	return *router.TLS
+}
+
+// matchesTLSDomain reports whether a certificate for domain covers host. A wildcard such as
+// *.example.com covers exactly one label in front of example.com.
+func matchesTLSDomain(host, domain string) bool {
+	if base, ok := strings.CutPrefix(domain, "*."); ok {
+		label, rest, found := strings.Cut(host, ".")
+		return found && label != "" && rest == base
+	}
+	return host == domain
+}
+
+// literalHost returns the host matched by a HostRegexp pattern that is effectively a fixed
+// string, such as `^grafana\.example\.com$`. Anchors and escaped characters are allowed and
+// of an alternation the first alternative is used. Patterns with repetitions, wildcards or
+// character ranges match hosts that cannot be known in advance and are reported as not ok.
+func literalHost(pattern string) (string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+	var host strings.Builder
+	if !writeLiteral(&host, re) || host.Len() == 0 {
+		return "", false
+	}
+	hostname := strings.ToLower(host.String())
+	if strings.Trim(hostname, "abcdefghijklmnopqrstuvwxyz0123456789.-:[]") != "" {
+		return "", false
+	}
+	return hostname, true
+}
+
+// writeLiteral writes the single string matched by re to b. It returns false when re matches
+// more than one string, apart from the choice between the alternatives of an alternation.
+func writeLiteral(b *strings.Builder, re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpLiteral:
+		b.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		// A class listing single characters, such as [Gg], is treated like an alternation.
+		// Ranges like [a-z] match hosts that cannot be known in advance. Alternatives that
+		// differ in one character, such as app1|app2, are parsed into the range app[1-2].
+		for i := 0; i < len(re.Rune); i += 2 {
+			if re.Rune[i] != re.Rune[i+1] {
+				return false
+			}
+		}
+		b.WriteRune(re.Rune[0])
+	case syntax.OpCapture:
+		return writeLiteral(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !writeLiteral(b, sub) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		return writeLiteral(b, re.Sub[0])
+	case syntax.OpBeginText, syntax.OpEndText, syntax.OpBeginLine, syntax.OpEndLine, syntax.OpEmptyMatch:
+	default:
+		return false
+	}
+	return true
+}
+
+// appendDefaultDomain appends domain to single-label host names such as "grafana". Host names
+// containing a dot, IPv6 literals and an empty domain leave hostname unchanged.
+func appendDefaultDomain(hostname, domain string) string {
+	if domain == "" || strings.ContainsAny(hostname, ".:") {
+		return hostname
+	}
+	return hostname + "." + domain
+}
+
+// DefaultEntryPoint returns the entrypoint to use for a router whose entrypoint is missing from
+// entryPoints: the entrypoint named by fallback.Name if it exists, otherwise an entrypoint with
+// the fallback port and scheme. It returns false when no fallback is configured.
+func DefaultEntryPoint(entryPoints map[string]models.TraefikEntryPoint, fallback config.DefaultEntryPointConfig) (models.TraefikEntryPoint, bool) {
+	if fallback.Name != "" {
+		if ep, ok := entryPoints[fallback.Name]; ok {
+			return ep, true
+		}
+	}
+	if fallback.Port == 0 {
+		return models.TraefikEntryPoint{}, false
+	}
+	name := fallback.Name
+	if name == "" {
+		name = "default"
+	}
+	ep := models.TraefikEntryPoint{Name: name, Address: ":" + strconv.Itoa(fallback.Port)}
+	if fallback.Scheme != "http" {
+		ep.HTTP.TLS = json.RawMessage(`{"options":"default"}`)
+	}
+	return ep, true
+}
+
+// rewriteHost replaces the longest matching suffix of hostname according to rewrites, which maps
+// internal suffixes (e.g. "internal") to external ones (e.g. "example.com"). Suffixes only match
+// whole labels, so "internal" matches "app.internal" and "internal" but not "app.myinternal".
+func rewriteHost(hostname string, rewrites map[string]string) string {
+	lower := strings.ToLower(hostname)
+	best := ""
+	for from := range rewrites {
+		if len(from) <= len(best) {
+			continue
+		}
+		if lower == from || strings.HasSuffix(lower, "."+from) {
+			best = from
+		}
+	}
+	if best == "" {
+		return hostname
+	}
+	return hostname[:len(hostname)-len(best)] + rewrites[best]
+}
+
+// bracketIPv6 wraps an IPv6 literal in brackets so it can be used as a URL host.
+// Host names, IPv4 addresses and already bracketed literals are returned unchanged.
+func bracketIPv6(hostname string) string {
+	if strings.Contains(hostname, ":") && net.ParseIP(hostname) != nil {
+		return "[" + hostname + "]"
+	}
+	return hostname
+}
+
 // debugf is a wrapper for the shared debug utility
 var debugf = debug.Debugf
@@ -0,0 +1,439 @@
+package traefik
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+	"server/internal/models"
+)
+
+// testEntryPoints returns plain HTTP entrypoints on port 80 and 8080, a TLS entrypoint on 443
+// and a TLS entrypoint on 8443 with a wildcard certificate for example.com.
+func testEntryPoints() map[string]models.TraefikEntryPoint {
+	eps := map[string]models.TraefikEntryPoint{
+		"web":       {Name: "web", Address: ":80"},
+		"alt":       {Name: "alt", Address: ":8080"},
+		"websecure": {Name: "websecure", Address: ":443"},
+		"wildcard":  {Name: "wildcard", Address: ":8443"},
+	}
+	secure := eps["websecure"]
+	secure.HTTP.TLS = json.RawMessage(`{"certResolver":"le"}`)
+	eps["websecure"] = secure
+	wildcard := eps["wildcard"]
+	wildcard.HTTP.TLS = json.RawMessage(`{"certResolver":"le","domains":[{"main":"example.com","sans":["*.example.com"]}]}`)
+	eps["wildcard"] = wildcard
+	return eps
+}
+
+func TestReconstructURL_HostSNI(t *testing.T) {
+	t.Parallel()
+	entryPoints := map[string]models.TraefikEntryPoint{"postgres": {Name: "postgres", Address: ":5432"}}
+	passthrough := json.RawMessage(`{"passthrough":true}`)
+
+	cases := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"single host", "HostSNI(`db.example.com`)", "https://db.example.com:5432"},
+		{"spaces around the host", "HostSNI( `db.example.com` )", "https://db.example.com:5432"},
+		{"with other matchers", "HostSNI(`db.example.com`) && ClientIP(`10.0.0.0/8`)", "https://db.example.com:5432"},
+		{"several hosts use the first", "HostSNI(`db.internal`) || HostSNI(`db.example.com`)", "https://db.internal:5432"},
+		{"catch-all names no host", "HostSNI(`*`)", ""},
+		{"catch-all is skipped for a host", "HostSNI(`*`) || HostSNI(`db.example.com`)", "https://db.example.com:5432"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			router := models.TraefikRouter{Name: "db@docker", Rule: tc.rule, EntryPoints: []string{"postgres"}, TLS: &passthrough}
+			assert.Equal(t, tc.want, ReconstructURL(router, entryPoints))
+		})
+	}
+}
+
+func TestReconstructURL(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name       string
+		rule       string
+		entryPoint string
+		want       string
+	}{
+		{"host on default port", "Host(`app.example.com`)", "web", "http://app.example.com"},
+		{"host with path", "Host(`app.example.com`) && PathPrefix(`/admin/`)", "websecure", "https://app.example.com/admin"},
+		{"IPv6 on default port", "Host(`::1`)", "web", "http://[::1]"},
+		{"IPv6 on non-default port", "Host(`fd00::10`)", "alt", "http://[fd00::10]:8080"},
+		{"bracketed IPv6", "Host(`[fd00::10]`)", "alt", "http://[fd00::10]:8080"},
+		{"IPv4 on non-default port", "Host(`192.168.1.10`)", "alt", "http://192.168.1.10:8080"},
+		{"only Path", "Host(`app.example.com`) && Path(`/ui`)", "web", "http://app.example.com/ui"},
+		{"Path without leading slash", "Host(`app.example.com`) && Path(`ui/`)", "web", "http://app.example.com/ui"},
+		{"PathPrefix before Path", "Host(`app.example.com`) && (Path(`/ui`) || PathPrefix(`/admin`))", "web", "http://app.example.com/admin"},
+		{"fixed HostRegexp", "HostRegexp(`^app\\.example\\.com$`)", "websecure", "https://app.example.com"},
+		{"dynamic HostRegexp", "HostRegexp(`^.+\\.example\\.com$`)", "websecure", ""},
+		{"Host before HostRegexp", "HostRegexp(`^.+\\.example\\.com$`) || Host(`app.example.com`)", "web", "http://app.example.com"},
+		{"several hosts use the first", "Host(`app.internal`) || Host(`app.example.com`)", "websecure", "https://app.internal"},
+		{"several hosts prefer the TLS domain", "Host(`app.internal`) || Host(`app.example.com`)", "wildcard", "https://app.example.com:8443"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			router := models.TraefikRouter{Name: "app@docker", Rule: tc.rule, EntryPoints: []string{tc.entryPoint}}
+			assert.Equal(t, tc.want, ReconstructURL(router, testEntryPoints()))
+		})
+	}
+}
+
+func TestReconstructURL_PrefersHTTPSEntryPoint(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name        string
+		entryPoints []string
+		want        string
+	}{
+		{"HTTPS listed second", []string{"web", "websecure"}, "https://app.example.com"},
+		{"HTTPS listed first", []string{"websecure", "web"}, "https://app.example.com"},
+		{"first of several HTTPS", []string{"web", "wildcard", "websecure"}, "https://app.example.com:8443"},
+		{"no HTTPS uses the first", []string{"alt", "web"}, "http://app.example.com:8080"},
+		{"unknown entrypoints are skipped", []string{"missing", "web", "websecure"}, "https://app.example.com"},
+		{"unknown before plain HTTP", []string{"missing", "web"}, "http://app.example.com"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			router := models.TraefikRouter{Name: "app@docker", Rule: "Host(`app.example.com`)", EntryPoints: tc.entryPoints}
+			assert.Equal(t, tc.want, ReconstructURL(router, testEntryPoints()))
+		})
+	}
+}
+
+func TestReconstructURLWithHost(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name string
+		rule string
+		want string
+	}{
+		{"dynamic HostRegexp uses fallback", "HostRegexp(`^.+\\.example\\.com$`)", "https://wild.example.com"},
+		{"no host matcher uses fallback", "PathPrefix(`/app`)", "https://wild.example.com/app"},
+		{"rule host wins", "Host(`app.example.com`)", "https://app.example.com"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			router := models.TraefikRouter{Name: "app@docker", Rule: tc.rule, EntryPoints: []string{"websecure"}}
+			assert.Equal(t, tc.want, ReconstructURLWithHost(router, testEntryPoints(), "wild.example.com"))
+		})
+	}
+}
+
+func TestLiteralHost(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		pattern string
+		want    string
+		wantOK  bool
+	}{
+		{"anchored with escaped dots", `^grafana\.example\.com$`, "grafana.example.com", true},
+		{"unanchored", `grafana\.example\.com`, "grafana.example.com", true},
+		{"text anchors", `\Agrafana\.lan\z`, "grafana.lan", true},
+		{"case-insensitive", `(?i)^Grafana\.LAN$`, "grafana.lan", true},
+		{"first alternative", `^(grafana|prometheus)\.lan$`, "grafana.lan", true},
+		{"alternatives sharing a prefix", `^(grafana|grafana-old)\.lan$`, "grafana.lan", true},
+		{"listed characters", `^[Gg]rafana\.lan$`, "grafana.lan", true},
+		{"alternatives differing in one character", `^(app1|app2)\.lan$`, "", false},
+		{"unescaped dot", `^grafana.lan$`, "", false},
+		{"any subdomain", `^.+\.example\.com$`, "", false},
+		{"character range", `^[a-z]+\.example\.com$`, "", false},
+		{"optional part", `^(www\.)?example\.com$`, "", false},
+		{"Traefik v2 variable", `{subdomain:[a-z]+}.example.com`, "", false},
+		{"invalid pattern", `^(grafana\.lan$`, "", false},
+		{"only anchors", `^$`, "", false},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, ok := literalHost(tc.pattern)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRuleHost(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"Host(`grafana`)": "grafana",
+		"Host(`app.example.com`) && PathPrefix(`/x`)": "app.example.com",
+		"Host(`[fd00::10]`)":                          "fd00::10",
+		"HostRegexp(`^grafana\\.lan$`)":               "grafana.lan",
+		"PathPrefix(`/api`)":                          "",
+	}
+	for rule, want := range cases {
+		assert.Equal(t, want, RuleHost(models.TraefikRouter{Rule: rule}, nil), rule)
+	}
+}
+
+func TestRewriteHost(t *testing.T) {
+	t.Parallel()
+	rewrites := map[string]string{
+		"internal":     "example.com",
+		"lab.internal": "lab.example.org",
+		"home.arpa":    "home.example.net",
+	}
+	cases := []struct {
+		name     string
+		hostname string
+		want     string
+	}{
+		{"matching suffix", "app.internal", "app.example.com"},
+		{"longest suffix wins", "nas.lab.internal", "nas.lab.example.org"},
+		{"multi-label suffix", "router.home.arpa", "router.home.example.net"},
+		{"case-insensitive match", "App.INTERNAL", "App.example.com"},
+		{"whole host matches", "internal", "example.com"},
+		{"partial label does not match", "app.myinternal", "app.myinternal"},
+		{"non-matching host", "app.example.com", "app.example.com"},
+		{"IPv4 address", "192.168.1.10", "192.168.1.10"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, rewriteHost(tc.hostname, rewrites))
+		})
+	}
+}
+
+func TestRewriteHost_NoRewrites(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "app.internal", rewriteHost("app.internal", nil))
+}
+
+func TestAppendDefaultDomain(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name     string
+		hostname string
+		domain   string
+		want     string
+	}{
+		{"bare host", "grafana", "example.com", "grafana.example.com"},
+		{"host with dot", "grafana.lan", "example.com", "grafana.lan"},
+		{"IPv4 address", "192.168.1.10", "example.com", "192.168.1.10"},
+		{"IPv6 literal", "[::1]", "example.com", "[::1]"},
+		{"no default domain", "grafana", "", "grafana"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, appendDefaultDomain(tc.hostname, tc.domain))
+		})
+	}
+}
+
+func TestDefaultEntryPoint(t *testing.T) {
+	t.Parallel()
+	router := models.TraefikRouter{Name: "app@docker", Rule: "Host(`app.example.com`)", EntryPoints: []string{"missing"}}
+	cases := []struct {
+		name     string
+		fallback config.DefaultEntryPointConfig
+		want     string
+		wantOK   bool
+	}{
+		{"not configured", config.DefaultEntryPointConfig{}, "", false},
+		{"existing entrypoint by name", config.DefaultEntryPointConfig{Name: "alt", Port: 443}, "http://app.example.com:8080", true},
+		{"unknown name uses port", config.DefaultEntryPointConfig{Name: "nope", Port: 8443}, "https://app.example.com:8443", true},
+		{"https by default", config.DefaultEntryPointConfig{Port: 443}, "https://app.example.com", true},
+		{"http scheme", config.DefaultEntryPointConfig{Port: 80, Scheme: "http"}, "http://app.example.com", true},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			entryPoints := testEntryPoints()
+			ep, ok := DefaultEntryPoint(entryPoints, tc.fallback)
+			require.Equal(t, tc.wantOK, ok)
+			if !ok {
+				return
+			}
+			entryPoints["missing"] = ep
+			assert.Equal(t, tc.want, ReconstructURL(router, entryPoints))
+		})
+	}
+}
+
+func TestCreateHTTPRequest_RereadsPasswordFile(t *testing.T) {
+	t.Parallel()
+	pwFile := filepath.Join(t.TempDir(), "pw")
+	require.NoError(t, os.WriteFile(pwFile, []byte("old-password\n"), 0o600))
+	instance := config.TraefikInstanceConfig{
+		Name:            "traefik",
+		EnableBasicAuth: true,
+		BasicAuth:       config.TraefikBasicAuth{Username: "alice", Password: "old-password", PasswordFile: pwFile},
+	}
+
+	password := func() string {
+		req, err := CreateHTTPRequestWithInstanceAuthAndContext(t.Context(), http.MethodGet, "http://traefik/api/version", instance)
+		require.NoError(t, err)
+		_, pw, ok := req.BasicAuth()
+		require.True(t, ok)
+		return pw
+	}
+	assert.Equal(t, "old-password", password())
+
+	info, err := os.Stat(pwFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(pwFile, []byte("new-password\n"), 0o600))
+	require.NoError(t, os.Chtimes(pwFile, info.ModTime(), info.ModTime()))
+	assert.Equal(t, "old-password", password(), "the file is not read again while it looks unchanged")
+
+	require.NoError(t, os.Chtimes(pwFile, info.ModTime().Add(time.Minute), info.ModTime().Add(time.Minute)))
+	assert.Equal(t, "new-password", password(), "a rotated password is used without reloading the configuration")
+
+	require.NoError(t, os.Remove(pwFile))
+	assert.Equal(t, "old-password", password(), "the loaded password is used when the file disappears")
+}
+
+func TestBasicAuthPassword_LogsReadFailureOnce(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	pwFile := filepath.Join(t.TempDir(), "pw")
+	instance := config.TraefikInstanceConfig{
+		Name:      "traefik",
+		BasicAuth: config.TraefikBasicAuth{Password: "loaded", PasswordFile: pwFile},
+	}
+
+	for range 3 {
+		assert.Equal(t, "loaded", basicAuthPassword(instance))
+	}
+	assert.Equal(t, 1, strings.Count(logs.String(), "Could not read password file"), "a missing file is logged once")
+
+	require.NoError(t, os.WriteFile(pwFile, []byte("rotated"), 0o600))
+	assert.Equal(t, "rotated", basicAuthPassword(instance))
+	assert.Contains(t, logs.String(), "can be read again")
+
+	require.NoError(t, os.Remove(pwFile))
+	basicAuthPassword(instance)
+	basicAuthPassword(instance)
+	assert.Equal(t, 2, strings.Count(logs.String(), "Could not read password file"), "a new failure is logged again")
+}
+
+func TestFetchAllPages_RetriesRateLimitedRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"name":"web"}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := &config.TralaConfiguration{}
+	c.Environment.RateLimitRetries = 1
+	c.Environment.RateLimitWaitSeconds = 5
+	Init(c)
+	t.Cleanup(func() { Init(nil) })
+
+	entryPoints, err := FetchAllPagesWithInstanceAuth[models.TraefikEntryPoint](t.Context(), server.Client(), server.URL, config.TraefikInstanceConfig{Name: "home"})
+	require.NoError(t, err)
+	assert.Equal(t, []models.TraefikEntryPoint{{Name: "web"}}, entryPoints)
+	assert.Equal(t, int32(2), requests.Load())
+
+	// Once the retries are used up the 429 is reported.
+	requests.Store(0)
+	c.Environment.RateLimitRetries = 0
+	_, err = FetchAllPagesWithInstanceAuth[models.TraefikEntryPoint](t.Context(), server.Client(), server.URL, config.TraefikInstanceConfig{Name: "home"})
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		header  string
+		attempt int
+		want    time.Duration
+	}{
+		{"seconds", "3", 0, 3 * time.Second},
+		{"past date", "Wed, 21 Oct 2015 07:28:00 GMT", 0, 0},
+		{"missing header", "", 0, time.Second},
+		{"missing header backs off", "", 2, 4 * time.Second},
+		{"negative seconds", "-1", 1, 2 * time.Second},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, retryAfter(tc.header, tc.attempt))
+		})
+	}
+}
+
+func TestReconstructURL_PreferredHostSuffix(t *testing.T) {
+	c := &config.TralaConfiguration{}
+	c.Environment.Traefik.PreferredHostSuffix = "lan"
+	Init(c)
+	t.Cleanup(func() { Init(nil) })
+
+	router := models.TraefikRouter{
+		Name:        "app@docker",
+		Rule:        "Host(`app.internal`) || Host(`app.example.com`) || Host(`app.lan`)",
+		EntryPoints: []string{"wildcard"},
+	}
+	assert.Equal(t, "https://app.lan:8443", ReconstructURL(router, testEntryPoints()), "the suffix wins over the TLS domain")
+	assert.Equal(t, "app.lan", RuleHost(router, testEntryPoints()))
+}
+
+func TestPreferredHost(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		name    string
+		hosts   []string
+		suffix  string
+		domains []string
+		want    string
+	}{
+		{"first without preferences", []string{"app.internal", "app.example.com"}, "", nil, "app.internal"},
+		{"suffix", []string{"app.internal", "app.example.com"}, "example.com", nil, "app.example.com"},
+		{"suffix matches whole labels", []string{"app.myexample.com", "app.example.com"}, "example.com", nil, "app.example.com"},
+		{"suffix ignores case", []string{"app.internal", "App.Example.COM"}, "example.com", nil, "App.Example.COM"},
+		{"first of several suffix matches", []string{"b.example.com", "a.example.com"}, "example.com", nil, "b.example.com"},
+		{"exact TLS domain", []string{"app.internal", "app.example.com"}, "", []string{"app.example.com"}, "app.example.com"},
+		{"wildcard TLS domain", []string{"app.internal", "app.example.com"}, "", []string{"*.example.com"}, "app.example.com"},
+		{"wildcard covers one label", []string{"app.internal", "a.b.example.com"}, "", []string{"*.example.com"}, "app.internal"},
+		{"suffix before TLS domain", []string{"app.example.com", "app.lan"}, "lan", []string{"*.example.com"}, "app.lan"},
+		{"unmatched suffix falls back to TLS domain", []string{"app.internal", "app.example.com"}, "lan", []string{"*.example.com"}, "app.example.com"},
+	}
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.want, preferredHost(tc.hosts, tc.suffix, tc.domains))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Package traefik provides a client for interacting with the Traefik API.
+// This file contains Traefik API version detection, used to report the version each instance runs.
+package traefik
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/config"
+)
+
+// DefaultAPIVersion is reported when an instance's API version is neither configured nor detected.
+const DefaultAPIVersion = "v3"
+
+// detectedVersions holds the major API version detected per instance name.
+var (
+	detectedVersions   = make(map[string]string)
+	detectedVersionsMu sync.RWMutex
+)
+
+// traefikVersion is the response of the Traefik /api/version endpoint.
+type traefikVersion struct {
+	Version  string `json:"Version"`
+	Codename string `json:"Codename"`
+}
+
+// DetectAPIVersion queries the /api/version endpoint of an instance and returns the major
+// API version (e.g. "v3") together with the full Traefik version.
+func DetectAPIVersion(ctx context.Context, client *http.Client, instance config.TraefikInstanceConfig) (string, string, error) {
+	resp, err := CreateAndExecuteHTTPRequestWithInstance(ctx, client, http.MethodGet, instance.APIHost+"/api/version", instance)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	var v traefikVersion
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return "", "", fmt.Errorf("could not decode version response: %w", err)
+	}
+	major := majorAPIVersion(v.Version)
+	if major == "" {
+		return "", v.Version, fmt.Errorf("unrecognized Traefik version %q", v.Version)
+	}
+	return major, v.Version, nil
+}
+
+// majorAPIVersion converts a Traefik version such as "3.1.2" or "v2.11.0" to "v3" or "v2".
+func majorAPIVersion(version string) string {
+	major, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if major == "" || strings.Trim(major, "0123456789") != "" {
+		return ""
+	}
+	return "v" + major
+}
+
+// DetectAPIVersions detects the API version of every configured instance and logs it.
+// A configured api_version takes precedence; a mismatch with the detected version is logged.
+// Traefik v2 and v3 serve the API at the same paths, so the version is only reported.
+func DetectAPIVersions() {
+	if conf == nil {
+		return
+	}
+	for _, instance := range conf.GetTraefikInstances() {
+		_, major, full, err := detectAndStoreAPIVersion(instance)
+		if err != nil {
+			log.Printf("WARNING: Could not detect Traefik API version for instance %s, using %s: %v", instance.Name, InstanceAPIVersion(instance), err)
+			continue
+		}
+		reportAPIVersion(instance, major, full)
+	}
+}
+
+// redetecting holds the names of the instances whose API version is being detected again.
+var redetecting sync.Map
+
+// RedetectAPIVersion detects the API version of an instance again in the background. It is
+// called when fetching from the instance failed, which may be because it was upgraded or
+// replaced. The version is logged when it differs from the one detected before. At most one
+// detection per instance runs at a time.
+func RedetectAPIVersion(instance config.TraefikInstanceConfig) {
+	if _, running := redetecting.LoadOrStore(instance.Name, true); running {
+		return
+	}
+	go func() {
+		defer redetecting.Delete(instance.Name)
+		previous, major, full, err := detectAndStoreAPIVersion(instance)
+		if err != nil {
+			debugf("Could not detect Traefik API version for instance %s again: %v", instance.Name, err)
+			return
+		}
+		if major != previous {
+			reportAPIVersion(instance, major, full)
+		}
+	}()
+}
+
+// detectAndStoreAPIVersion detects the API version of an instance and stores it. It returns
+// the version detected before, or "" if there was none, and the detected major and full version.
+func detectAndStoreAPIVersion(instance config.TraefikInstanceConfig) (previous, major, full string, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	major, full, err = DetectAPIVersion(ctx, CreateHTTPClientForInstance(instance.InsecureSkipVerify), instance)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	detectedVersionsMu.Lock()
+	defer detectedVersionsMu.Unlock()
+	previous = detectedVersions[instance.Name]
+	detectedVersions[instance.Name] = major
+	return previous, major, full, nil
+}
+
+// reportAPIVersion logs the version an instance runs, warning when it differs from the
+// configured api_version.
+func reportAPIVersion(instance config.TraefikInstanceConfig, major, full string) {
+	if instance.APIVersion != "" && instance.APIVersion != major {
+		log.Printf("WARNING: Traefik instance %s runs version %s but api_version is set to %s, using %s", instance.Name, full, instance.APIVersion, instance.APIVersion)
+		return
+	}
+	log.Printf("Traefik instance %s runs version %s (API %s)", instance.Name, full, major)
+}
+
+// InstanceAPIVersion returns the API version reported for an instance: the configured
+// api_version, otherwise the detected version, otherwise DefaultAPIVersion.
+func InstanceAPIVersion(instance config.TraefikInstanceConfig) string {
+	if instance.APIVersion != "" {
+		return instance.APIVersion
+	}
+	detectedVersionsMu.RLock()
+	defer detectedVersionsMu.RUnlock()
+	if v, ok := detectedVersions[instance.Name]; ok {
+		return v
+	}
+	return DefaultAPIVersion
+}
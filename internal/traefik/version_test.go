@@ -0,0 +1,71 @@
+package traefik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"server/internal/config"
+)
+
+func TestMajorAPIVersion(t *testing.T) {
+	t.Parallel()
+	cases := map[string]string{
+		"3.1.2":   "v3",
+		"v2.11.0": "v2",
+		"2":       "v2",
+		"":        "",
+		"dev":     "",
+	}
+	for in, want := range cases {
+		assert.Equal(t, want, majorAPIVersion(in), in)
+	}
+}
+
+func TestDetectAPIVersion(t *testing.T) {
+	t.Parallel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/version", r.URL.Path)
+		w.Write([]byte(`{"Version":"3.1.2","Codename":"comte"}`))
+	}))
+	defer srv.Close()
+
+	major, full, err := DetectAPIVersion(context.Background(), srv.Client(), config.TraefikInstanceConfig{Name: "t", APIHost: srv.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "v3", major)
+	assert.Equal(t, "3.1.2", full)
+}
+
+func TestInstanceAPIVersion_ConfiguredBeatsDefault(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "v2", InstanceAPIVersion(config.TraefikInstanceConfig{Name: "configured", APIVersion: "v2"}))
+	assert.Equal(t, DefaultAPIVersion, InstanceAPIVersion(config.TraefikInstanceConfig{Name: "never-detected"}))
+}
+
+func TestRedetectAPIVersion(t *testing.T) {
+	t.Parallel()
+	var version atomic.Value
+	version.Store("2.11.0")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Version":%q}`, version.Load())
+	}))
+	defer srv.Close()
+	instance := config.TraefikInstanceConfig{Name: "upgraded", APIHost: srv.URL}
+
+	RedetectAPIVersion(instance)
+	assert.Eventually(t, func() bool { return InstanceAPIVersion(instance) == "v2" }, time.Second, 10*time.Millisecond)
+
+	// After an upgrade, a failed fetch detects the new version.
+	version.Store("3.1.2")
+	assert.Eventually(t, func() bool {
+		RedetectAPIVersion(instance)
+		return InstanceAPIVersion(instance) == "v3"
+	}, time.Second, 10*time.Millisecond)
+}
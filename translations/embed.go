@@ -0,0 +1,10 @@
+// Package translations embeds the default translation files so the binary can run without
+// the /app/translations directory.
+package translations
+
+import "embed"
+
+// FS contains the default translation files, one <language>.yaml per language.
+//
+//go:embed *.yaml
+var FS embed.FS
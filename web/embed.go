@@ -0,0 +1,10 @@
+// Package web embeds the default HTML template so the binary can run without the
+// /app/template directory.
+package web
+
+import _ "embed"
+
+// IndexHTML is the default index.html template.
+//
+//go:embed html/index.html
+var IndexHTML []byte
@@ -1,11 +1,12 @@
 /**
  * TraLa Application JavaScript
  */
-const API_URL = '/api/services';
+const API_URL = 'api/services?envelope=1';
 // Defaults. These will be overridden by frontend config fetch
 let SEARCH_ENGINE_URL = 'https://www.google.com/search?q=';
 let SEARCH_ENGINE_ICON_URL = '';
 let REFRESH_INTERVAL_SECONDS = 30;
+let CONFIG_REVISION = '';
 let GROUPING_COLUMNS = 3;
 
 // Translation strings are loaded from data attributes on the body element
@@ -28,6 +29,7 @@ const escapeHtml = (unsafe) => {
 };
 
 const serviceGrid = document.getElementById('service-grid');
+const quickLinksNav = document.getElementById('quick-links');
 const searchInput = document.getElementById('search-input');
 const clearButton = document.getElementById('clear-button');
 const sortControls = document.getElementById('sort-controls');
@@ -42,6 +44,7 @@ const errorMessage = document.getElementById('error-message');
 const greetingText = document.getElementById('greeting-text');
 const clock = document.getElementById('clock');
 const configWarning = document.getElementById('config-warning');
+const maintenanceBanner = document.getElementById('maintenance-banner');
 const groupControls = document.getElementById('group-controls');
 const groupingButtons = document.getElementById('group-buttons');
 const groupToggle = document.getElementById('group-toggle');
@@ -118,6 +121,13 @@ const updateClock = () => {
 };
 
 
+// Show the maintenance banner when a message is configured
+const updateMaintenanceBanner = (message) => {
+    if (!maintenanceBanner) return;
+    maintenanceBanner.textContent = message || '';
+    maintenanceBanner.style.display = message ? '' : 'none';
+};
+
 const startRefreshBarAnimation = () => {
     refreshProgressBar.style.transition = 'none';
     refreshProgressBar.style.width = '0%';
@@ -132,17 +142,26 @@ const createServiceCard = (service) => {
     card.href = service.url;
     card.target = '_blank';
     card.rel = 'noopener noreferrer';
-    card.className = 'block p-4 rounded-lg bg-white dark:bg-gray-800 shadow-md hover:shadow-lg hover:-translate-y-1 transition-all duration-300';
+    card.className = 'relative block p-4 rounded-lg bg-white dark:bg-gray-800 shadow-md hover:shadow-lg hover:-translate-y-1 transition-all duration-300';
 
     const firstLetter = service.Name.charAt(0).toUpperCase();
     const bgColor = getColorFromString(service.Name);
 
     card.innerHTML = `<div class="flex flex-col items-center text-center"><div class="w-16 h-16 mb-4 flex items-center justify-center rounded-lg overflow-hidden"><img class="w-full h-full object-contain icon-img" src="${escapeHtml(service.icon)}" alt="Icon for ${escapeHtml(service.Name)}" style="display: block;" /><div class="fallback-icon w-full h-full ${bgColor}" style="display: none;">${escapeHtml(firstLetter)}</div></div><p class="font-semibold truncate w-full" title="${escapeHtml(service.Name)}">${escapeHtml(service.Name)}</p><p class="text-xs text-gray-500 dark:text-gray-400 truncate w-full" title="${escapeHtml(service.url)}">${escapeHtml(service.url.replace('https://', ''))}</p></div>`;
 
+    // Badge services that point outside the internal domains
+    if (service.external) {
+        card.insertAdjacentHTML('beforeend', `<svg class="external-badge absolute top-2 right-2 w-4 h-4 text-gray-400 dark:text-gray-500" fill="none" stroke="currentColor" viewBox="0 0 24 24" xmlns="http://www.w3.org/2000/svg"><title>${escapeHtml(getTranslation('external'))}</title><path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M10 6H6a2 2 0 00-2 2v10a2 2 0 002 2h10a2 2 0 002-2v-4M14 4h6m0 0v6m0-6L10 14"></path></svg>`);
+    }
+
     const img = card.querySelector('.icon-img');
     const fallback = card.querySelector('.fallback-icon');
 
     if (service.icon) {
+        // Don't reveal the dashboard address to external icon hosts
+        if (!service.iconLocal) {
+            img.referrerPolicy = 'no-referrer';
+        }
         img.onerror = () => {
             img.style.display = 'none';
             fallback.style.display = 'flex';
@@ -155,6 +174,41 @@ const createServiceCard = (service) => {
     return card;
 };
 
+// Quick links are rendered as a compact row above the services and are not grouped or sorted
+const renderQuickLinks = (links) => {
+    quickLinksNav.innerHTML = '';
+    for (const link of links) {
+        const a = document.createElement('a');
+        a.href = link.url;
+        a.target = '_blank';
+        a.rel = 'noopener noreferrer';
+        a.title = link.url;
+        a.className = 'flex items-center gap-2 px-3 py-1.5 text-sm font-medium rounded-lg bg-white dark:bg-gray-800 border border-gray-300 dark:border-gray-700 hover:bg-gray-50 dark:hover:bg-gray-700 transition-colors';
+        a.innerHTML = `<img class="w-4 h-4 object-contain" src="${escapeHtml(link.icon)}" alt="" /><span>${escapeHtml(link.name)}</span>`;
+        const img = a.querySelector('img');
+        if (link.icon) {
+            img.onerror = () => { img.style.display = 'none'; };
+        } else {
+            img.style.display = 'none';
+        }
+        quickLinksNav.appendChild(a);
+    }
+    quickLinksNav.style.display = links.length > 0 ? 'flex' : 'none';
+};
+
+const fetchQuickLinks = async () => {
+    try {
+        const response = await fetch('api/links');
+        if (!response.ok) {
+            throw new Error(`Quick links request failed: ${response.status}`);
+        }
+        const links = await response.json();
+        renderQuickLinks(Array.isArray(links) ? links : []);
+    } catch (error) {
+        console.error('Error fetching quick links:', error);
+    }
+};
+
 // In ungrouped mode, services are displayed in a single flat grid
 const renderUngroupedView = (servicesToRender, container = serviceGrid) => {
     container.className = GRID_CLASSES_UNGROUPED;
@@ -293,7 +347,20 @@ const fetchAndProcessServices = async () => {
             const errorText = await response.text();
             throw new Error(`API request failed: ${response.status} - ${errorText}`); 
         }
+        if (response.headers.get('X-Services-Stale') === 'true') {
+            console.warn('A Traefik instance is unreachable, showing its last known services.');
+        }
+        if (response.headers.get('X-Services-Partial') === 'true') {
+            console.warn('Building the service list took too long (services_timeout_seconds), showing the services processed so far.');
+        }
         let data = await response.json();
+        // The envelope wraps the services in an object that reports the truncation by max_services.
+        if (data && Array.isArray(data.services)) {
+            if (data.truncated) {
+                console.warn(`Service list truncated by max_services (${data.total} services found).`);
+            }
+            data = data.services;
+        }
         if (!Array.isArray(data)) { 
             showErrorPage("Invalid data from API."); 
             allServices = []; 
@@ -376,7 +443,7 @@ const initialize = () => {
     // Fetch all application status information in a single call
     const fetchApplicationStatus = async () => {
         try {
-            const response = await fetch('/api/status');
+            const response = await fetch('api/status');
             if (!response.ok) {
                 throw new Error(`Status request failed: ${response.status}`);
             }
@@ -406,6 +473,8 @@ const initialize = () => {
                 SEARCH_ENGINE_URL = status.frontend.searchEngineURL || SEARCH_ENGINE_URL;
                 SEARCH_ENGINE_ICON_URL = status.frontend.searchEngineIconURL || '';
                 REFRESH_INTERVAL_SECONDS = status.frontend.refreshIntervalSeconds || REFRESH_INTERVAL_SECONDS;
+                CONFIG_REVISION = status.frontend.configRevision || '';
+                updateMaintenanceBanner(status.frontend.maintenanceMessage);
 
                 // Update search icon if available
                 if (SEARCH_ENGINE_ICON_URL) {
@@ -480,16 +549,43 @@ const initialize = () => {
             updateGreeting();
         }, 6000);
 
-        await fetchAndProcessServices();
+        await Promise.all([fetchAndProcessServices(), fetchQuickLinks()]);
+        scheduleRefresh();
+    };
+
+    // (Re)start the periodic refresh with the current interval
+    const scheduleRefresh = () => {
         if (refreshIntervalId) clearInterval(refreshIntervalId);
         if (!isNaN(REFRESH_INTERVAL_SECONDS) && REFRESH_INTERVAL_SECONDS > 0) {
             startRefreshBarAnimation();
             refreshIntervalId = setInterval(async () => {
                 await fetchAndProcessServices();
+                await checkConfigRevision();
                 startRefreshBarAnimation();
             }, REFRESH_INTERVAL_SECONDS * 1000);
         }
     };
+
+    // Pick up configuration changes, such as a new refresh interval, without a page reload
+    const checkConfigRevision = async () => {
+        try {
+            const response = await fetch('api/status');
+            if (!response.ok) return;
+            const frontend = (await response.json()).frontend || {};
+            if (!frontend.configRevision || frontend.configRevision === CONFIG_REVISION) return;
+
+            CONFIG_REVISION = frontend.configRevision;
+            updateMaintenanceBanner(frontend.maintenanceMessage);
+            fetchQuickLinks();
+            const interval = frontend.refreshIntervalSeconds || REFRESH_INTERVAL_SECONDS;
+            if (interval !== REFRESH_INTERVAL_SECONDS) {
+                REFRESH_INTERVAL_SECONDS = interval;
+                scheduleRefresh();
+            }
+        } catch (error) {
+            console.error('Error checking configuration revision:', error);
+        }
+    };
     
     startApp();
 };